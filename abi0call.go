@@ -0,0 +1,15 @@
+//go:build amd64 || arm64
+
+package assembly
+
+import "unsafe"
+
+// abi0Bridge reports whether callABI0 is implemented for the architecture.
+const abi0Bridge = true
+
+// callABI0 copies size bytes of frame to the outgoing argument area, calls the ABI0 code at pc
+// and copies the area back, so frame holds the results the callee stored after its arguments.
+// size must be a multiple of 8 and at most abi0MaxFrame.
+//
+//go:noescape
+func callABI0(pc uintptr, frame unsafe.Pointer, size uintptr)
@@ -0,0 +1,21 @@
+#include "textflag.h"
+#include "funcdata.h"
+
+// func callABI0(pc uintptr, frame unsafe.Pointer, size uintptr)
+TEXT ·callABI0(SB), 0, $1024-24
+	NO_LOCAL_POINTERS
+	MOVQ frame+8(FP), SI
+	MOVQ SP, DI
+	MOVQ size+16(FP), CX
+	SHRQ $3, CX
+	CLD
+	REP; MOVSQ
+	MOVQ pc+0(FP), AX
+	CALL AX
+	MOVQ SP, SI
+	MOVQ frame+8(FP), DI
+	MOVQ size+16(FP), CX
+	SHRQ $3, CX
+	CLD
+	REP; MOVSQ
+	RET
@@ -0,0 +1,30 @@
+#include "textflag.h"
+#include "funcdata.h"
+
+// func callABI0(pc uintptr, frame unsafe.Pointer, size uintptr)
+// The outgoing arguments start at 8(RSP), above the slot of the saved link register.
+TEXT ·callABI0(SB), 0, $1032-24
+	NO_LOCAL_POINTERS
+	MOVD frame+8(FP), R0
+	MOVD size+16(FP), R1
+	ADD $8, RSP, R2
+in:
+	CBZ R1, call
+	MOVD.P 8(R0), R3
+	MOVD.P R3, 8(R2)
+	SUB $8, R1, R1
+	B in
+call:
+	MOVD pc+0(FP), R4
+	CALL (R4)
+	MOVD frame+8(FP), R0
+	MOVD size+16(FP), R1
+	ADD $8, RSP, R2
+out:
+	CBZ R1, done
+	MOVD.P 8(R2), R3
+	MOVD.P R3, 8(R0)
+	SUB $8, R1, R1
+	B out
+done:
+	RET
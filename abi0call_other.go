@@ -0,0 +1,11 @@
+//go:build !amd64 && !arm64
+
+package assembly
+
+import "unsafe"
+
+const abi0Bridge = false
+
+func callABI0(pc uintptr, frame unsafe.Pointer, size uintptr) {
+	panic(ErrNotSupport)
+}
@@ -0,0 +1,82 @@
+//go:build amd64 || arm64
+
+package assembly
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-hotfix/assembly/internal/abi0test"
+)
+
+func TestABI0Bridge(t *testing.T) {
+	asm, err := NewDwarfAssembly()
+	if nil != err {
+		t.Fatalf("NewDwarfAssembly() error: %v", err)
+	}
+	defer asm.Close()
+
+	var v = abi0test.Vector{A: 1, B: 2, C: 3, D: 4, E: 5, N: 7}
+	_, _ = abi0test.Scale(v, 1)
+
+	var scale func(v abi0test.Vector, k float64) (abi0test.Vector, float64)
+	fn, err := asm.FindFuncAs("github.com/go-hotfix/assembly/internal/abi0test.Scale", reflect.TypeOf(scale))
+	if nil != err {
+		t.Fatalf("FindFuncAs() error: %v", err)
+	}
+	reflect.ValueOf(&scale).Elem().Set(fn)
+
+	r, sum := scale(v, 2)
+	if want := (abi0test.Vector{A: 2, B: 4, C: 6, D: 8, E: 10, N: 7}); want != r || 30 != sum {
+		t.Fatalf("Scale() got = %+v, %v, want %+v, 30", r, sum, want)
+	}
+}
+
+// testSpilled takes more integers than the integer argument registers of amd64 and arm64, the
+// last ones are passed on the stack by ABIInternal.
+func testSpilled(a, b, c, d, e, f, g, h, i, j, k, l, m, n, o, p, q, r int) int {
+	return a + 2*b + 3*c + 4*d + 5*e + 6*f + 7*g + 8*h + 9*i + 10*j + 11*k + 12*l + 13*m + 14*n + 15*o + 16*p + 17*q + 18*r
+}
+
+// testMixedSpilled interleaves floats with integers and a struct taking both kinds of registers,
+// so the integers overflow theirs while floats are still passed in registers.
+func testMixedSpilled(a int8, x float64, b int, y float32, s string, c uint16, pt testPoint, d, e, f, g, h, i, j, k int, z float64) (float64, int, string) {
+	return x + float64(y) + z + pt.X + pt.Y, int(a) + b + int(c) + pt.N + d + e + f + g + h + i + j + k, s
+}
+
+func TestCallSpilledArguments(t *testing.T) {
+	asm, err := NewDwarfAssembly()
+	if nil != err {
+		t.Fatalf("NewDwarfAssembly() error: %v", err)
+	}
+	defer asm.Close()
+
+	var ints = make([]reflect.Value, 18)
+	var want int
+	for idx := range ints {
+		ints[idx] = reflect.ValueOf(idx + 1)
+		want += (idx + 1) * (idx + 1)
+	}
+	if got := testSpilled(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18); want != got {
+		t.Fatalf("testSpilled() got = %v, want %v", got, want)
+	}
+	results, err := asm.CallFunc("github.com/go-hotfix/assembly.testSpilled", false, ints)
+	if nil != err || 1 != len(results) || want != results[0].Interface().(int) {
+		t.Fatalf("CallFunc(testSpilled) got = %v, %v, want %v", results, err, want)
+	}
+
+	pt := testPoint{X: 0.5, Y: 0.25, N: 100}
+	wantF, wantN, wantS := testMixedSpilled(-1, 1.5, 2, 2.5, "mixed", 3, pt, 4, 5, 6, 7, 8, 9, 10, 11, 3.5)
+	results, err = asm.CallFunc("github.com/go-hotfix/assembly.testMixedSpilled", false, []reflect.Value{
+		reflect.ValueOf(int8(-1)), reflect.ValueOf(1.5), reflect.ValueOf(2), reflect.ValueOf(float32(2.5)),
+		reflect.ValueOf("mixed"), reflect.ValueOf(uint16(3)), reflect.ValueOf(pt),
+		reflect.ValueOf(4), reflect.ValueOf(5), reflect.ValueOf(6), reflect.ValueOf(7),
+		reflect.ValueOf(8), reflect.ValueOf(9), reflect.ValueOf(10), reflect.ValueOf(11), reflect.ValueOf(3.5),
+	})
+	if nil != err || 3 != len(results) {
+		t.Fatalf("CallFunc(testMixedSpilled) got = %v, %v", results, err)
+	}
+	if wantF != results[0].Float() || wantN != int(results[1].Int()) || wantS != results[2].String() {
+		t.Fatalf("CallFunc(testMixedSpilled) got = %v, want %v, %v, %v", results, wantF, wantN, wantS)
+	}
+}
@@ -0,0 +1,324 @@
+package assembly
+
+import (
+	"debug/dwarf"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
+	"github.com/go-delve/delve/pkg/dwarf/op"
+	"github.com/go-delve/delve/pkg/goversion"
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// CallABI is the calling convention generation a function was compiled for.
+type CallABI int
+
+const (
+	ABIUnknown  CallABI = iota
+	ABIStack            // ABI0, every argument and result passed on the stack
+	ABIRegister         // ABIInternal with register based argument passing, Go 1.17+
+)
+
+func (abi CallABI) String() string {
+	switch abi {
+	case ABIStack:
+		return "stack"
+	case ABIRegister:
+		return "register"
+	}
+	return "unknown"
+}
+
+// ParamLocation describes where a formal parameter is passed at function entry.
+type ParamLocation struct {
+	Name      string
+	Type      string
+	IsResult  bool
+	Registers []uint64 // DWARF numbers of the registers holding the parameter
+	Stack     bool     // some part of the parameter is passed in memory
+	Offset    int64    // offset from the frame base of the part passed in memory
+	Unknown   bool     // the location list has no entry covering the function entry
+}
+
+// regABISince is the first Go minor version using register based arguments by default per architecture.
+var regABISince = map[string]int{
+	"amd64":   17,
+	"arm64":   18,
+	"ppc64":   18,
+	"ppc64le": 18,
+	"riscv64": 19,
+	"loong64": 20,
+}
+
+// funcParam is a formal parameter decoded from the debug_info entry of a function.
+type funcParam struct {
+	name  string
+	typ   godwarf.Type
+	isret bool
+	entry *dwarf.Entry
+}
+
+// abiArgRegs is the number of integer and floating point argument registers
+// of the Go internal ABI, architectures not listed pass everything on the stack.
+var abiArgRegs = map[string][2]int{
+	"amd64":   {9, 15},
+	"arm64":   {16, 16},
+	"loong64": {16, 16},
+	"ppc64":   {12, 12},
+	"ppc64le": {12, 12},
+	"riscv64": {16, 16},
+}
+
+// funcParams reads the formal parameters of f in declaration order directly from DWARF,
+// following abstract origins of out-of-line copies of inlined functions.
+func (da *dwarfAssembly) funcParams(f *proc.Function) ([]funcParam, error) {
+	img := funcToImage(da.binaryInfo, f)
+	reader := da.acquireReader(img)
+	defer da.releaseReader(img, reader)
+	reader.Seek(funcOffset(f))
+	entry, err := reader.Next()
+	if err != nil {
+		return nil, fmt.Errorf("DWARF read error: %s: %w", f.Name, err)
+	}
+	if entry == nil || !entry.Children {
+		return nil, nil
+	}
+
+	var params []funcParam
+	for {
+		child, err := reader.Next()
+		if err != nil {
+			return nil, fmt.Errorf("DWARF read error: %s: %w", f.Name, err)
+		}
+		if child == nil || child.Tag == 0 {
+			break
+		}
+		if child.Children {
+			reader.SkipChildren()
+		}
+		if child.Tag != dwarf.TagFormalParameter {
+			continue
+		}
+
+		origin := child
+		if off, ok := child.Val(dwarf.AttrAbstractOrigin).(dwarf.Offset); ok {
+			r := da.acquireReader(img)
+			r.Seek(off)
+			origin, err = r.Next()
+			da.releaseReader(img, r)
+			if err != nil || origin == nil {
+				return nil, fmt.Errorf("DWARF read error: %s: abstract origin %#x", f.Name, off)
+			}
+		}
+
+		name, _ := origin.Val(dwarf.AttrName).(string)
+		off, ok := origin.Val(dwarf.AttrType).(dwarf.Offset)
+		if !ok {
+			return nil, fmt.Errorf("malformed parameter DIE: %s: %s", f.Name, name)
+		}
+		typ, err := img.Type(off)
+		if err != nil {
+			return nil, fmt.Errorf("resolve parameter type failed: %s: %s: %w", f.Name, name, err)
+		}
+		isret, _ := origin.Val(dwarf.AttrVarParam).(bool)
+		params = append(params, funcParam{name: name, typ: resolveTypedef(typ), isret: isret, entry: child})
+	}
+	return params, nil
+}
+
+func (da *dwarfAssembly) ParamLocations(name string) ([]ParamLocation, error) {
+	f, err := da.findFunc(name)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := da.funcParams(f)
+	if err != nil {
+		return nil, err
+	}
+
+	locations := make([]ParamLocation, len(params))
+	for i, param := range params {
+		locations[i] = da.paramLocation(f, param)
+	}
+	return locations, nil
+}
+
+func (da *dwarfAssembly) paramLocation(f *proc.Function, param funcParam) ParamLocation {
+	loc := ParamLocation{Name: param.name, Type: godwarfTypeName(param.typ), IsResult: param.isret}
+
+	instr, err := funcLocationExpr(f, param.entry, dwarf.AttrLocation, f.Entry)
+	if err != nil {
+		loc.Unknown = true
+		return loc
+	}
+	addr, pieces, err := op.ExecuteStackProgram(op.DwarfRegisters{}, instr, da.binaryInfo.Arch.PtrSize(), nil)
+	if err != nil {
+		loc.Unknown = true
+		return loc
+	}
+	if len(pieces) == 0 {
+		loc.Stack, loc.Offset = true, addr
+		return loc
+	}
+
+	for _, piece := range pieces {
+		switch piece.Kind {
+		case op.RegPiece:
+			loc.Registers = append(loc.Registers, piece.Val)
+		case op.AddrPiece:
+			if !loc.Stack {
+				loc.Stack, loc.Offset = true, int64(piece.Val)
+			}
+		}
+	}
+	return loc
+}
+
+// ValidateCallABI checks that the arguments of the named function are passed where DWARF
+// says they are (registers or stack), according to the internal ABI reflect calls use.
+func (da *dwarfAssembly) ValidateCallABI(name string) error {
+	f, err := da.findFunc(name)
+	if err != nil {
+		return err
+	}
+
+	params, err := da.funcParams(f)
+	if err != nil {
+		return err
+	}
+
+	inTyps, _, inNames, _, err := da.getFunctionArgTypes(f)
+	if err != nil {
+		return err
+	}
+
+	var i = 0
+	var assign = newABIAssigner(da.binaryInfo.Arch.Name)
+	for _, param := range params {
+		if param.isret {
+			continue
+		}
+		inTyp := inTyps[i]
+		i++
+
+		expectRegs := assign.assign(inTyp)
+		if inTyp.Size() == 0 {
+			continue
+		}
+
+		loc := da.paramLocation(f, param)
+		if loc.Unknown {
+			continue
+		}
+		if gotRegs := len(loc.Registers) > 0; gotRegs != (expectRegs > 0) {
+			return fmt.Errorf("abi mismatch arg: %d:%s (%s), register passed: %v, reflect expects: %v", i-1, inNames[i-1], inTyp.String(), gotRegs, expectRegs > 0)
+		}
+	}
+	return nil
+}
+
+// abiAssigner follows the register assignment algorithm of the Go internal ABI.
+type abiAssigner struct {
+	ints, floats int
+}
+
+func newABIAssigner(arch string) *abiAssigner {
+	regs := abiArgRegs[arch]
+	return &abiAssigner{ints: regs[0], floats: regs[1]}
+}
+
+// assign returns the number of registers typ consumes, or 0 if it is passed on the stack.
+func (a *abiAssigner) assign(typ reflect.Type) int {
+	ints, floats, ok := abiRegs(typ)
+	if !ok || ints > a.ints || floats > a.floats {
+		return 0
+	}
+	a.ints -= ints
+	a.floats -= floats
+	return ints + floats
+}
+
+// abiRegs counts the integer and floating point registers needed to pass typ,
+// ok is false if the type is never passed in registers.
+func abiRegs(typ reflect.Type) (ints, floats int, ok bool) {
+	switch typ.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return 0, 1, true
+	case reflect.Complex64, reflect.Complex128:
+		return 0, 2, true
+	case reflect.String, reflect.Interface:
+		return 2, 0, true
+	case reflect.Slice:
+		return 3, 0, true
+	case reflect.Array:
+		switch typ.Len() {
+		case 0:
+			return 0, 0, true
+		case 1:
+			return abiRegs(typ.Elem())
+		}
+		return 0, 0, false
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			fi, ff, ok := abiRegs(typ.Field(i).Type)
+			if !ok {
+				return 0, 0, false
+			}
+			ints, floats = ints+fi, floats+ff
+		}
+		return ints, floats, true
+	}
+	return 1, 0, true
+}
+
+func (da *dwarfAssembly) FuncABI(name string) (CallABI, error) {
+	f, err := da.findFunc(name)
+	if err != nil {
+		return ABIUnknown, err
+	}
+	return da.funcABI(f), nil
+}
+
+// funcABI detects the ABI generation of f from the producer of its compile unit. Assembly
+// functions use ABI0 whatever the producer, their ELF symbols carry an ".abi0" suffix.
+func (da *dwarfAssembly) funcABI(f *proc.Function) CallABI {
+	if strings.HasSuffix(f.Name, ".abi0") || da.abi0Entries(funcToImage(da.binaryInfo, f))[f.Entry] {
+		return ABIStack
+	}
+	producer := reflect.ValueOf(f).Elem().FieldByName("cu").Elem().FieldByName("producer").String()
+	return producerABI(producer, da.binaryInfo.Arch.Name, da.regabiExperiment())
+}
+
+// hostABI is the ABI generation reflect.Value.Call of the running program uses.
+func (da *dwarfAssembly) hostABI() CallABI {
+	return producerABI(da.binaryInfo.Producer(), da.binaryInfo.Arch.Name, da.regabiExperiment())
+}
+
+// regabiExperiment reports whether delve saw a GOEXPERIMENT=regabi producer, which predates the default switch.
+func (da *dwarfAssembly) regabiExperiment() bool {
+	rRegabi := reflect.ValueOf(da.binaryInfo).Elem().FieldByName("regabi")
+	return rRegabi.IsValid() && rRegabi.Bool()
+}
+
+func producerABI(producer string, arch string, regabi bool) CallABI {
+	if producer == "" {
+		return ABIUnknown
+	}
+	if minor, ok := regABISince[arch]; regabi || (ok && goversion.ProducerAfterOrEqual(producer, 1, minor)) {
+		return ABIRegister
+	}
+	return ABIStack
+}
+
+// checkCallABI refuses functions compiled for an ABI generation reflect calls can not target,
+// ABI0 functions on register ABI hosts are called through callABI0 where it is implemented.
+func (da *dwarfAssembly) checkCallABI(f *proc.Function) error {
+	abi, host := da.funcABI(f), da.hostABI()
+	if abi != ABIUnknown && host != ABIUnknown && abi != host && !da.bridgedABI(f) {
+		return fmt.Errorf("%w: %s uses %s abi, reflect calls use %s abi", ErrABIMismatch, f.Name, abi, host)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package assembly
+
+import (
+	"debug/elf"
+	"fmt"
+	"reflect"
+	"strings"
+	"unsafe"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// abi0MaxFrame is the size of the outgoing argument area callABI0 reserves.
+const abi0MaxFrame = 1024
+
+// abi0Layout is the frame ABI0 passes the arguments and results of a function type in: every
+// argument at its natural alignment, the results from the next pointer aligned offset and the
+// whole frame rounded up to pointers. in and out are the field indices of the frame struct.
+type abi0Layout struct {
+	frame reflect.Type
+	in    []int
+	out   []int
+}
+
+func newABI0Layout(ftyp reflect.Type) (*abi0Layout, error) {
+	const ptrSize = unsafe.Sizeof(uintptr(0))
+	var layout = new(abi0Layout)
+	var fields []reflect.StructField
+	var off uintptr
+	add := func(typ reflect.Type) int {
+		off = alignUp(off, uintptr(typ.Align())) + typ.Size()
+		fields = append(fields, reflect.StructField{Name: fmt.Sprintf("F%d", len(fields)), Type: typ})
+		return len(fields) - 1
+	}
+	pad := func() {
+		if n := alignUp(off, ptrSize) - off; n > 0 {
+			add(reflect.ArrayOf(int(n), reflect.TypeOf(byte(0))))
+		}
+	}
+
+	for i := 0; i < ftyp.NumIn(); i++ {
+		layout.in = append(layout.in, add(ftyp.In(i)))
+	}
+	pad()
+	for i := 0; i < ftyp.NumOut(); i++ {
+		layout.out = append(layout.out, add(ftyp.Out(i)))
+	}
+	pad()
+
+	layout.frame = reflect.StructOf(fields)
+	if size := layout.frame.Size(); size%ptrSize != 0 || size > abi0MaxFrame {
+		return nil, fmt.Errorf("%w: %s needs an abi0 frame of %d bytes", ErrNotSupport, ftyp, size)
+	}
+	return layout, nil
+}
+
+func alignUp(off, align uintptr) uintptr {
+	return (off + align - 1) &^ (align - 1)
+}
+
+// createABI0Func returns the function value of type ftyp calling the ABI0 code at pc. Unlike
+// the values of createFunc it is a reflect.MakeFunc copying the arguments into an ABI0 frame
+// for callABI0, reflect calls pass arguments in registers on register ABI hosts.
+func (da *dwarfAssembly) createABI0Func(ftyp reflect.Type, pc uint64) (reflect.Value, error) {
+	key := funcKey{typ: ftyp, pc: pc}
+	da.cacheMu.Lock()
+	defer da.cacheMu.Unlock()
+	if f, ok := da.funcs[key]; ok {
+		return f.value, nil
+	}
+	layout, err := newABI0Layout(ftyp)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	f := &createdFunc{image: da.binaryInfo.PCToImage(pc)}
+	f.value = reflect.MakeFunc(ftyp, func(args []reflect.Value) []reflect.Value {
+		if f.unloaded.Load() {
+			panic(ErrImageUnloaded)
+		}
+		frame := reflect.New(layout.frame).Elem()
+		for i, arg := range args {
+			frame.Field(layout.in[i]).Set(arg)
+		}
+		callABI0(uintptr(pc), unsafe.Pointer(frame.UnsafeAddr()), layout.frame.Size())
+		out := make([]reflect.Value, len(layout.out))
+		for i, field := range layout.out {
+			out[i] = frame.Field(field)
+		}
+		return out
+	})
+	// swapping in the stub on unload leaves the value unchanged, unloaded guards the calls.
+	f.stubPtr = funcValue(f.value).codePtr
+
+	if da.funcs == nil {
+		da.funcs = make(map[funcKey]*createdFunc)
+	}
+	da.funcs[key] = f
+	return f.value, nil
+}
+
+// abi0Entries returns the entries of the functions of img whose ELF symbols carry the ".abi0"
+// suffix, DWARF names assembly functions without it. Other executable formats report none.
+func (da *dwarfAssembly) abi0Entries(img *proc.Image) map[uint64]bool {
+	da.cacheMu.Lock()
+	entries, ok := da.abi0Funcs[img]
+	da.cacheMu.Unlock()
+	if ok {
+		return entries
+	}
+
+	entries = make(map[uint64]bool)
+	if f, err := elf.Open(img.Path); err == nil {
+		syms, _ := f.Symbols()
+		for _, sym := range syms {
+			if elf.ST_TYPE(sym.Info) == elf.STT_FUNC && strings.HasSuffix(sym.Name, ".abi0") {
+				entries[sym.Value+img.StaticBase] = true
+			}
+		}
+		f.Close()
+	}
+
+	da.cacheMu.Lock()
+	defer da.cacheMu.Unlock()
+	if da.abi0Funcs == nil {
+		da.abi0Funcs = make(map[*proc.Image]map[uint64]bool)
+	}
+	da.abi0Funcs[img] = entries
+	return entries
+}
+
+// callableFunc returns the function value of type ftyp calling f, bridging ABI0 functions
+// on register ABI hosts.
+func (da *dwarfAssembly) callableFunc(f *proc.Function, ftyp reflect.Type) (reflect.Value, error) {
+	if da.bridgedABI(f) {
+		return da.createABI0Func(ftyp, f.Entry)
+	}
+	return da.createFunc(ftyp, f.Entry), nil
+}
+
+// bridgedABI reports whether calls of f go through callABI0.
+func (da *dwarfAssembly) bridgedABI(f *proc.Function) bool {
+	return abi0Bridge && da.funcABI(f) == ABIStack && da.hostABI() == ABIRegister
+}
+
+// FindFuncAs returns the function name as a value of type ftyp, for functions whose signature
+// is not recorded in DWARF such as assembly functions. Functions compiled for ABI0, like most
+// assembly functions, are called through an ABI0 frame; ftyp is trusted.
+func (da *dwarfAssembly) FindFuncAs(name string, ftyp reflect.Type) (reflect.Value, error) {
+	if ftyp.Kind() != reflect.Func {
+		return reflect.Value{}, fmt.Errorf("%s: %s is not a function type", name, ftyp)
+	}
+	f, err := da.findFunc(name)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if err = da.checkCallABI(f); err != nil {
+		return reflect.Value{}, err
+	}
+	if err = da.checkClosure(f); err != nil {
+		return reflect.Value{}, err
+	}
+	return da.callableFunc(f, ftyp)
+}
@@ -0,0 +1,218 @@
+package assembly
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// AdminOptions configures ServeAdmin, the zero value listens on DefaultAdminAddr without a
+// layout watcher.
+type AdminOptions struct {
+	// Addr is the address the admin surface listens on, DefaultAdminAddr if empty.
+	Addr string
+	// WatchInterval runs WatchLayout at this interval alongside the server, zero runs none.
+	WatchInterval time.Duration
+	// Patches keeps the patches applied through the admin surface, a new manager if nil.
+	Patches *PatchManager
+	// Token authorizes the requests changing the process, sent as "Authorization: Bearer <token>".
+	// Without a token they are refused and ServeAdmin only listens on loopback addresses.
+	Token string
+	// Handlers are served alongside the admin endpoints under their patterns, behind Token like
+	// the POST requests, e.g. to load patch plugins.
+	Handlers map[string]http.Handler
+}
+
+// DefaultAdminAddr is the loopback address ServeAdmin listens on by default.
+const DefaultAdminAddr = "127.0.0.1:6061"
+
+// AdminHandler serves the admin surface of asm over HTTP:
+//
+//	GET  /healthz                                 HealthHandler
+//	GET  /metrics                                 MetricsHandler
+//	GET  /patches                                 the patches of opts.Patches, as JSON
+//	POST /patches?target=t&replacement=r          patch t with the function r, e.g. of a patch plugin
+//	POST /patches/rollback?target=t               revert the patch of t
+//	POST /patches/rollback-all                    revert every patch of opts.Patches
+//
+// along with opts.Handlers. The POST requests and opts.Handlers carry opts.Token and are refused
+// when opts has none, patches go through the mutation and rate limit policies of asm.
+// opts.Addr and opts.WatchInterval are not used.
+func AdminHandler(asm DwarfAssembly, opts AdminOptions) http.Handler {
+	patches := opts.Patches
+	if patches == nil {
+		patches = NewPatchManager(asm)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", HealthHandler(asm))
+	mux.Handle("/metrics", MetricsHandler(asm))
+	mux.HandleFunc("/patches", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeAdminJSON(w, http.StatusOK, patches.ListPatches())
+		case http.MethodPost:
+			if !AdminAuthorized(r, opts.Token) {
+				http.Error(w, "a valid admin token is required", http.StatusUnauthorized)
+				return
+			}
+			target, replacement := r.URL.Query().Get("target"), r.URL.Query().Get("replacement")
+			if target == "" || replacement == "" {
+				http.Error(w, "target and replacement are required", http.StatusBadRequest)
+				return
+			}
+			fn, err := asm.FindFunc(replacement, false)
+			if err != nil {
+				writeAdminError(w, err)
+				return
+			}
+			p, err := patches.Apply(target, fn)
+			if err != nil {
+				writeAdminError(w, err)
+				return
+			}
+			writeAdminJSON(w, http.StatusCreated, p.Info())
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/patches/rollback", adminPost(opts.Token, func(w http.ResponseWriter, r *http.Request) {
+		target := r.URL.Query().Get("target")
+		if target == "" {
+			http.Error(w, "target is required", http.StatusBadRequest)
+			return
+		}
+		if err := patches.Rollback(target); err != nil {
+			writeAdminError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	mux.HandleFunc("/patches/rollback-all", adminPost(opts.Token, func(w http.ResponseWriter, _ *http.Request) {
+		if err := patches.RollbackAll(); err != nil {
+			writeAdminError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	for pattern, h := range opts.Handlers {
+		mux.Handle(pattern, adminAuth(opts.Token, h))
+	}
+	return mux
+}
+
+// ServeAdmin serves AdminHandler on opts.Addr and runs the layout watcher until ctx is done,
+// then shuts the server down and returns the error of ctx. It returns early with the error of
+// the listener, the server or the watcher. Without opts.Token, opts.Addr must be a loopback address.
+func ServeAdmin(ctx context.Context, asm DwarfAssembly, opts AdminOptions) error {
+	if opts.Addr == "" {
+		opts.Addr = DefaultAdminAddr
+	}
+	if opts.Token == "" && !LoopbackAddr(opts.Addr) {
+		return fmt.Errorf("admin address %s is not a loopback address and no token is set", opts.Addr)
+	}
+	if opts.Patches == nil {
+		opts.Patches = NewPatchManager(asm)
+	}
+	ln, err := net.Listen("tcp", opts.Addr)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	server := &http.Server{Handler: AdminHandler(asm, opts), ReadHeaderTimeout: 10 * time.Second}
+	errs := make(chan error, 2)
+	go func() { errs <- server.Serve(ln) }()
+	if opts.WatchInterval > 0 {
+		go func() { errs <- asm.WatchLayout(ctx, opts.WatchInterval) }()
+	}
+
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+	case err = <-errs:
+	}
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	server.Shutdown(shutdownCtx)
+	return err
+}
+
+// AdminAuthorized reports whether r carries token as its bearer token, always false for an
+// empty token.
+func AdminAuthorized(r *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+	got := []byte(r.Header.Get("Authorization"))
+	return subtle.ConstantTimeCompare(got, []byte("Bearer "+token)) == 1
+}
+
+// LoopbackAddr reports whether the host of addr, a host:port pair, is a loopback address.
+// An empty host listens on every interface and is not.
+func LoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// adminAuth refuses the requests to h not authorized by token.
+func adminAuth(token string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !AdminAuthorized(r, token) {
+			http.Error(w, "a valid admin token is required", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// adminPost restricts h to POST requests authorized by token.
+func adminPost(token string, h http.HandlerFunc) http.HandlerFunc {
+	auth := adminAuth(token, h)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		auth.ServeHTTP(w, r)
+	}
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeAdminError answers err with the status of its cause.
+func writeAdminError(w http.ResponseWriter, err error) {
+	status := http.StatusUnprocessableEntity
+	switch {
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrAlreadyPatched):
+		status = http.StatusConflict
+	case errors.Is(err, ErrRateLimited):
+		status = http.StatusTooManyRequests
+	case errors.Is(err, ErrProtectedSymbol):
+		status = http.StatusForbidden
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	io.WriteString(w, err.Error()+"\n")
+}
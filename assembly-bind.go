@@ -0,0 +1,58 @@
+package assembly
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// BindFunc resolves the function name and stores it into fnPtr, a pointer to a variable of
+// the function's static type. It is the runtime half of the wrappers emitted by assembly-gen.
+func BindFunc(asm FuncResolver, name string, variadic bool, fnPtr any) error {
+	ptr := reflect.ValueOf(fnPtr)
+	if ptr.Kind() != reflect.Pointer || ptr.Elem().Kind() != reflect.Func {
+		return fmt.Errorf("%s: BindFunc requires a pointer to a func variable, got %T", name, fnPtr)
+	}
+
+	fn, err := asm.FindFunc(name, variadic)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if fn.Type() != ptr.Elem().Type() {
+		return fmt.Errorf("%s: signature mismatch: resolved %s, bound to %s", name, fn.Type(), ptr.Elem().Type())
+	}
+	ptr.Elem().Set(fn)
+	return nil
+}
+
+// GetGlobal returns the value of the global variable name, which must be assignable to T.
+func GetGlobal[T any](asm GlobalAccessor, name string) (T, error) {
+	var out T
+	global, err := asm.FindGlobal(name)
+	if err != nil {
+		return out, fmt.Errorf("%s: %w", name, err)
+	}
+	want := reflect.TypeOf(&out).Elem()
+	if !global.Type().AssignableTo(want) {
+		return out, fmt.Errorf("%s: type mismatch: resolved %s, read as %s", name, global.Type(), want)
+	}
+	reflect.ValueOf(&out).Elem().Set(global)
+	return out, nil
+}
+
+// SetGlobal assigns value to the global variable name, subject to the mutation policy.
+func SetGlobal[T any](asm GlobalAccessor, name string, value T) error {
+	return asm.SetGlobal(name, reflect.ValueOf(&value).Elem())
+}
+
+// TypedFunc resolves the function name as a value of the function type T, variadic if T is.
+func TypedFunc[T any](asm FuncResolver, name string) (T, error) {
+	var out T
+	want := reflect.TypeOf(&out).Elem()
+	if want.Kind() != reflect.Func {
+		return out, fmt.Errorf("%s: TypedFunc requires a func type, got %s", name, want)
+	}
+	if err := BindFunc(asm, name, want.IsVariadic(), &out); err != nil {
+		return out, err
+	}
+	return out, nil
+}
@@ -0,0 +1,126 @@
+package assembly
+
+import (
+	"debug/dwarf"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
+	"github.com/go-delve/delve/pkg/goversion"
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// Closure is an anonymous function recorded in DWARF. A closure capturing variables expects
+// the context of the closure that created it and cannot be called by name, but can be located
+// and traced.
+type Closure struct {
+	Name   string
+	Parent string
+	File   string
+	Line   int
+	Entry  uint64
+	Image  *proc.Image
+}
+
+// closureSuffix matches the compiler generated segments appended to the name of the function
+// declaring a closure, including nested closures, range-over-func bodies and go/defer wrappers.
+var closureSuffix = regexp.MustCompile(`((\.func\d+|\.gowrap\d+|\.deferwrap\d+|\.\d+)|-range\d+)+$`)
+
+// Closures enumerates the anonymous functions of every loaded image with the named function
+// declaring them, filter selects closures by their parent and may be nil. Closures inlined at
+// every use have no entry and no location.
+func (da *dwarfAssembly) Closures(filter func(parent string) bool) []Closure {
+	var closures []Closure
+	functions := da.snapshot().functions
+	for i := range functions {
+		f := &functions[i]
+		loc := closureSuffix.FindStringIndex(f.Name)
+		if loc == nil || loc[0] == 0 || !compilerFuncName.MatchString(f.Name) {
+			continue
+		}
+		parent := da.displayName(f.Name[:loc[0]])
+		if filter != nil && !filter(parent) {
+			continue
+		}
+
+		closure := Closure{
+			Name:   da.displayName(f.Name),
+			Parent: parent,
+			Entry:  f.Entry,
+			Image:  funcToImage(da.binaryInfo, f),
+		}
+		if f.Entry != 0 {
+			closure.File, closure.Line = da.binaryInfo.EntryLineForFunc(f)
+		}
+		closures = append(closures, closure)
+	}
+	return closures
+}
+
+// checkClosure refuses closures reading captured variables through the closure context, a
+// forged function value carries no context, so the closure would read past it. Closures that
+// capture nothing are compiled as static function values and are called like any function.
+// Toolchains before go1.23 do not record captured variables, their closures are all refused.
+func (da *dwarfAssembly) checkClosure(f *proc.Function) error {
+	if !compilerFuncName.MatchString(f.Name) {
+		return nil
+	}
+	producer := reflect.ValueOf(f).Elem().FieldByName("cu").Elem().FieldByName("producer").String()
+	if !goversion.ProducerAfterOrEqual(producer, 1, 23) {
+		return fmt.Errorf("%w: %s, captured variables are not recorded by %s", ErrClosureNotSupported, f.Name, producer)
+	}
+	captured, err := da.closureCaptures(f)
+	if err != nil {
+		return err
+	}
+	if len(captured) > 0 {
+		return fmt.Errorf("%w: %s captures %s", ErrClosureNotSupported, f.Name, strings.Join(captured, ", "))
+	}
+	return nil
+}
+
+// closureCaptures returns the names of the variables f reads from its closure context,
+// the variables declared with a closure offset anywhere in the body of f.
+func (da *dwarfAssembly) closureCaptures(f *proc.Function) ([]string, error) {
+	img := funcToImage(da.binaryInfo, f)
+	reader := da.acquireReader(img)
+	defer da.releaseReader(img, reader)
+	reader.Seek(funcOffset(f))
+	entry, err := reader.Next()
+	if err != nil {
+		return nil, fmt.Errorf("DWARF read error: %s: %w", f.Name, err)
+	}
+	if entry == nil || !entry.Children {
+		return nil, nil
+	}
+
+	var captured []string
+	for depth := 1; depth > 0; {
+		child, err := reader.Next()
+		if err != nil {
+			return nil, fmt.Errorf("DWARF read error: %s: %w", f.Name, err)
+		}
+		if child == nil {
+			break
+		}
+		switch {
+		case child.Tag == 0:
+			depth--
+			continue
+		case child.Tag == dwarf.TagInlinedSubroutine:
+			if child.Children {
+				reader.SkipChildren()
+			}
+			continue
+		case child.Children:
+			depth++
+		}
+		if _, ok := child.Val(godwarf.AttrGoClosureOffset).(int64); ok {
+			name, _ := child.Val(dwarf.AttrName).(string)
+			captured = append(captured, name)
+		}
+	}
+	return captured, nil
+}
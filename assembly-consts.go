@@ -0,0 +1,90 @@
+package assembly
+
+import (
+	"debug/dwarf"
+	"fmt"
+	"sort"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// Constant is a package level constant recorded in DWARF. The compiler records the constants of
+// integer kinds only, typed ones with their named type, e.g. the values of an enum.
+type Constant struct {
+	Name  string
+	Type  string
+	Value int64
+	Image *proc.Image
+}
+
+// ForeachConst yields the constants of every image sorted by name. A constant defined by several
+// images is yielded once, as the resolve policy picks it.
+func (da *dwarfAssembly) ForeachConst(f func(c Constant) bool) {
+	consts := da.constants()
+	names := make([]string, 0, len(consts))
+	for name := range consts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		c := da.preferredConst(consts[name])
+		c.Name = da.displayName(c.Name)
+		if !f(c) {
+			break
+		}
+	}
+}
+
+func (da *dwarfAssembly) FindConst(name string) (Constant, error) {
+	consts := da.constants()
+	resolved, ok := da.resolveName(name, func(name string) bool { return len(consts[name]) > 0 })
+	if !ok {
+		return Constant{}, fmt.Errorf("const %s: %w", name, ErrNotFound)
+	}
+	return da.preferredConst(consts[resolved]), nil
+}
+
+func (da *dwarfAssembly) preferredConst(defs []Constant) Constant {
+	images := make([]*proc.Image, len(defs))
+	for i, def := range defs {
+		images[i] = def.Image
+	}
+	return defs[da.preferredImage(images, len(defs)-1)]
+}
+
+// constants reads the constants delve collected while loading the images, by name in load order.
+func (da *dwarfAssembly) constants() map[string][]Constant {
+	var consts = make(map[string][]Constant)
+	rConsts := unexportedField(da.binaryInfo, "consts")
+	if !rConsts.IsValid() {
+		return consts
+	}
+
+	images := da.binaryInfo.Images
+	iter := rConsts.MapRange()
+	for iter.Next() {
+		index := int(iter.Key().FieldByName("imageIndex").Int())
+		if index >= len(images) || da.imageStale(images[index]) != nil {
+			continue
+		}
+		img := images[index]
+		typ, err := img.Type(dwarf.Offset(iter.Key().FieldByName("offset").Uint()))
+		if err != nil {
+			continue
+		}
+		typeName := godwarfTypeName(typ)
+
+		values := iter.Value().Elem().FieldByName("values")
+		for i := 0; i < values.Len(); i++ {
+			v := values.Index(i)
+			name := v.FieldByName("name").String()
+			consts[name] = append(consts[name], Constant{Name: name, Type: typeName, Value: v.FieldByName("value").Int(), Image: img})
+		}
+	}
+
+	for _, defs := range consts {
+		sort.Slice(defs, func(i, j int) bool { return da.imageIndex(defs[i].Image) < da.imageIndex(defs[j].Image) })
+	}
+	return consts
+}
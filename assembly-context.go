@@ -0,0 +1,36 @@
+package assembly
+
+import (
+	"context"
+)
+
+// ForeachTypeContext is ForeachType stopping with the error of ctx once it is done.
+func (da *dwarfAssembly) ForeachTypeContext(ctx context.Context, f func(name string) bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	types, err := da.binaryInfo.Types()
+	if err != nil {
+		return err
+	}
+	visible := da.visibleFilter()
+	for _, name := range types {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		if !visible(name) {
+			continue
+		}
+		if !f(da.displayName(name)) {
+			break
+		}
+	}
+	return nil
+}
+
+// LoadGlobalsContext builds the globals cache ahead of the first global lookup. A build stopped
+// by ctx is discarded and the error of ctx returned, the next lookup starts over.
+func (da *dwarfAssembly) LoadGlobalsContext(ctx context.Context) error {
+	_, err := da.loadGlobals(ctx)
+	return err
+}
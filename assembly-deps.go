@@ -0,0 +1,141 @@
+package assembly
+
+import (
+	"debug/buildinfo"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+)
+
+// DependencyPolicy declares which dependency mismatches prevent a library from being loaded,
+// every other mismatch is reported as a warning by CheckDependencies.
+type DependencyPolicy struct {
+	// Fatal lists module path prefixes that must match the host exactly,
+	// typically modules whose types cross the patch boundary.
+	Fatal []string
+	// AllFatal makes every mismatch fatal.
+	AllFatal bool
+}
+
+func (p DependencyPolicy) fatal(path string) bool {
+	if p.AllFatal {
+		return true
+	}
+	for _, prefix := range p.Fatal {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// DependencyError reports the mismatches the dependency policy declared fatal.
+type DependencyError struct {
+	Path       string
+	Mismatches []DependencyMismatch
+}
+
+func (e *DependencyError) Error() string {
+	mismatches := make([]string, len(e.Mismatches))
+	for i, m := range e.Mismatches {
+		mismatches[i] = m.String()
+	}
+	return fmt.Sprintf("incompatible dependencies: %s: [%s]", e.Path, strings.Join(mismatches, ", "))
+}
+
+// DependencyMismatch is a module both the host and a library depend on at different versions.
+type DependencyMismatch struct {
+	Path           string
+	HostVersion    string
+	LibraryVersion string
+	HostSum        string
+	LibrarySum     string
+}
+
+func (m DependencyMismatch) String() string {
+	return fmt.Sprintf("%s: host %s, library %s", m.Path, m.HostVersion, m.LibraryVersion)
+}
+
+func (da *dwarfAssembly) CompareDependencies(path string) ([]DependencyMismatch, error) {
+	host, err := da.BuildInfo(da.binaryInfo.Images[0])
+	if err != nil {
+		return nil, err
+	}
+
+	lib, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read build info failed: %s: %w", path, err)
+	}
+
+	return compareDependencies(host, lib), nil
+}
+
+func compareDependencies(host, lib *buildinfo.BuildInfo) []DependencyMismatch {
+	hostDeps := make(map[string]*debug.Module, len(host.Deps))
+	for _, dep := range host.Deps {
+		hostDeps[dep.Path] = effectiveModule(dep)
+	}
+
+	var mismatches []DependencyMismatch
+	for _, dep := range lib.Deps {
+		hostDep, ok := hostDeps[dep.Path]
+		if !ok {
+			continue
+		}
+		libDep := effectiveModule(dep)
+		if hostDep.Path == libDep.Path && hostDep.Version == libDep.Version && hostDep.Sum == libDep.Sum {
+			continue
+		}
+		mismatches = append(mismatches, DependencyMismatch{
+			Path:           dep.Path,
+			HostVersion:    moduleVersion(hostDep),
+			LibraryVersion: moduleVersion(libDep),
+			HostSum:        hostDep.Sum,
+			LibrarySum:     libDep.Sum,
+		})
+	}
+
+	sort.Slice(mismatches, func(i, j int) bool { return mismatches[i].Path < mismatches[j].Path })
+	return mismatches
+}
+
+// effectiveModule returns the module actually compiled in, following replace directives.
+func effectiveModule(m *debug.Module) *debug.Module {
+	for m.Replace != nil {
+		m = m.Replace
+	}
+	return m
+}
+
+func moduleVersion(m *debug.Module) string {
+	if m.Version == "" {
+		return m.Path
+	}
+	return m.Path + "@" + m.Version
+}
+
+func (da *dwarfAssembly) SetDependencyPolicy(policy DependencyPolicy) {
+	da.depPolicy = policy
+}
+
+func (da *dwarfAssembly) CheckDependencies(path string) ([]DependencyMismatch, error) {
+	mismatches, err := da.CompareDependencies(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var warnings, fatal []DependencyMismatch
+	for _, m := range mismatches {
+		if da.depPolicy.fatal(m.Path) {
+			fatal = append(fatal, m)
+		} else {
+			warnings = append(warnings, m)
+		}
+	}
+
+	if len(fatal) > 0 {
+		return warnings, &DependencyError{Path: path, Mismatches: fatal}
+	}
+	return warnings, nil
+}
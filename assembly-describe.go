@@ -0,0 +1,146 @@
+package assembly
+
+import (
+	"debug/dwarf"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// Param is a formal parameter or result of a function.
+type Param struct {
+	Name string
+	Type string
+}
+
+// TypeParam is a type the compiler recorded in the dictionary of a generic instantiation.
+type TypeParam struct {
+	Name      string // compiler generated, e.g. ".param0"
+	DictIndex int64
+	Type      string
+}
+
+// FuncDescription describes a function resolved from DWARF.
+type FuncDescription struct {
+	Name       string
+	Entry, End uint64
+	Params     []Param
+	Results    []Param
+	TypeArgs   []string // type arguments of an instantiation as written in its name, e.g. "go.shape.int"
+	TypeParams []TypeParam
+}
+
+// TypeDescription describes a type resolved from DWARF.
+type TypeDescription struct {
+	Name     string
+	Kind     reflect.Kind
+	Size     uintptr
+	TypeArgs []string // type arguments of an instantiation as written in its name
+}
+
+func (da *dwarfAssembly) DescribeFunc(name string) (*FuncDescription, error) {
+	f, err := da.findFunc(name)
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := da.funcParams(f)
+	if nil != err {
+		return nil, fmt.Errorf("resolve function args failed: %s:%w", f.Name, err)
+	}
+
+	desc := &FuncDescription{Name: f.Name, Entry: f.Entry, End: f.End, TypeArgs: parseTypeArgs(f.Name)}
+	for _, arg := range args {
+		param := Param{Name: arg.name, Type: godwarfTypeName(arg.typ)}
+		if arg.isret {
+			desc.Results = append(desc.Results, param)
+		} else {
+			desc.Params = append(desc.Params, param)
+		}
+	}
+
+	if desc.TypeParams, err = da.funcTypeParams(f); err != nil {
+		return nil, err
+	}
+	return desc, nil
+}
+
+func (da *dwarfAssembly) DescribeType(name string) (*TypeDescription, error) {
+	rtyp, err := da.layoutType(name)
+	if err != nil {
+		return nil, err
+	}
+	return &TypeDescription{Name: name, Kind: rtyp.Kind(), Size: rtyp.Size(), TypeArgs: parseTypeArgs(name)}, nil
+}
+
+// funcTypeParams reads the dictionary typedefs the compiler emits as children of a generic instantiation.
+func (da *dwarfAssembly) funcTypeParams(f *proc.Function) ([]TypeParam, error) {
+	img := funcToImage(da.binaryInfo, f)
+	reader := da.acquireReader(img)
+	defer da.releaseReader(img, reader)
+	reader.Seek(funcOffset(f))
+	entry, err := reader.Next()
+	if err != nil || entry == nil || !entry.Children {
+		return nil, err
+	}
+
+	var params []TypeParam
+	for {
+		child, err := reader.Next()
+		if err != nil {
+			return nil, err
+		}
+		if child == nil || child.Tag == 0 {
+			break
+		}
+		if child.Tag == dwarf.TagTypedef {
+			if index, ok := child.Val(godwarf.AttrGoDictIndex).(int64); ok {
+				param := TypeParam{DictIndex: index}
+				param.Name, _ = child.Val(dwarf.AttrName).(string)
+				if off, ok := child.Val(dwarf.AttrType).(dwarf.Offset); ok {
+					if typ, err := img.Type(off); err == nil {
+						param.Type = godwarfTypeName(resolveTypedef(typ))
+					}
+				}
+				params = append(params, param)
+			}
+		}
+		if child.Children {
+			reader.SkipChildren()
+		}
+	}
+	return params, nil
+}
+
+// parseTypeArgs returns the type arguments of the outermost instantiation in name,
+// e.g. ["int", "string"] for "pkg.(*Pair[int,string]).Get".
+func parseTypeArgs(name string) []string {
+	start := strings.Index(name, "[")
+	if start < 0 {
+		return nil
+	}
+
+	var args []string
+	var depth = 0
+	var last = start + 1
+	for i := start; i < len(name); i++ {
+		switch name[i] {
+		case '[', '(', '{':
+			depth++
+		case ']', ')', '}':
+			depth--
+			if depth == 0 {
+				return append(args, name[last:i])
+			}
+		case ',':
+			if depth == 1 {
+				args = append(args, name[last:i])
+				last = i + 1
+			}
+		}
+	}
+	return nil
+}
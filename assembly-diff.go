@@ -0,0 +1,165 @@
+package assembly
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// ResultDiff is a difference between two results found by CompareResults. Path locates the
+// value from the result list, e.g. "[1].Items[2].Name", A and B are the formatted values,
+// "<missing>" where one side has no value at Path.
+type ResultDiff struct {
+	Path string
+	A, B string
+}
+
+const missingValue = "<missing>"
+
+// CompareResults compares the results of two calls of a function, e.g. of the original and of
+// its replacement, descending into structs, pointers, interfaces, slices, arrays and maps down
+// to the differing leaves. Unexported fields are compared too; functions and channels are equal
+// when they are the same. The differences are returned in the order of the results and fields.
+func CompareResults(a, b []reflect.Value) []ResultDiff {
+	c := resultComparer{visited: make(map[[2]uintptr]bool)}
+	for i := 0; i < len(a) || i < len(b); i++ {
+		path := fmt.Sprintf("[%d]", i)
+		switch {
+		case i >= len(a):
+			c.diff(path, missingValue, formatValue(b[i]))
+		case i >= len(b):
+			c.diff(path, formatValue(a[i]), missingValue)
+		default:
+			c.compare(path, a[i], b[i])
+		}
+	}
+	return c.diffs
+}
+
+type resultComparer struct {
+	diffs   []ResultDiff
+	visited map[[2]uintptr]bool // pointer pairs being compared, values may be cyclic
+}
+
+func (c *resultComparer) diff(path, a, b string) {
+	c.diffs = append(c.diffs, ResultDiff{Path: path, A: a, B: b})
+}
+
+func (c *resultComparer) compare(path string, a, b reflect.Value) {
+	if !a.IsValid() || !b.IsValid() {
+		if a.IsValid() != b.IsValid() {
+			c.diff(path, formatValue(a), formatValue(b))
+		}
+		return
+	}
+	if a.Type() != b.Type() {
+		c.diff(path, formatValue(a), formatValue(b))
+		return
+	}
+
+	switch a.Kind() {
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			c.compare(path+"."+a.Type().Field(i).Name, a.Field(i), b.Field(i))
+		}
+	case reflect.Pointer:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				c.diff(path, formatValue(a), formatValue(b))
+			}
+			return
+		}
+		key := [2]uintptr{a.Pointer(), b.Pointer()}
+		if key[0] == key[1] || c.visited[key] {
+			return
+		}
+		c.visited[key] = true
+		c.compare(path, a.Elem(), b.Elem())
+	case reflect.Interface:
+		c.compare(path, a.Elem(), b.Elem())
+	case reflect.Slice, reflect.Array:
+		if a.Kind() == reflect.Slice && a.IsNil() != b.IsNil() {
+			c.diff(path, formatValue(a), formatValue(b))
+			return
+		}
+		for i := 0; i < a.Len() || i < b.Len(); i++ {
+			elem := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= a.Len():
+				c.diff(elem, missingValue, formatValue(b.Index(i)))
+			case i >= b.Len():
+				c.diff(elem, formatValue(a.Index(i)), missingValue)
+			default:
+				c.compare(elem, a.Index(i), b.Index(i))
+			}
+		}
+	case reflect.Map:
+		if a.IsNil() != b.IsNil() {
+			c.diff(path, formatValue(a), formatValue(b))
+			return
+		}
+		for _, key := range mapKeys(a, b) {
+			elem := fmt.Sprintf("%s[%#v]", path, key)
+			av, bv := a.MapIndex(key), b.MapIndex(key)
+			switch {
+			case !av.IsValid():
+				c.diff(elem, missingValue, formatValue(bv))
+			case !bv.IsValid():
+				c.diff(elem, formatValue(av), missingValue)
+			default:
+				c.compare(elem, av, bv)
+			}
+		}
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		if a.Pointer() != b.Pointer() {
+			c.diff(path, formatValue(a), formatValue(b))
+		}
+	default:
+		if !leafEqual(a, b) {
+			c.diff(path, formatValue(a), formatValue(b))
+		}
+	}
+}
+
+// leafEqual compares values of a basic kind, without Interface which unexported fields refuse.
+func leafEqual(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Bool:
+		return a.Bool() == b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() == b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() == b.Uint()
+	case reflect.Float32, reflect.Float64:
+		// NaN results of both calls agree
+		return a.Float() == b.Float() || (a.Float() != a.Float() && b.Float() != b.Float())
+	case reflect.Complex64, reflect.Complex128:
+		return a.Complex() == b.Complex()
+	case reflect.String:
+		return a.String() == b.String()
+	}
+	return false
+}
+
+// mapKeys returns the keys of either map, sorted by their formatting.
+func mapKeys(a, b reflect.Value) []reflect.Value {
+	seen := make(map[string]bool)
+	var keys []reflect.Value
+	for _, m := range []reflect.Value{a, b} {
+		for _, key := range m.MapKeys() {
+			if s := fmt.Sprintf("%#v", key); !seen[s] {
+				seen[s] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return fmt.Sprintf("%#v", keys[i]) < fmt.Sprintf("%#v", keys[j]) })
+	return keys
+}
+
+func formatValue(v reflect.Value) string {
+	if !v.IsValid() {
+		return "<invalid>"
+	}
+	return fmt.Sprintf("%#v", v)
+}
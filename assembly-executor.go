@@ -0,0 +1,88 @@
+package assembly
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
+)
+
+// Executor invokes one resolved function from a bounded pool of worker goroutines.
+// The function holds a handle on its image until the executor is closed.
+type Executor struct {
+	da      *dwarfAssembly
+	call    *preparedCall
+	handle  *Handle
+	workers int
+}
+
+// NewExecutor resolves the function name once for fanning out calls over at most workers goroutines,
+// workers <= 0 selects GOMAXPROCS.
+func (da *dwarfAssembly) NewExecutor(name string, variadic bool, workers int) (*Executor, error) {
+	f, err := da.findFunc(name)
+	if err != nil {
+		return nil, err
+	}
+	call, err := da.prepareCall(f, variadic)
+	if err != nil {
+		return nil, err
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	handle := da.newHandle(call.fn, da.binaryInfo.PCToImage(f.Entry))
+	return &Executor{da: da, call: call, handle: handle, workers: workers}, nil
+}
+
+// Run performs n calls, the i-th with the arguments returned by args(i), and reports every outcome
+// to done from the worker that made the call. Each call is checked and rate limited like CallFunc,
+// a panic of the callee is reported as an error. Run returns once all calls completed; args and
+// done must be safe for concurrent use.
+func (e *Executor) Run(n int, args func(i int) []reflect.Value, done func(i int, out []reflect.Value, err error)) error {
+	if _, err := e.handle.Value(); err != nil {
+		return err
+	}
+
+	var next = make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < e.workers && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				out, err := e.invoke(args(i))
+				done(i, out, err)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return nil
+}
+
+// Close releases the function, calls through a closed executor fail with ErrReleased.
+func (e *Executor) Close() {
+	e.handle.Release()
+}
+
+// invoke makes one call, converting a panic into an error.
+func (e *Executor) invoke(args []reflect.Value) (out []reflect.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = fmt.Errorf("call panicked: %w", e)
+			} else {
+				err = fmt.Errorf("call panicked: %v", r)
+			}
+		}
+	}()
+	res, err := e.da.call(e.call, args)
+	if err != nil {
+		return nil, err
+	}
+	return res.Values, nil
+}
@@ -2,15 +2,20 @@ package assembly
 
 import (
 	"fmt"
+	"math"
 	"reflect"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/go-delve/delve/pkg/proc"
 )
 
 func (da *dwarfAssembly) ForeachFunc(f func(name string, pc uint64) bool) {
-	for _, function := range da.binaryInfo.Functions {
-		if function.Entry != 0 {
-			if !f(function.Name, function.Entry) {
+	visible := da.visibleFilter()
+	for _, function := range da.snapshot().functions {
+		if function.Entry != 0 && visible(function.Name) {
+			if !f(da.displayName(function.Name), function.Entry) {
 				break
 			}
 		}
@@ -25,6 +30,28 @@ func (da *dwarfAssembly) FindFuncEntry(name string) (*proc.Function, error) {
 	return f, nil
 }
 
+func (da *dwarfAssembly) FindGenericMethod(typeName string, typeArgs []string, method string) (*proc.Function, error) {
+	pkg, base := splitPackagePath(typeName)
+	if pkg == "" || len(typeArgs) == 0 {
+		return nil, ErrNotFound
+	}
+
+	shapeArgs := make([]string, len(typeArgs))
+	for i, arg := range typeArgs {
+		shapeArgs[i] = "go.shape." + arg
+	}
+
+	for _, args := range [][]string{typeArgs, shapeArgs} {
+		inst := base + "[" + strings.Join(args, ",") + "]"
+		for _, name := range []string{pkg + "." + inst + "." + method, pkg + ".(*" + inst + ")." + method} {
+			if f, err := da.findFunc(name); err == nil {
+				return f, nil
+			}
+		}
+	}
+	return nil, ErrNotFound
+}
+
 func (da *dwarfAssembly) FindFuncPc(name string) (uint64, error) {
 	f, err := da.findFunc(name)
 	if err != nil {
@@ -33,47 +60,155 @@ func (da *dwarfAssembly) FindFuncPc(name string) (uint64, error) {
 	return f.Entry, nil
 }
 
+// funcTypeKey identifies a signature resolved by FindFuncType, keyed by function rather
+// than by name since the resolve policy decides which image a name resolves to.
+type funcTypeKey struct {
+	fn       *proc.Function
+	variadic bool
+}
+
+// FindFuncType resolves the signature of the function name as it is called, the receiver of a
+// method is its first parameter. FindMethodType reports the receiver separately.
 func (da *dwarfAssembly) FindFuncType(name string, variadic bool) (reflect.Type, error) {
 	f, err := da.findFunc(name)
 	if err != nil {
 		return nil, err
 	}
+	return da.funcTypeOf(f, variadic)
+}
+
+// funcTypeOf returns the signature of the resolved function f.
+func (da *dwarfAssembly) funcTypeOf(f *proc.Function, variadic bool) (reflect.Type, error) {
+	key := funcTypeKey{fn: f, variadic: variadic}
+	da.cacheMu.Lock()
+	ftyp, ok := da.funcTypes[key]
+	da.cacheMu.Unlock()
+	if ok {
+		return ftyp, nil
+	}
+
 	inTyps, outTyps, _, _, err := da.getFunctionArgTypes(f)
 	if err != nil {
 		return nil, err
 	}
 
-	ftyp := reflect.FuncOf(inTyps, outTyps, variadic)
+	ftyp = reflect.FuncOf(inTyps, outTyps, variadic)
+	da.cacheMu.Lock()
+	defer da.cacheMu.Unlock()
+	if da.funcTypes == nil {
+		da.funcTypes = make(map[funcTypeKey]reflect.Type)
+	}
+	da.funcTypes[key] = ftyp
 	return ftyp, nil
 }
 
 func (da *dwarfAssembly) FindFunc(name string, variadic bool) (reflect.Value, error) {
-	pc, err := da.FindFuncPc(name)
+	f, err := da.findFunc(name)
 	if err != nil {
 		return reflect.Value{}, err
 	}
-	ftyp, err := da.FindFuncType(name, variadic)
+	return da.findFuncOf(f, variadic)
+}
+
+// findFuncOf returns a function value calling the resolved function f.
+func (da *dwarfAssembly) findFuncOf(f *proc.Function, variadic bool) (reflect.Value, error) {
+	if err := da.checkCallABI(f); err != nil {
+		return reflect.Value{}, err
+	}
+	if err := da.checkClosure(f); err != nil {
+		return reflect.Value{}, err
+	}
+	ftyp, err := da.funcTypeOf(f, variadic)
 	if err != nil {
 		return reflect.Value{}, err
 	}
 
-	newFunc := CreateFuncForCodePtr(ftyp, pc)
-	return newFunc, nil
+	return da.callableFunc(f, ftyp)
 }
 
 func (da *dwarfAssembly) CallFunc(name string, variadic bool, args []reflect.Value) ([]reflect.Value, error) {
+	res, err := da.CallFuncResult(name, variadic, args)
+	if err != nil {
+		return nil, err
+	}
+	return res.Values, nil
+}
+
+func (da *dwarfAssembly) CallFuncResult(name string, variadic bool, args []reflect.Value) (*CallResult, error) {
 	f, err := da.findFunc(name)
 	if err != nil {
 		return nil, err
 	}
+	return da.callFunc(f, variadic, args)
+}
+
+// callFunc calls the resolved function f, checking args against its parameters.
+func (da *dwarfAssembly) callFunc(f *proc.Function, variadic bool, args []reflect.Value) (*CallResult, error) {
+	c, err := da.prepareCall(f, variadic)
+	if err != nil {
+		return nil, err
+	}
+	return da.call(c, args)
+}
+
+// preparedCall is a function resolved once for calls, the arguments of every call are checked
+// against its parameters.
+type preparedCall struct {
+	f        *proc.Function
+	fn       reflect.Value
+	variadic bool
+	inTyps   []reflect.Type
+	outTyps  []reflect.Type
+	inNames  []string
+	outNames []string
+}
 
-	inTyps, outTyps, inNames, _, err := da.getFunctionArgTypes(f)
+// prepareCall checks f can be called through reflect and creates the function value calling it.
+func (da *dwarfAssembly) prepareCall(f *proc.Function, variadic bool) (*preparedCall, error) {
+	err := da.checkCallABI(f)
+	if err != nil {
+		return nil, err
+	}
+	if err = da.checkClosure(f); err != nil {
+		return nil, err
+	}
+
+	inTyps, outTyps, inNames, outNames, err := da.getFunctionArgTypes(f)
 	if err != nil {
 		return nil, err
 	}
 
 	ftyp := reflect.FuncOf(inTyps, outTyps, variadic)
-	newFunc := CreateFuncForCodePtr(ftyp, f.Entry)
+	newFunc, err := da.callableFunc(f, ftyp)
+	if err != nil {
+		return nil, err
+	}
+	return &preparedCall{f: f, fn: newFunc, variadic: variadic, inTyps: inTyps, outTyps: outTyps, inNames: inNames, outNames: outNames}, nil
+}
+
+// call calls c with args, once they match its parameters and pass the call policy and the image
+// of the function is still mapped. Every call made on behalf of a caller of the assembly goes
+// through call, which takes its token from the call rate limit.
+func (da *dwarfAssembly) call(c *preparedCall, args []reflect.Value) (*CallResult, error) {
+	if err := da.limit(&da.limits.calls, "call", da.displayName(c.f.Name)); err != nil {
+		return nil, err
+	}
+	if err := da.imageStale(funcToImage(da.binaryInfo, c.f)); err != nil {
+		return nil, err
+	}
+	if err := da.checkArgs(c, args); err != nil {
+		return nil, err
+	}
+	out := c.fn.Call(args)
+	return &CallResult{Values: out, Names: c.outNames}, nil
+}
+
+// checkArgs checks the count and types of args against the parameters of c and the call policy.
+func (da *dwarfAssembly) checkArgs(c *preparedCall, args []reflect.Value) error {
+	inTyps, inNames := c.inTyps, c.inNames
+	if c.variadic && len(args) < len(inTyps)-1 || !c.variadic && len(args) < len(inTyps) {
+		return fmt.Errorf("len mismatch, except %d args, got %d", len(inTyps), len(args))
+	}
 
 	getInTyp := func(i int) (reflect.Type, string) {
 		if len(inTyps) <= 0 {
@@ -82,7 +217,7 @@ func (da *dwarfAssembly) CallFunc(name string, variadic bool, args []reflect.Val
 		if i < len(inTyps)-1 {
 			return inTyps[i], inNames[i]
 		}
-		if variadic {
+		if c.variadic {
 			return inTyps[len(inTyps)-1].Elem(), inNames[len(inNames)-1]
 		}
 		if i < len(inTyps) {
@@ -94,28 +229,185 @@ func (da *dwarfAssembly) CallFunc(name string, variadic bool, args []reflect.Val
 	for i, arg := range args {
 		inTyp, inName := getInTyp(i)
 		if inTyp == nil {
-			return nil, fmt.Errorf("len mismatch %d", i)
+			return fmt.Errorf("len mismatch %d", i)
+		}
+
+		if !arg.IsValid() || !arg.Type().AssignableTo(inTyp) {
+			return fmt.Errorf("type mismatch arg: %d:%s, except: %s, got: %v", i, inName, inTyp.String(), argType(arg))
+		}
+
+		if err := da.checkAddressArg(inTyp, arg); err != nil {
+			return fmt.Errorf("arg: %d:%s: %w", i, inName, err)
 		}
+	}
+	return nil
+}
+
+// argType returns the type of arg for error messages, "invalid" for the zero Value.
+func argType(arg reflect.Value) string {
+	if !arg.IsValid() {
+		return "invalid"
+	}
+	return arg.Type().String()
+}
+
+// CallFuncNamed calls the function name with arguments matched by their DWARF parameter names.
+// Argument values are converted to the parameter types when the conversion is lossless, a nil
+// value passes the zero value. Missing and unexpected names are reported by an *ArgumentError.
+func (da *dwarfAssembly) CallFuncNamed(name string, args map[string]any) (*CallResult, error) {
+	f, err := da.findFunc(name)
+	if err != nil {
+		return nil, err
+	}
+
+	inTyps, _, inNames, _, err := da.getFunctionArgTypes(f)
+	if err != nil {
+		return nil, err
+	}
 
-		if !arg.Type().AssignableTo(inTyp) {
-			return nil, fmt.Errorf("type mismatch arg: %d:%s, except: %s, got: %s", i, inName, inTyp.String(), arg.Type().String())
+	var argErr = &ArgumentError{Func: name}
+	var values = make([]reflect.Value, len(inTyps))
+	for i, inName := range inNames {
+		arg, ok := args[inName]
+		if !ok {
+			argErr.Missing = append(argErr.Missing, inName)
+			continue
 		}
+		if values[i], err = convertArg(arg, inTyps[i]); err != nil {
+			return nil, fmt.Errorf("%s: arg %s: %w", name, inName, err)
+		}
+	}
+	for argName := range args {
+		if !slices.Contains(inNames, argName) {
+			argErr.Extra = append(argErr.Extra, argName)
+		}
+	}
+	if len(argErr.Missing) > 0 || len(argErr.Extra) > 0 {
+		sort.Strings(argErr.Extra)
+		return nil, argErr
 	}
 
-	out := newFunc.Call(args)
-	return out, nil
+	// a variadic parameter is passed as the slice it is received as.
+	return da.CallFuncResult(name, false, values)
+}
+
+// convertArg converts arg to typ, refusing conversions that lose information.
+func convertArg(arg any, typ reflect.Type) (reflect.Value, error) {
+	if arg == nil {
+		return reflect.Zero(typ), nil
+	}
+
+	v := reflect.ValueOf(arg)
+	if v.Type().AssignableTo(typ) {
+		return v, nil
+	}
+	if isNumberKind(v.Kind()) && isNumberKind(typ.Kind()) {
+		if overflowsNumber(v, typ) {
+			return reflect.Value{}, fmt.Errorf("%v overflows %s", arg, typ)
+		}
+		converted := v.Convert(typ)
+		if converted.Convert(v.Type()).Interface() != v.Interface() {
+			return reflect.Value{}, fmt.Errorf("%v overflows or truncates %s", arg, typ)
+		}
+		return converted, nil
+	}
+	if v.Kind() == typ.Kind() && v.Type().ConvertibleTo(typ) {
+		return v.Convert(typ), nil
+	}
+	return reflect.Value{}, fmt.Errorf("cannot use %s as %s", v.Type(), typ)
+}
+
+func isNumberKind(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Float64
+}
+
+// overflowsNumber reports whether the number v lies outside the range of the number type typ,
+// a negative value converted to an unsigned type included.
+func overflowsNumber(v reflect.Value, typ reflect.Type) bool {
+	target := reflect.Zero(typ)
+	switch {
+	case v.CanInt():
+		switch {
+		case target.CanInt():
+			return target.OverflowInt(v.Int())
+		case target.CanUint():
+			return v.Int() < 0 || target.OverflowUint(uint64(v.Int()))
+		}
+	case v.CanUint():
+		switch {
+		case target.CanInt():
+			return v.Uint() > math.MaxInt64 || target.OverflowInt(int64(v.Uint()))
+		case target.CanUint():
+			return target.OverflowUint(v.Uint())
+		}
+	case v.CanFloat():
+		switch f := v.Float(); {
+		case target.CanInt():
+			return !(f >= math.MinInt64 && f < math.MaxInt64) || target.OverflowInt(int64(f))
+		case target.CanUint():
+			return !(f >= 0 && f < math.MaxUint64) || target.OverflowUint(uint64(f))
+		case target.CanFloat():
+			return target.OverflowFloat(f)
+		}
+	}
+	return false
+}
+
+func (da *dwarfAssembly) ResolveFunc(name string) (*proc.Function, *proc.Image, error) {
+	return da.resolveFunc(name, da.policy.Priority)
+}
+
+// resolveFunc is ResolveFunc preferring the images of priority over the resolve order.
+func (da *dwarfAssembly) resolveFunc(name string, priority []string) (*proc.Function, *proc.Image, error) {
+	var fns []*proc.Function
+	if _, ok := da.resolveName(name, func(name string) bool {
+		fns, _ = da.binaryInfo.FindFunction(name)
+		return nil != fns
+	}); !ok {
+		return nil, nil, ErrNotFound
+	}
+
+	// Definitions in images found unmapped by CheckLayout are skipped.
+	var staleErr error
+	images := make([]*proc.Image, 0, len(fns))
+	mapped := make([]*proc.Function, 0, len(fns))
+	for _, fn := range fns {
+		img := funcToImage(da.binaryInfo, fn)
+		if err := da.imageStale(img); err != nil {
+			staleErr = err
+			continue
+		}
+		images = append(images, img)
+		mapped = append(mapped, fn)
+	}
+	if fns = mapped; len(fns) == 0 {
+		return nil, nil, staleErr
+	}
+
+	chosen := da.preferredImageOf(images, len(fns)-1, priority)
+	da.traceChosen(fns[chosen].Name, images, chosen)
+	return fns[chosen], images[chosen], nil
 }
 
 func (da *dwarfAssembly) findFunc(name string) (*proc.Function, error) {
-	if fns, _ := da.binaryInfo.FindFunction(name); nil != fns {
-		return fns[len(fns)-1], nil
+	f, _, err := da.ResolveFunc(name)
+	return f, err
+}
+
+// splitPackagePath splits a qualified name like "github.com/x/pkg.Name" into its package path and name.
+func splitPackagePath(name string) (string, string) {
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return "", name
 	}
-	return nil, ErrNotFound
+	dot += slash + 1
+	return name[:dot], name[dot+1:]
 }
 
 func (da *dwarfAssembly) getFunctionArgTypes(f *proc.Function) ([]reflect.Type, []reflect.Type, []string, []string, error) {
 
-	_, args, err := funcCallArgs(f, da.binaryInfo, true)
+	args, err := da.funcParams(f)
 	if nil != err {
 		return nil, nil, nil, nil, fmt.Errorf("resolve function args failed: %s:%w", f.Name, err)
 	}
@@ -126,10 +418,10 @@ func (da *dwarfAssembly) getFunctionArgTypes(f *proc.Function) ([]reflect.Type,
 	var outNames []string
 
 	for idx, arg := range args {
-		argType := resolveTypedef(arg.typ)
-		rtyp, err := da.FindType(argType.String())
+		argType := godwarfTypeName(arg.typ)
+		rtyp, err := da.layoutType(argType)
 		if err != nil {
-			return nil, nil, nil, nil, fmt.Errorf("resolve function arg failed: %s arg: %d: (%s %s): %w", f.Name, idx, arg.name, argType.String(), err)
+			return nil, nil, nil, nil, fmt.Errorf("resolve function arg failed: %s arg: %d: (%s %s): %w", f.Name, idx, arg.name, argType, err)
 		}
 
 		if arg.isret {
@@ -0,0 +1,71 @@
+package assembly
+
+import (
+	"reflect"
+	"sync/atomic"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// funcKey identifies a created function value by signature and code pointer.
+type funcKey struct {
+	typ reflect.Type
+	pc  uint64
+}
+
+// createdFunc is a forged function value together with the image its code lives in.
+// The value stays valid only while that image is loaded, once the image is gone
+// the code pointer is swapped back to the reflect stub, which panics with ErrImageUnloaded.
+type createdFunc struct {
+	value    reflect.Value
+	image    *proc.Image
+	stubPtr  uintptr
+	unloaded atomic.Bool
+}
+
+func unloadedFunc([]reflect.Value) []reflect.Value {
+	panic(ErrImageUnloaded)
+}
+
+// createFunc returns the function value of type ftyp calling the code at pc.
+//
+// Values are created once per (type, pc) and retained by the assembly until Close,
+// so the heap allocated funcval whose code pointer was swapped is never collected while
+// the assembly may still hand it out, and repeated lookups share the same allocation
+// instead of forging a new funcval every time.
+func (da *dwarfAssembly) createFunc(ftyp reflect.Type, pc uint64) reflect.Value {
+	key := funcKey{typ: ftyp, pc: pc}
+	da.cacheMu.Lock()
+	defer da.cacheMu.Unlock()
+	if f, ok := da.funcs[key]; ok {
+		return f.value
+	}
+
+	if da.funcs == nil {
+		da.funcs = make(map[funcKey]*createdFunc)
+	}
+	value, stubPtr := forgeFunc(ftyp, pc, unloadedFunc)
+	f := &createdFunc{value: value, image: da.binaryInfo.PCToImage(pc), stubPtr: stubPtr}
+	da.funcs[key] = f
+	return f.value
+}
+
+// invalidateImage detaches every function value and handle issued for img,
+// so later use reports ErrImageUnloaded instead of touching unmapped memory.
+func (da *dwarfAssembly) invalidateImage(img *proc.Image) {
+	da.cacheMu.Lock()
+	defer da.cacheMu.Unlock()
+	for key, f := range da.funcs {
+		if f.image == img {
+			f.unloaded.Store(true)
+			funcValue(f.value).codePtr = f.stubPtr
+			delete(da.funcs, key)
+		}
+	}
+
+	for h := range da.handles[img] {
+		h.invalidate(ErrImageUnloaded)
+	}
+	delete(da.handles, img)
+	da.resetReaders(img)
+}
@@ -0,0 +1,83 @@
+package assembly
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// FuzzTarget is a function whose parameters are all basic types or slices of basic types,
+// so arguments can be generated without knowledge of the program.
+type FuzzTarget struct {
+	Name  string
+	Image *proc.Image
+	Type  reflect.Type
+}
+
+// compilerFuncName matches functions the compiler generates for closures, range-over-func
+// bodies and go/defer statements, which expect a closure context and cannot be called directly.
+var compilerFuncName = regexp.MustCompile(`\.(func\d+|gowrap\d+|deferwrap\d+)(\.|$)|-range\d+`)
+
+// FuzzTargets enumerates the callable functions of every loaded image with simple signatures,
+// filter selects functions by name and may be nil. Closures, generic instantiations and
+// functions compiled for a foreign calling convention are skipped.
+func (da *dwarfAssembly) FuzzTargets(filter func(name string) bool) []FuzzTarget {
+	var targets []FuzzTarget
+	functions := da.snapshot().functions
+	for i := range functions {
+		f := &functions[i]
+		if f.Entry == 0 || strings.Contains(f.Name, "[") || compilerFuncName.MatchString(f.Name) {
+			continue
+		}
+		if filter != nil && !filter(f.Name) {
+			continue
+		}
+		if da.checkCallABI(f) != nil || !da.simpleParams(f) {
+			continue
+		}
+
+		inTyps, outTyps, _, _, err := da.getFunctionArgTypes(f)
+		if err != nil {
+			continue
+		}
+		targets = append(targets, FuzzTarget{
+			Name:  da.displayName(f.Name),
+			Image: funcToImage(da.binaryInfo, f),
+			Type:  reflect.FuncOf(inTyps, outTyps, false),
+		})
+	}
+	return targets
+}
+
+// simpleParams reports whether every parameter of f is a basic type or a slice of one.
+func (da *dwarfAssembly) simpleParams(f *proc.Function) bool {
+	params, err := da.funcParams(f)
+	if err != nil {
+		return false
+	}
+	for _, param := range params {
+		if param.isret {
+			continue
+		}
+		typ := resolveTypedef(param.typ)
+		if slice, ok := typ.(*godwarf.SliceType); ok {
+			typ = resolveTypedef(slice.ElemType)
+		}
+		if !basicType(typ) {
+			return false
+		}
+	}
+	return true
+}
+
+func basicType(typ godwarf.Type) bool {
+	switch typ.(type) {
+	case *godwarf.BoolType, *godwarf.IntType, *godwarf.UintType, *godwarf.FloatType,
+		*godwarf.ComplexType, *godwarf.StringType, *godwarf.CharType, *godwarf.UcharType:
+		return true
+	}
+	return false
+}
@@ -1,40 +1,354 @@
 package assembly
 
 import (
+	"context"
 	"debug/dwarf"
+	"fmt"
 	"reflect"
+	"sort"
 	"unsafe"
 
+	"github.com/go-delve/delve/pkg/dwarf/reader"
 	"github.com/go-delve/delve/pkg/proc"
 )
 
+// imageGlobal is a global variable defined by one loaded image.
+type imageGlobal struct {
+	image    *proc.Image
+	value    reflect.Value
+	typeName string
+}
+
 func (da *dwarfAssembly) FindGlobal(name string) (reflect.Value, error) {
-	if nil == da.globals {
-		da.loadGlobals()
+	value, _, err := da.ResolveGlobal(name)
+	return value, err
+}
+
+// ResolveGlobal resolves the global variable name and the image defining it. Until the globals
+// cache is built by ForeachGlobal, LoadGlobalsContext or WarmCaches, only the variables named
+// name are resolved.
+func (da *dwarfAssembly) ResolveGlobal(name string) (reflect.Value, *proc.Image, error) {
+	return da.resolveGlobal(name, da.policy.Priority)
+}
+
+// resolveGlobal is ResolveGlobal preferring the images of priority over the resolve order.
+func (da *dwarfAssembly) resolveGlobal(name string, priority []string) (reflect.Value, *proc.Image, error) {
+	if name, ok := da.resolveName(name, da.hasGlobal); ok {
+		if defs := da.namedGlobals(name); len(defs) > 0 {
+			g := da.preferredGlobalOf(defs, priority)
+			if da.tracing() {
+				da.trace(ResolveStep{Step: "chosen", Name: name, Image: g.image, Detail: fmt.Sprintf("of %d images", len(defs))})
+			}
+			return g.value, g.image, nil
+		}
+	}
+	return reflect.Value{}, nil, ErrNotFound
+}
+
+// FindGlobalAddr returns the address of the global variable name. Unlike FindGlobal it does not
+// need a runtime type for the variable, the address is suitable for unsafe.Pointer arguments.
+func (da *dwarfAssembly) FindGlobalAddr(name string) (uintptr, error) {
+	var images []*proc.Image
+	var addrs []uint64
+	da.resolveName(name, func(name string) bool {
+		da.walkPackageVars(func(v packageVar) bool {
+			if v.name == name {
+				images = append(images, v.image)
+				addrs = append(addrs, v.addr)
+			}
+			return true
+		})
+		return len(addrs) > 0
+	})
+	if len(addrs) == 0 {
+		return 0, ErrNotFound
+	}
+	return uintptr(addrs[da.preferredImage(images, len(addrs)-1)]), nil
+}
+
+// SetGlobal assigns value to the global variable name, value must be assignable to its type.
+// Writes to protected symbols are refused unless the mutation policy allows them.
+func (da *dwarfAssembly) SetGlobal(name string, value reflect.Value) error {
+	name, _ = da.resolveName(name, da.hasGlobal)
+	if err := da.checkMutation(name); err != nil {
+		return err
+	}
+	if err := da.limit(&da.limits.globals, "set", name); err != nil {
+		return err
+	}
+
+	global, err := da.FindGlobal(name)
+	if err != nil {
+		return err
+	}
+	if !value.IsValid() || !value.Type().AssignableTo(global.Type()) {
+		return fmt.Errorf("%s: cannot assign %s to %s", name, argType(value), global.Type())
+	}
+	global.Set(value)
+	return nil
+}
+
+func (da *dwarfAssembly) hasGlobal(name string) bool {
+	return len(da.namedGlobals(name)) > 0
+}
+
+// globalNames returns the DWARF names of the global variables, without resolving their types
+// unless the globals cache is built.
+func (da *dwarfAssembly) globalNames() []string {
+	da.globalsMu.Lock()
+	defer da.globalsMu.Unlock()
+	var names []string
+	if da.globals != nil {
+		for name := range da.globals {
+			names = append(names, name)
+		}
+	} else {
+		for name := range da.packageVarsByName() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// packageVarsByName indexes the package variables recorded by delve by name, the caller holds globalsMu.
+func (da *dwarfAssembly) packageVarsByName() map[string][]packageVar {
+	if da.lazyGlobals.vars == nil {
+		vars := make(map[string][]packageVar)
+		da.walkPackageVars(func(v packageVar) bool {
+			vars[v.name] = append(vars[v.name], v)
+			return true
+		})
+		da.lazyGlobals.vars = vars
+	}
+	return da.lazyGlobals.vars
+}
+
+// lazyGlobals holds the globals resolved one name at a time while the globals cache is not built.
+type lazyGlobals struct {
+	vars     map[string][]packageVar
+	resolved map[string][]imageGlobal
+}
+
+// namedGlobals returns the definitions of the global name from the globals cache once it is
+// built, and otherwise resolves the types of only the package variables named name.
+func (da *dwarfAssembly) namedGlobals(name string) []imageGlobal {
+	da.globalsMu.Lock()
+	defer da.globalsMu.Unlock()
+	if da.globals != nil {
+		return da.globals[name]
+	}
+	if defs, ok := da.lazyGlobals.resolved[name]; ok {
+		return defs
 	}
 
-	if value, ok := da.globals[name]; ok {
-		return value, nil
+	readers := imageReaders{da: da}
+	defer readers.release()
+	var defs []imageGlobal
+	for _, v := range da.packageVarsByName()[name] {
+		if da.imageStale(v.image) != nil {
+			continue
+		}
+		if g, ok := da.resolveVar(v, readers.get(v.image)); ok {
+			defs = append(defs, g)
+		}
+	}
+	if da.lazyGlobals.resolved == nil {
+		da.lazyGlobals.resolved = make(map[string][]imageGlobal)
 	}
-	return reflect.Value{}, ErrNotFound
+	da.lazyGlobals.resolved[name] = defs
+	return defs
 }
 
 func (da *dwarfAssembly) ForeachGlobal(fn func(name string, value reflect.Value) bool) {
-	if nil == da.globals {
-		da.loadGlobals()
+	for name, defs := range da.globalsCache() {
+		if !fn(da.displayName(name), da.preferredGlobal(defs).value) {
+			break
+		}
+	}
+}
+
+func (da *dwarfAssembly) preferredGlobal(defs []imageGlobal) imageGlobal {
+	return da.preferredGlobalOf(defs, da.policy.Priority)
+}
+
+// preferredGlobalOf is preferredGlobal with priority in place of the priority of the policy.
+func (da *dwarfAssembly) preferredGlobalOf(defs []imageGlobal, priority []string) imageGlobal {
+	images := make([]*proc.Image, len(defs))
+	for i, def := range defs {
+		images[i] = def.image
 	}
+	return defs[da.preferredImageOf(images, len(defs)-1, priority)]
+}
+
+// StreamGlobals walks the DWARF variables on the fly and yields their values without building
+// or retaining the globals cache. Unlike ForeachGlobal, a variable defined by several images is
+// yielded once per image, in load order, and the resolve policy is not applied.
+func (da *dwarfAssembly) StreamGlobals(fn func(name string, value reflect.Value) bool) {
+	da.walkGlobals(nil, func(name string, g imageGlobal) bool {
+		return fn(da.displayName(name), g.value)
+	})
+}
 
-	for name, value := range da.globals {
-		if !fn(name, value) {
+// globalsCache returns the globals of every image by name, building them on first use.
+// Concurrent callers wait for a single build.
+func (da *dwarfAssembly) globalsCache() map[string][]imageGlobal {
+	globals, _ := da.loadGlobals(context.Background())
+	return globals
+}
+
+// loadGlobals builds the globals cache unless it exists, a build stopped by ctx is not kept.
+func (da *dwarfAssembly) loadGlobals(ctx context.Context) (map[string][]imageGlobal, error) {
+	da.globalsMu.Lock()
+	defer da.globalsMu.Unlock()
+	if da.globals != nil {
+		return da.globals, nil
+	}
+
+	var err error
+	var globals = make(map[string][]imageGlobal)
+	var indexed = make(map[*proc.Image]bool)
+	var walked = make(map[*proc.Image][]persistedGlobal)
+	for _, img := range da.binaryInfo.Images {
+		if index := da.persistedIndex(img); index != nil && index.Globals != nil && da.imageStale(img) == nil {
+			for _, pg := range index.Globals {
+				if g, ok := da.indexedGlobal(img, pg); ok {
+					globals[pg.Name] = append(globals[pg.Name], g)
+				}
+			}
+			indexed[img] = true
+		} else if index != nil {
+			walked[img] = []persistedGlobal{}
+		}
+	}
+
+	da.walkGlobals(indexed, func(name string, g imageGlobal) bool {
+		if err = ctx.Err(); err != nil {
+			return false
+		}
+		globals[name] = append(globals[name], g)
+		if pgs, ok := walked[g.image]; ok {
+			walked[g.image] = append(pgs, persistedGlobal{Name: name, Addr: uint64(g.value.UnsafeAddr()) - g.image.StaticBase, Type: g.typeName})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(indexed) > 0 {
+		// Definitions are kept in the order of their addresses, as delve reports them.
+		for _, defs := range globals {
+			sort.Slice(defs, func(i, j int) bool { return defs[i].value.UnsafeAddr() < defs[j].value.UnsafeAddr() })
+		}
+	}
+	for img, pgs := range walked {
+		da.storeIndex(img, func(index *persistedIndex) { index.Globals = pgs })
+		// The indexed globals are typed through the runtime types of the images, index them too.
+		da.imageTypeCache(img)
+	}
+	da.globals = globals
+	da.lazyGlobals = lazyGlobals{}
+	return globals, nil
+}
+
+// indexedGlobal resolves a global of img read from the index cache, its type is looked up in the
+// runtime types of img first and then in those of the other images.
+func (da *dwarfAssembly) indexedGlobal(img *proc.Image, pg persistedGlobal) (imageGlobal, bool) {
+	typeAddr := da.findImageType(img, pg.Type)
+	for _, other := range da.binaryInfo.Images {
+		if typeAddr != 0 {
 			break
 		}
+		if other != img && da.imageStale(other) == nil {
+			typeAddr = da.findImageType(other, pg.Type)
+		}
 	}
+	if typeAddr == 0 {
+		return imageGlobal{}, false
+	}
+	value := reflect.NewAt(runtimeType(typeAddr), unsafe.Pointer(uintptr(img.StaticBase+pg.Addr))).Elem()
+	return imageGlobal{image: img, value: value, typeName: da.intern(pg.Type)}, true
+}
+
+// resetGlobals drops the globals cache.
+func (da *dwarfAssembly) resetGlobals() {
+	da.globalsMu.Lock()
+	da.globals = nil
+	da.lazyGlobals = lazyGlobals{}
+	da.globalsMu.Unlock()
 }
 
-func (da *dwarfAssembly) loadGlobals() {
-	da.globals = make(map[string]reflect.Value)
+// walkGlobals resolves the package variables recorded by delve one by one, until fn returns false.
+// The variables of the images in skip are passed over.
+func (da *dwarfAssembly) walkGlobals(skip map[*proc.Image]bool, fn func(name string, g imageGlobal) bool) {
+	readers := imageReaders{da: da}
+	defer readers.release()
+	da.walkPackageVars(func(v packageVar) bool {
+		if skip[v.image] {
+			return true
+		}
+		if g, ok := da.resolveVar(v, readers.get(v.image)); ok {
+			return fn(v.name, g)
+		}
+		return true
+	})
+}
 
-	packageVars := reflect.ValueOf(da.binaryInfo).Elem().FieldByName("packageVars")
+// resolveVar types the package variable v by the runtime type named by its DWARF type, reading
+// its entry with reader. Variables without a runtime type are reported as not ok.
+func (da *dwarfAssembly) resolveVar(v packageVar, reader *reader.Reader) (imageGlobal, bool) {
+	entry, err := v.entry(reader)
+	if err != nil {
+		return imageGlobal{}, false
+	}
+
+	dtyp, err := entryType(v.dwarf, entry)
+	if err != nil {
+		return imageGlobal{}, false
+	}
+	dname := dwarfTypeName(dtyp)
+	if dname == "<unspecified>" || dname == "" {
+		return imageGlobal{}, false
+	}
+
+	rtyp, err := da.layoutType(dname)
+	if err != nil || rtyp == nil {
+		return imageGlobal{}, false
+	}
+	value := reflect.NewAt(rtyp, unsafe.Pointer(uintptr(v.addr))).Elem()
+	return imageGlobal{image: v.image, value: value, typeName: da.intern(dname)}, true
+}
+
+// packageVar is a package variable recorded by delve.
+type packageVar struct {
+	name   string
+	addr   uint64
+	offset dwarf.Offset
+	image  *proc.Image
+	dwarf  *dwarf.Data
+}
+
+// entry reads the debug_info entry describing the variable with a reader of its image.
+func (v packageVar) entry(reader *reader.Reader) (*dwarf.Entry, error) {
+	reader.Seek(v.offset)
+	entry, err := reader.Next()
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil || entry.Tag != dwarf.TagVariable {
+		return nil, fmt.Errorf("%s: %w", v.name, ErrNotFound)
+	}
+	if name, ok := entry.Val(dwarf.AttrName).(string); !ok || v.name != name {
+		return nil, fmt.Errorf("%s: %w", v.name, ErrNotFound)
+	}
+	return entry, nil
+}
+
+// walkPackageVars enumerates the package variables recorded by delve, until fn returns false.
+func (da *dwarfAssembly) walkPackageVars(fn func(v packageVar) bool) {
+	packageVars := da.snapshot().packageVars
+	stale := da.staleImages()
 	if packageVars.IsValid() {
 		for i, size := 0, packageVars.Len(); i < size; i++ {
 			rv := packageVars.Index(i)
@@ -53,34 +367,20 @@ func (da *dwarfAssembly) loadGlobals() {
 			if !rDwarf.IsValid() {
 				continue
 			}
-			image := (*proc.Image)(unsafe.Pointer(rImage.Pointer()))
-			dwarfData := (*dwarf.Data)(unsafe.Pointer(rDwarf.Pointer()))
 
-			reader := image.DwarfReader()
-			reader.Seek(dwarf.Offset(rOffset.Uint()))
-			entry, err := reader.Next()
-			if err != nil || entry == nil || entry.Tag != dwarf.TagVariable {
-				continue
+			v := packageVar{
+				name:   rName.String(),
+				addr:   rAddr.Uint(),
+				offset: dwarf.Offset(rOffset.Uint()),
+				image:  (*proc.Image)(unsafe.Pointer(rImage.Pointer())),
+				dwarf:  (*dwarf.Data)(unsafe.Pointer(rDwarf.Pointer())),
 			}
-			name, ok := entry.Val(dwarf.AttrName).(string)
-			if !ok || rName.String() != name {
+			if stale[v.image] {
 				continue
 			}
-
-			dtyp, err := entryType(dwarfData, entry)
-			if err != nil {
-				continue
-			}
-			dname := dwarfTypeName(dtyp)
-			if dname == "<unspecified>" || dname == "" {
-				continue
-			}
-
-			rtyp, err := da.FindType(dname)
-			if err != nil || rtyp == nil {
-				continue
+			if !fn(v) {
+				return
 			}
-			da.globals[name] = reflect.NewAt(rtyp, unsafe.Pointer(uintptr(rAddr.Uint()))).Elem()
 		}
 	}
 }
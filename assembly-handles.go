@@ -0,0 +1,132 @@
+package assembly
+
+import (
+	"reflect"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// Handle is a tracked reference to a value living in the memory of a loaded image,
+// such as a global variable or a function created for code of the image.
+// Images with outstanding handles are considered in use until every handle is released.
+type Handle struct {
+	da    *dwarfAssembly
+	value reflect.Value
+	image *proc.Image
+	err   error
+}
+
+// Value returns the referenced value, ErrReleased once the handle was released,
+// or ErrImageUnloaded once the image the value lives in was unloaded.
+func (h *Handle) Value() (reflect.Value, error) {
+	if h.err != nil {
+		return reflect.Value{}, h.err
+	}
+	return h.value, nil
+}
+
+// Image returns the image the referenced value lives in.
+func (h *Handle) Image() *proc.Image {
+	return h.image
+}
+
+// Release drops the reference, it is safe to call Release more than once.
+func (h *Handle) Release() {
+	if h.err != nil {
+		return
+	}
+	delete(h.da.handles[h.image], h)
+	if len(h.da.handles[h.image]) == 0 {
+		delete(h.da.handles, h.image)
+	}
+	h.invalidate(ErrReleased)
+}
+
+func (h *Handle) invalidate(err error) {
+	h.da = nil
+	h.value = reflect.Value{}
+	h.err = err
+}
+
+func (da *dwarfAssembly) AcquireGlobal(name string) (*Handle, error) {
+	value, img, err := da.ResolveGlobal(name)
+	if err != nil {
+		return nil, err
+	}
+	return da.newHandle(value, img), nil
+}
+
+func (da *dwarfAssembly) AcquireFunc(name string, variadic bool) (*Handle, error) {
+	value, err := da.FindFunc(name, variadic)
+	if err != nil {
+		return nil, err
+	}
+	return da.newHandle(value, da.binaryInfo.PCToImage(uint64(value.Pointer()))), nil
+}
+
+func (da *dwarfAssembly) References(image *proc.Image) int {
+	return len(da.handles[image])
+}
+
+func (da *dwarfAssembly) newHandle(value reflect.Value, img *proc.Image) *Handle {
+	h := &Handle{da: da, value: value, image: img}
+	if da.handles == nil {
+		da.handles = make(map[*proc.Image]map[*Handle]struct{})
+	}
+	if da.handles[img] == nil {
+		da.handles[img] = make(map[*Handle]struct{})
+	}
+	da.handles[img][h] = struct{}{}
+	return h
+}
+
+// SymbolHandle names a symbol that is resolved again on every use instead of caching
+// raw addresses, so long-lived references stay valid while images are loaded and reloaded.
+type SymbolHandle struct {
+	da        *dwarfAssembly
+	Name      string
+	ImageHint string // image path suffix preferred over the resolve policy, may be empty
+}
+
+func (da *dwarfAssembly) Symbol(name string, imageHint string) *SymbolHandle {
+	return &SymbolHandle{da: da, Name: name, ImageHint: imageHint}
+}
+
+func (s *SymbolHandle) Func(variadic bool) (reflect.Value, error) {
+	f, _, err := s.da.resolveFunc(s.Name, s.priority())
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return s.da.findFuncOf(f, variadic)
+}
+
+func (s *SymbolHandle) Call(variadic bool, args []reflect.Value) ([]reflect.Value, error) {
+	f, _, err := s.da.resolveFunc(s.Name, s.priority())
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.da.callFunc(f, variadic, args)
+	if err != nil {
+		return nil, err
+	}
+	return res.Values, nil
+}
+
+func (s *SymbolHandle) Type() (reflect.Type, error) {
+	typ, _, err := s.da.resolveType(s.Name, s.priority())
+	return typ, err
+}
+
+func (s *SymbolHandle) Global() (reflect.Value, error) {
+	value, _, err := s.da.resolveGlobal(s.Name, s.priority())
+	return value, err
+}
+
+// priority returns the image priority of the resolve policy with the image hint taking
+// precedence, the policy itself is left untouched for concurrent lookups.
+func (s *SymbolHandle) priority() []string {
+	if s.ImageHint == "" {
+		return s.da.policy.Priority
+	}
+	return append([]string{s.ImageHint}, s.da.policy.Priority...)
+}
@@ -0,0 +1,78 @@
+package assembly
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Healthy checks that the assembly can serve lookups and that its patches are intact: the
+// function index and module data are loaded, no image failed to load or was remapped, and the
+// entry of every patched target still holds the jump of its patch. The checks only read state
+// that is already built, a failure joins one error wrapping ErrUnhealthy per problem.
+func (da *dwarfAssembly) Healthy() error {
+	var problems []error
+	problem := func(format string, args ...any) {
+		problems = append(problems, fmt.Errorf("%w: "+format, append([]any{ErrUnhealthy}, args...)...))
+	}
+
+	if len(da.binaryInfo.Images) == 0 || da.modules == nil {
+		problem("no image loaded")
+	} else if len(da.snapshot().functions) == 0 {
+		problem("function index is empty")
+	}
+	for _, err := range da.ImageErrors() {
+		problem("%v", err)
+	}
+	for _, img := range da.binaryInfo.Images {
+		if err := da.imageStale(img); err != nil {
+			problem("%s: %v", img.Path, err)
+		}
+	}
+	problems = append(problems, da.checkPatches()...)
+	return errors.Join(problems...)
+}
+
+// checkPatches reports the patches of the assembly whose jump was overwritten, and a patch count
+// of the metrics diverging from the patches in place.
+func (da *dwarfAssembly) checkPatches() []error {
+	patches.mu.Lock()
+	defer patches.mu.Unlock()
+
+	var problems []error
+	var active int
+	for pc, p := range patches.active {
+		if p.da != da {
+			continue
+		}
+		active++
+		code, err := jumpCode(da.binaryInfo.Arch.Name, uintptr(p.funcval))
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%w: patch of %s: %v", ErrUnhealthy, p.Site.Target, err))
+			continue
+		}
+		if !bytes.Equal(entryAddress(uintptr(pc), len(code)), code) {
+			problems = append(problems, fmt.Errorf("%w: patch of %s was overwritten", ErrUnhealthy, p.Site.Target))
+		}
+	}
+	if metrics := da.PatchMetrics(); metrics.Active != active {
+		problems = append(problems, fmt.Errorf("%w: %d patches counted active, %d in place", ErrUnhealthy, metrics.Active, active))
+	}
+	return problems
+}
+
+// HealthHandler answers a probe with 200 while asm is Healthy and 503 listing the problems
+// otherwise, e.g. http.Handle("/healthz/assembly", HealthHandler(asm)).
+func HealthHandler(asm DwarfAssembly) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := asm.Healthy(); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			io.WriteString(w, err.Error()+"\n")
+			return
+		}
+		io.WriteString(w, "ok\n")
+	})
+}
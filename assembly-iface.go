@@ -0,0 +1,117 @@
+package assembly
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
+)
+
+// itabTypes are the names of the itab type across Go releases.
+var itabTypes = []string{"internal/abi.ITab", "runtime.itab"}
+
+// CallInterfaceMethod dispatches the method of the interface held by iface, a value of interface
+// kind, like a call through the interface would: the code pointer is taken from the itab of the
+// value and its signature from DWARF, so unexported methods of concrete types unknown at compile
+// time, possibly defined by a plugin, can be called. Values of empty interfaces are dispatched
+// by the method of their dynamic type.
+func (da *dwarfAssembly) CallInterfaceMethod(iface reflect.Value, method string, args []reflect.Value) ([]reflect.Value, error) {
+	if !iface.IsValid() || iface.Kind() != reflect.Interface {
+		return nil, fmt.Errorf("call %s: %v is not an interface value: %w", method, iface, ErrNotSupport)
+	}
+	if iface.IsNil() {
+		return nil, fmt.Errorf("call %s: nil interface %s", method, iface.Type())
+	}
+	if iface.NumMethod() == 0 {
+		c, bound, _, err := da.boundMethod(iface.Elem(), method)
+		if err != nil {
+			return nil, err
+		}
+		res, err := da.call(c, append([]reflect.Value{bound}, args...))
+		if err != nil {
+			return nil, err
+		}
+		return res.Values, nil
+	}
+
+	index := -1
+	for i := 0; i < iface.Type().NumMethod(); i++ {
+		if iface.Type().Method(i).Name == method {
+			index = i
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("call %s: %s has no method %s: %w", method, iface.Type(), method, ErrNotFound)
+	}
+
+	if !iface.CanAddr() {
+		copied := reflect.New(iface.Type()).Elem()
+		copied.Set(iface)
+		iface = copied
+	}
+	words := (*[2]uintptr)(unsafe.Pointer(iface.UnsafeAddr()))
+	pc, err := da.itabMethod(words[0], index)
+	if err != nil {
+		return nil, fmt.Errorf("call %s: %w", method, err)
+	}
+
+	f := da.binaryInfo.PCToFunc(pc)
+	if f == nil || f.Entry != pc {
+		return nil, fmt.Errorf("call %s: no function at %#x: %w", method, pc, ErrNotFound)
+	}
+	if f.Name == "runtime.unreachableMethod" {
+		return nil, fmt.Errorf("call %s: method of %s removed by the linker: %w", method, iface.Elem().Type(), ErrNotFound)
+	}
+	c, err := da.prepareCall(f, false)
+	if err != nil {
+		return nil, err
+	}
+	inTyps, inNames := c.inTyps, c.inNames
+	if len(inTyps) != len(args)+1 {
+		return nil, fmt.Errorf("%s: len mismatch, except %d args, got %d", f.Name, len(inTyps)-1, len(args))
+	}
+	if inTyps[0].Size() != unsafe.Sizeof(uintptr(0)) {
+		return nil, fmt.Errorf("%s: receiver %s is not pointer shaped: %w", f.Name, inTyps[0], ErrNotSupport)
+	}
+	for i, arg := range args {
+		if !arg.Type().AssignableTo(inTyps[i+1]) {
+			return nil, fmt.Errorf("type mismatch arg: %d:%s, except: %s, got: %s", i, inNames[i+1], inTyps[i+1], arg.Type())
+		}
+	}
+
+	// the data word of the interface is the receiver of the itab method.
+	recv := reflect.NewAt(inTyps[0], unsafe.Pointer(&words[1])).Elem()
+	res, err := da.call(c, append([]reflect.Value{recv}, args...))
+	if err != nil {
+		return nil, err
+	}
+	return res.Values, nil
+}
+
+// itabMethod reads the code pointer of the index-th method from the itab at tab, the layout of
+// the itab is read from DWARF.
+func (da *dwarfAssembly) itabMethod(tab uintptr, index int) (uint64, error) {
+	var typ godwarf.Type
+	var err error
+	for _, name := range itabTypes {
+		if typ, err = findType(da.binaryInfo, name); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	itab := runtimeVar{name: "itab", addr: uint64(tab), typ: typ}
+	var fun runtimeVar
+	for _, field := range []string{"Fun", "fun"} {
+		if fun, err = itab.field(field); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return uint64(*(*uintptr)(unsafe.Pointer(uintptr(fun.addr) + uintptr(index)*unsafe.Sizeof(uintptr(0))))), nil
+}
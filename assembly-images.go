@@ -0,0 +1,211 @@
+package assembly
+
+import (
+	"bytes"
+	"debug/buildinfo"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+const (
+	goBuildIDNote   = ".note.go.buildid"
+	goBuildIDTag    = 4 // ELF_NOTE_GOBUILDID_TAG as defined by cmd/link
+	goBuildIDPrefix = "\xff Go build ID: \""
+	goBuildIDSuffix = "\"\n \xff"
+	goBuildIDScan   = 32 * 1024 // the toolchain places the raw build ID within the first 32kB of the file
+)
+
+// ImageInfo describes a loaded image.
+type ImageInfo struct {
+	Path       string
+	BuildID    string // GNU build ID recorded by the linker, if any
+	GoBuildID  string
+	StaticBase uint64 // load address relative to the addresses recorded in the image
+	Addr       uint64 // address the image was loaded at, as passed to LoadImage
+	Main       bool   // the image is the main executable
+	PluginPath string // plugin path recorded by the runtime, only reported by ListImages
+
+	// The provenance recorded in the embedded build information, empty if the image has none.
+	// BuildInfo returns all of it, including the dependencies and build settings.
+	GoVersion     string
+	Module        string // path of the main module
+	ModuleVersion string
+	VCSRevision   string
+	VCSModified   bool
+}
+
+func (da *dwarfAssembly) imageInfo(img *proc.Image) ImageInfo {
+	info := ImageInfo{
+		Path:       img.Path,
+		BuildID:    img.BuildID,
+		GoBuildID:  da.goBuildID(img),
+		StaticBase: img.StaticBase,
+		Addr:       imageAddr(img),
+		Main:       da.imageIndex(img) == 0,
+	}
+	if build, err := da.BuildInfo(img); err == nil {
+		info.GoVersion = build.GoVersion
+		info.Module, info.ModuleVersion = build.Main.Path, build.Main.Version
+		settings := buildSettings(build)
+		info.VCSRevision, info.VCSModified = settings["vcs.revision"], settings["vcs.modified"] == "true"
+	}
+	return info
+}
+
+// ListImages describes the loaded images, the main executable first. Images whose debug
+// information failed to load, or which were unloaded, are left out.
+func (da *dwarfAssembly) ListImages() []ImageInfo {
+	modules, _ := da.RuntimeModules()
+	var infos []ImageInfo
+	for _, img := range da.binaryInfo.Images {
+		if !imageLoaded(img) || da.imageStale(img) != nil {
+			continue
+		}
+		info := da.imageInfo(img)
+		if md := imageToModuleData(da.binaryInfo, img, da.modules); md != nil {
+			for _, m := range modules {
+				if m.Text == md.text {
+					info.PluginPath = m.PluginPath
+				}
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// imageAddr returns the address delve loaded img at.
+func imageAddr(img *proc.Image) uint64 {
+	if field := reflect.ValueOf(img).Elem().FieldByName("addr"); field.IsValid() {
+		return field.Uint()
+	}
+	return 0
+}
+
+func (da *dwarfAssembly) Provenance(kind SymbolKind, name string) (ImageInfo, error) {
+	var img *proc.Image
+	var err error
+	switch kind {
+	case SymbolFunc:
+		_, img, err = da.ResolveFunc(name)
+	case SymbolType:
+		_, img, err = da.ResolveType(name)
+	case SymbolGlobal:
+		_, img, err = da.ResolveGlobal(name)
+	default:
+		err = ErrNotSupport
+	}
+	if err != nil {
+		return ImageInfo{}, err
+	}
+	return da.imageInfo(img), nil
+}
+
+func (da *dwarfAssembly) BuildID(image *proc.Image) (string, error) {
+	da.cacheMu.Lock()
+	id, ok := da.buildIDs[image]
+	da.cacheMu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	id, err := readGoBuildID(image.Path)
+	if err != nil {
+		return "", fmt.Errorf("read build id failed: %s: %w", image.Path, err)
+	}
+
+	da.cacheMu.Lock()
+	defer da.cacheMu.Unlock()
+	if da.buildIDs == nil {
+		da.buildIDs = make(map[*proc.Image]string)
+	}
+	da.buildIDs[image] = id
+	return id, nil
+}
+
+func (da *dwarfAssembly) goBuildID(img *proc.Image) string {
+	id, _ := da.BuildID(img)
+	return id
+}
+
+// readGoBuildID reads the Go build ID from the .note.go.buildid ELF note,
+// or from the raw build ID string the linker places at the start of the text segment.
+func readGoBuildID(path string) (string, error) {
+	if f, err := elf.Open(path); err == nil {
+		defer f.Close()
+		if sec := f.Section(goBuildIDNote); sec != nil {
+			data, err := sec.Data()
+			if err != nil {
+				return "", err
+			}
+			return parseGoBuildIDNote(data, f.ByteOrder)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	data := make([]byte, goBuildIDScan)
+	n, err := io.ReadFull(f, data)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	data = data[:n]
+
+	i := bytes.Index(data, []byte(goBuildIDPrefix))
+	if i < 0 {
+		return "", ErrNotFound
+	}
+	data = data[i+len(goBuildIDPrefix)-1:]
+	j := bytes.Index(data, []byte(goBuildIDSuffix))
+	if j < 0 {
+		return "", ErrNotFound
+	}
+	return strconv.Unquote(string(data[:j+1]))
+}
+
+func parseGoBuildIDNote(data []byte, order binary.ByteOrder) (string, error) {
+	if len(data) < 16 {
+		return "", ErrNotFound
+	}
+	// the sizes are widened before they are added, a name or description size near the uint32
+	// limit would otherwise wrap around and pass the bounds check.
+	namesz, descsz, tag := uint64(order.Uint32(data)), uint64(order.Uint32(data[4:])), order.Uint32(data[8:])
+	nameEnd := 12 + (namesz+3)&^3
+	if tag != goBuildIDTag || uint64(len(data)) < nameEnd+descsz {
+		return "", ErrNotFound
+	}
+	return string(data[nameEnd : nameEnd+descsz]), nil
+}
+
+func (da *dwarfAssembly) BuildInfo(image *proc.Image) (*buildinfo.BuildInfo, error) {
+	da.cacheMu.Lock()
+	info, ok := da.buildInfos[image]
+	da.cacheMu.Unlock()
+	if ok {
+		return info, nil
+	}
+
+	info, err := buildinfo.ReadFile(image.Path)
+	if err != nil {
+		return nil, fmt.Errorf("read build info failed: %s: %w", image.Path, err)
+	}
+
+	da.cacheMu.Lock()
+	defer da.cacheMu.Unlock()
+	if da.buildInfos == nil {
+		da.buildInfos = make(map[*proc.Image]*buildinfo.BuildInfo)
+	}
+	da.buildInfos[image] = info
+	return info, nil
+}
@@ -0,0 +1,182 @@
+package assembly
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// indexCacheVersion is bumped whenever the layout of persistedIndex changes, files of
+// another version are ignored and rewritten.
+const indexCacheVersion = 1
+
+// persistedIndex is what the assembly derives from the DWARF of one image, stored on disk
+// under the build ID of the image. Addresses are relative to the static base of the image,
+// so the index stays valid when the image is mapped elsewhere.
+type persistedIndex struct {
+	Version int
+	BuildID string
+	// Types maps the DWARF name of a runtime type to the address of its descriptor.
+	Types map[string]uint64
+	// Globals is nil until the globals of the image were indexed.
+	Globals []persistedGlobal
+}
+
+// persistedGlobal is a package variable and the DWARF name of its type.
+type persistedGlobal struct {
+	Name string
+	Addr uint64
+	Type string
+}
+
+// indexCache holds the persisted indexes of the images, read on first use.
+type indexCache struct {
+	mu      sync.Mutex
+	dir     string
+	indexes map[*proc.Image]*persistedIndex
+}
+
+// SetIndexCache stores the type and global indexes of every image in dir, keyed by the build ID
+// of the image. Later assemblies using the same dir read them instead of parsing the DWARF
+// entries again, an image rebuilt with another build ID gets a new index. Only types and
+// globals are cached: functions are not indexed, delve still loads them with the DWARF of
+// every image. An index with an address outside the sections of its image is discarded.
+// An empty dir disables the cache.
+func (da *dwarfAssembly) SetIndexCache(dir string) error {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	da.indexCache.mu.Lock()
+	defer da.indexCache.mu.Unlock()
+	da.indexCache.dir = dir
+	da.indexCache.indexes = nil
+	return nil
+}
+
+// indexKey returns the build ID an index of img is stored under, empty if img has none. It does
+// not go through BuildID, indexes are also read by the background warming of the type caches.
+func indexKey(img *proc.Image) string {
+	if id, err := readGoBuildID(img.Path); err == nil && id != "" {
+		return id
+	}
+	return img.BuildID
+}
+
+// persistedIndex returns the index of img read from the cache, nil without a cache or build ID.
+// An image without a stored index gets an empty one.
+func (da *dwarfAssembly) persistedIndex(img *proc.Image) *persistedIndex {
+	da.indexCache.mu.Lock()
+	defer da.indexCache.mu.Unlock()
+	if da.indexCache.dir == "" {
+		return nil
+	}
+	if index, ok := da.indexCache.indexes[img]; ok {
+		return index
+	}
+	if da.indexCache.indexes == nil {
+		da.indexCache.indexes = make(map[*proc.Image]*persistedIndex)
+	}
+	key := indexKey(img)
+	if key == "" {
+		da.indexCache.indexes[img] = nil
+		return nil
+	}
+
+	index := &persistedIndex{Version: indexCacheVersion, BuildID: key}
+	if data, err := os.ReadFile(da.indexPath(key)); err == nil {
+		var stored persistedIndex
+		if gob.NewDecoder(bytes.NewReader(data)).Decode(&stored) == nil && stored.Version == indexCacheVersion && stored.BuildID == key &&
+			da.indexInImage(img, &stored) {
+			index = &stored
+		}
+	}
+	da.indexCache.indexes[img] = index
+	return index
+}
+
+// indexInImage reports whether the addresses of index lie in img: the runtime types in one of
+// its sections and the globals in a writable one, data or bss. A build ID does not guard against
+// a corrupted or foreign file, whose addresses would be dereferenced.
+func (da *dwarfAssembly) indexInImage(img *proc.Image, index *persistedIndex) bool {
+	sections, err := readImageSections(da.binaryInfo.GOOS, img)
+	if err != nil {
+		return false
+	}
+	inSection := func(addr uint64, writable bool) bool {
+		for _, sec := range sections {
+			if sec.Contains(addr) && (!writable || sec.Writable()) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, addr := range index.Types {
+		if !inSection(img.StaticBase+addr, false) {
+			return false
+		}
+	}
+	for _, g := range index.Globals {
+		if !inSection(img.StaticBase+g.Addr, true) {
+			return false
+		}
+	}
+	return true
+}
+
+// storeIndex updates the index of img with update and writes it back, errors are ignored:
+// the cache only saves work.
+func (da *dwarfAssembly) storeIndex(img *proc.Image, update func(index *persistedIndex)) {
+	index := da.persistedIndex(img)
+	if index == nil {
+		return
+	}
+	da.indexCache.mu.Lock()
+	defer da.indexCache.mu.Unlock()
+	update(index)
+
+	var buf bytes.Buffer
+	if gob.NewEncoder(&buf).Encode(index) != nil {
+		return
+	}
+	path := da.indexPath(index.BuildID)
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(buf.Bytes())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// indexPath returns the file of the index stored under the build ID key, build IDs may
+// contain slashes so they are hashed.
+func (da *dwarfAssembly) indexPath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(da.indexCache.dir, hex.EncodeToString(sum[:16])+".idx")
+}
+
+// dropIndex forgets the index of img read from the cache, or of every image if img is nil.
+func (da *dwarfAssembly) dropIndex(img *proc.Image) {
+	da.indexCache.mu.Lock()
+	defer da.indexCache.mu.Unlock()
+	if img == nil {
+		da.indexCache.indexes = nil
+	} else {
+		delete(da.indexCache.indexes, img)
+	}
+}
@@ -0,0 +1,54 @@
+package assembly
+
+import (
+	"sort"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// InlineSite is a range of instructions of a caller into which a function was inlined.
+type InlineSite struct {
+	// Caller is the function whose code holds the inlined body, the function to patch
+	// instead. Bodies inlined into inlined bodies are reported in the outermost caller.
+	Caller   string
+	CallerPC uint64
+	// LowPC and HighPC delimit the inlined body, HighPC excluded.
+	LowPC, HighPC uint64
+	Image         *proc.Image
+}
+
+// InlineSites lists where the function name was inlined, in every image that defines it,
+// ordered by address. Patching name does not redirect these calls, their callers have to be
+// patched too. A function that was never inlined has no sites.
+func (da *dwarfAssembly) InlineSites(name string) ([]InlineSite, error) {
+	var fns []*proc.Function
+	if _, ok := da.resolveName(name, func(name string) bool {
+		fns, _ = da.binaryInfo.FindFunction(name)
+		return nil != fns
+	}); !ok {
+		return nil, ErrNotFound
+	}
+
+	var sites []InlineSite
+	for _, fn := range fns {
+		for _, call := range fn.InlinedCalls {
+			caller := da.binaryInfo.PCToFunc(call.LowPC)
+			if caller == nil {
+				continue
+			}
+			img := da.binaryInfo.PCToImage(call.LowPC)
+			if da.imageStale(img) != nil {
+				continue
+			}
+			sites = append(sites, InlineSite{
+				Caller:   da.displayName(caller.Name),
+				CallerPC: caller.Entry,
+				LowPC:    call.LowPC,
+				HighPC:   call.HighPC,
+				Image:    img,
+			})
+		}
+	}
+	sort.Slice(sites, func(i, j int) bool { return sites[i].LowPC < sites[j].LowPC })
+	return sites, nil
+}
@@ -0,0 +1,50 @@
+package assembly
+
+import (
+	"reflect"
+	"sync"
+)
+
+// nameTable interns the symbol names retained by the caches. Names read back from debug_info
+// are fresh copies, interning makes every image cache share a single copy of each, the one held
+// by the delve type index when it has one.
+type nameTable struct {
+	mu    sync.Mutex
+	names map[string]string
+}
+
+// intern returns the retained copy of name.
+func (da *dwarfAssembly) intern(name string) string {
+	t := &da.names
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.names == nil {
+		t.names = da.indexedTypeNames()
+	}
+	if s, ok := t.names[name]; ok {
+		return s
+	}
+	t.names[name] = name
+	return name
+}
+
+// indexedTypeNames seeds the name table with the keys of the delve type index, sharing their storage.
+func (da *dwarfAssembly) indexedTypeNames() map[string]string {
+	rTypes := reflect.ValueOf(da.binaryInfo).Elem().FieldByName("types")
+	if !rTypes.IsValid() || rTypes.Kind() != reflect.Map {
+		return make(map[string]string)
+	}
+	names := make(map[string]string, rTypes.Len())
+	for iter := rTypes.MapRange(); iter.Next(); {
+		name := iter.Key().String()
+		names[name] = name
+	}
+	return names
+}
+
+// resetNames drops the retained names, the caches holding them must be dropped too.
+func (da *dwarfAssembly) resetNames() {
+	da.names.mu.Lock()
+	da.names.names = nil
+	da.names.mu.Unlock()
+}
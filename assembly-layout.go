@@ -0,0 +1,159 @@
+package assembly
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// LayoutChange reports an image whose code is no longer mapped where it was loaded.
+type LayoutChange struct {
+	Image ImageInfo
+	// Mapped is the file now mapped over the text of the image, empty if none is.
+	Mapped string
+}
+
+// layoutState is the images found unmapped by CheckLayout or unloaded by UnloadImage, with
+// the error lookups of their symbols fail with, and the handler notified of layout changes.
+type layoutState struct {
+	mu      sync.Mutex
+	stale   map[*proc.Image]error
+	handler func(change LayoutChange)
+}
+
+// SetLayoutHandler installs the handler CheckLayout notifies of every image it finds unmapped,
+// once per image. A nil handler disables notifications.
+func (da *dwarfAssembly) SetLayoutHandler(handler func(change LayoutChange)) {
+	da.layout.mu.Lock()
+	defer da.layout.mu.Unlock()
+	da.layout.handler = handler
+}
+
+// CheckLayout verifies that the text of every loaded image is still mapped from its file at
+// the address it was loaded at, memory the images were loaded from can be unmapped by cgo code.
+// An image that moved is invalidated: its function values and handles report ErrImageUnloaded,
+// its caches are dropped and lookups no longer resolve symbols to it. The changes found by this
+// call are returned, images reported before are not reported again.
+func (da *dwarfAssembly) CheckLayout() ([]LayoutChange, error) {
+	var changes []LayoutChange
+	for _, img := range da.binaryInfo.Images {
+		if da.imageStale(img) != nil {
+			continue
+		}
+		md := imageToModuleData(da.binaryInfo, img, da.modules)
+		if md == nil || md.text == 0 {
+			continue
+		}
+		mapped, err := mappedFile(uintptr(md.text))
+		if err != nil {
+			return changes, err
+		}
+		if sameFile(mapped, img.Path) {
+			continue
+		}
+		change := LayoutChange{Image: da.imageInfo(img), Mapped: mapped}
+		da.markStale(img, change)
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// WatchLayout runs CheckLayout every interval until ctx is done and returns the error of ctx,
+// or the first error of CheckLayout.
+func (da *dwarfAssembly) WatchLayout(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := da.CheckLayout(); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// markStale invalidates img and notifies the layout handler.
+func (da *dwarfAssembly) markStale(img *proc.Image, change LayoutChange) {
+	handler := da.retireImage(img)
+	if handler != nil {
+		handler(change)
+	}
+}
+
+// retireImage stops resolving symbols to img and drops everything issued or cached for it,
+// it returns the layout handler to notify.
+func (da *dwarfAssembly) retireImage(img *proc.Image) func(change LayoutChange) {
+	da.layout.mu.Lock()
+	if da.layout.stale == nil {
+		da.layout.stale = make(map[*proc.Image]error)
+	}
+	da.layout.stale[img] = fmt.Errorf("%s: %w", img.Path, ErrImageUnloaded)
+	handler := da.layout.handler
+	da.layout.mu.Unlock()
+
+	da.invalidateImage(img)
+	da.dropImageCaches(img)
+	return handler
+}
+
+// imageStale returns an error wrapping ErrImageUnloaded if img was found unmapped or unloaded.
+func (da *dwarfAssembly) imageStale(img *proc.Image) error {
+	da.layout.mu.Lock()
+	defer da.layout.mu.Unlock()
+	return da.layout.stale[img]
+}
+
+// staleImages returns the set of images found unmapped or unloaded.
+func (da *dwarfAssembly) staleImages() map[*proc.Image]bool {
+	da.layout.mu.Lock()
+	defer da.layout.mu.Unlock()
+	if len(da.layout.stale) == 0 {
+		return nil
+	}
+	stale := make(map[*proc.Image]bool, len(da.layout.stale))
+	for img := range da.layout.stale {
+		stale[img] = true
+	}
+	return stale
+}
+
+// dropImageCaches forgets everything cached about img.
+func (da *dwarfAssembly) dropImageCaches(img *proc.Image) {
+	da.typesMu.Lock()
+	delete(da.imageTypes, img)
+	da.typesMu.Unlock()
+	da.cacheMu.Lock()
+	delete(da.sections, img)
+	delete(da.abi0Funcs, img)
+	delete(da.buildIDs, img)
+	delete(da.buildInfos, img)
+	da.funcTypes = nil
+	da.cacheMu.Unlock()
+	da.dropIndex(img)
+	da.resetGlobals()
+	da.resetPackageIndex()
+}
+
+// sameFile reports whether the paths name the same file, comparing the cleaned absolute
+// paths when either cannot be stat'ed.
+func sameFile(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	if fa, err := os.Stat(a); err == nil {
+		if fb, err := os.Stat(b); err == nil {
+			return os.SameFile(fa, fb)
+		}
+	}
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
@@ -0,0 +1,58 @@
+package assembly
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// FileLine returns the source location of the instruction at pc, an empty file if pc is not
+// in a function of a loaded image. For an instruction of an inlined call it is the location in
+// the inlined function.
+func (da *dwarfAssembly) FileLine(pc uint64) (file string, line int) {
+	file, line, fn := da.binaryInfo.PCToLine(pc)
+	if fn == nil {
+		return "", 0
+	}
+	return file, line
+}
+
+// LineToPC returns the addresses of the statements compiled from line of file, sorted, including
+// those of every inlined copy. file is the path recorded in the line tables or a suffix of it
+// starting at a path element, e.g. "orders/recalc.go", as long as it names a single file.
+func (da *dwarfAssembly) LineToPC(file string, line int) ([]uint64, error) {
+	path, err := da.sourceFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pcs := da.binaryInfo.AllPCsForFileLines(path, []int{line})[line]
+	if len(pcs) == 0 {
+		return nil, fmt.Errorf("%s:%d: no statement: %w", path, line, ErrNotFound)
+	}
+	slices.Sort(pcs)
+	return slices.Compact(pcs), nil
+}
+
+// sourceFile returns the path of the line tables file stands for.
+func (da *dwarfAssembly) sourceFile(file string) (string, error) {
+	sources := da.binaryInfo.Sources
+	if slices.Contains(sources, file) {
+		return file, nil
+	}
+
+	var matches []string
+	suffix := "/" + strings.TrimPrefix(file, "/")
+	for _, source := range sources {
+		if strings.HasSuffix(source, suffix) {
+			matches = append(matches, source)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("source %s: %w", file, ErrNotFound)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("source %s is ambiguous, matches %s", file, strings.Join(matches, ", "))
+	}
+}
@@ -0,0 +1,124 @@
+package assembly
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// ImageError is the failure to load the debug information of one image.
+type ImageError struct {
+	Path string
+	Err  error
+}
+
+func (e *ImageError) Error() string {
+	return fmt.Sprintf("load %s: %v", e.Path, e.Err)
+}
+
+func (e *ImageError) Unwrap() error {
+	return e.Err
+}
+
+// LoadError reports the images whose debug information could not be loaded. The assembly
+// remains usable, the symbols of the other images are resolved as before.
+type LoadError struct {
+	Images []*ImageError
+}
+
+func (e *LoadError) Error() string {
+	msgs := make([]string, len(e.Images))
+	for i, img := range e.Images {
+		msgs[i] = img.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *LoadError) Unwrap() []error {
+	errs := make([]error, len(e.Images))
+	for i, img := range e.Images {
+		errs[i] = img
+	}
+	return errs
+}
+
+// ImageErrors returns the load failure of every image that failed to load, in load order.
+func (da *dwarfAssembly) ImageErrors() []*ImageError {
+	var errs []*ImageError
+	for _, img := range da.binaryInfo.Images {
+		if err := img.LoadError(); err != nil {
+			errs = append(errs, &ImageError{Path: img.Path, Err: err})
+		}
+	}
+	return errs
+}
+
+// imageLoaded reports whether the debug information of img was loaded.
+func imageLoaded(img *proc.Image) bool {
+	return img.LoadError() == nil
+}
+
+// LoadImageAuto loads the debug information of the library at path, already mapped into the
+// process, at the address it was mapped at: the one recorded in the link map of the dynamic
+// loader, reported by EnumProcessModules on Windows or by dyld on macOS.
+func (da *dwarfAssembly) LoadImageAuto(path string) error {
+	addr, err := da.libraryAddr(path)
+	if err != nil {
+		return err
+	}
+	return da.LoadImage(path, addr)
+}
+
+// libraryAddr returns the address the library at path is mapped at.
+func (da *dwarfAssembly) libraryAddr(path string) (uint64, error) {
+	libraries, err := da.ListLoadedLibraries()
+	if err != nil {
+		return 0, err
+	}
+	for _, lib := range libraries {
+		if lib.Path == path || sameFile(lib.Path, path) {
+			return lib.Base, nil
+		}
+	}
+	return 0, fmt.Errorf("%s is not mapped: %w", path, ErrNotFound)
+}
+
+// LoadedLibrary is a module mapped into the process, Base the address to pass to LoadImage.
+type LoadedLibrary struct {
+	Path string
+	Base uint64
+	// Loaded reports whether the assembly holds the debug information of the library.
+	Loaded bool
+}
+
+// ListLoadedLibraries lists the modules mapped into the process, the executable first, from the
+// link map of the dynamic loader, from EnumProcessModules on Windows or from the image list of
+// dyld on macOS.
+func (da *dwarfAssembly) ListLoadedLibraries() ([]LoadedLibrary, error) {
+	var libraries []LoadedLibrary
+	switch runtime.GOOS {
+	case "windows", "darwin", "ios":
+		modules, err := processModules()
+		if err != nil {
+			return nil, err
+		}
+		libraries = modules
+	default:
+		libs, err := da.linkMaps()
+		if err != nil {
+			return nil, err
+		}
+		main := da.binaryInfo.Images[0]
+		libraries = append(libraries, LoadedLibrary{Path: main.Path, Base: imageAddr(main)})
+		for _, lm := range libs {
+			libraries = append(libraries, LoadedLibrary{Path: lm.name, Base: lm.addr})
+		}
+	}
+
+	for i := range libraries {
+		libraries[i].Loaded = da.findImage(libraries[i].Path) != nil
+	}
+	return libraries, nil
+}
@@ -0,0 +1,148 @@
+package assembly
+
+import (
+	"reflect"
+	"unsafe"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// MemoryUsage approximates the bytes retained by the assembly, to weigh eager caches against
+// lazy lookups. Map sizes are estimated from their key and value sizes, names are counted once.
+type MemoryUsage struct {
+	// DWARF is the debug information sections delve holds in memory for every image.
+	DWARF uint64
+	// TypeCaches is the runtime type cache of every image built so far.
+	TypeCaches map[*proc.Image]uint64
+	// Globals is the globals cache, zero until a lookup built it.
+	Globals uint64
+	// Funcs is the cached function types and created function values.
+	Funcs uint64
+	// Indexes is the function, source, type and package variable indexes of delve.
+	Indexes uint64
+	// Names is the interned names not already held by the delve type index.
+	Names uint64
+}
+
+// Total sums the usage of every category.
+func (u MemoryUsage) Total() uint64 {
+	total := u.DWARF + u.Globals + u.Funcs + u.Indexes + u.Names
+	for _, size := range u.TypeCaches {
+		total += size
+	}
+	return total
+}
+
+// mapEntryOverhead estimates the per entry cost of a map besides its key and value.
+const mapEntryOverhead = 16
+
+const stringHeaderSize = uint64(unsafe.Sizeof(""))
+
+// imageDebugFields are the fields of proc.Image holding debug sections read into memory.
+var imageDebugFields = []string{"dwarf", "debugLineStr", "debugAddr", "loclist2", "loclist5"}
+
+// MemoryUsage reports the memory retained by the assembly. The type caches requested so far
+// are waited for if they are still being built.
+func (da *dwarfAssembly) MemoryUsage() MemoryUsage {
+	var usage = MemoryUsage{TypeCaches: make(map[*proc.Image]uint64)}
+
+	for _, img := range da.binaryInfo.Images {
+		rImage := reflect.ValueOf(img).Elem()
+		for _, name := range imageDebugFields {
+			usage.DWARF += byteFields(rImage.FieldByName(name))
+		}
+	}
+
+	da.typesMu.Lock()
+	var images = make([]*proc.Image, 0, len(da.imageTypes))
+	for img := range da.imageTypes {
+		images = append(images, img)
+	}
+	da.typesMu.Unlock()
+	for _, img := range images {
+		usage.TypeCaches[img] = uint64(len(da.imageTypeCache(img))) * (stringHeaderSize + 8 + mapEntryOverhead)
+	}
+
+	da.globalsMu.Lock()
+	for _, defs := range da.globals {
+		usage.Globals += stringHeaderSize + uint64(unsafe.Sizeof(defs)) + mapEntryOverhead
+		usage.Globals += uint64(cap(defs)) * uint64(unsafe.Sizeof(imageGlobal{}))
+	}
+	for _, defs := range da.lazyGlobals.resolved {
+		usage.Globals += stringHeaderSize + uint64(unsafe.Sizeof(defs)) + mapEntryOverhead
+		usage.Globals += uint64(cap(defs)) * uint64(unsafe.Sizeof(imageGlobal{}))
+	}
+	da.globalsMu.Unlock()
+
+	da.cacheMu.Lock()
+	usage.Funcs += uint64(len(da.funcTypes)) * (uint64(unsafe.Sizeof(funcTypeKey{})) + 16 + mapEntryOverhead)
+	usage.Funcs += uint64(len(da.funcs)) * (uint64(unsafe.Sizeof(funcKey{})) + 8 + uint64(unsafe.Sizeof(createdFunc{})) + mapEntryOverhead)
+	da.cacheMu.Unlock()
+
+	usage.Indexes = da.indexUsage()
+	usage.Names = da.namesUsage()
+	return usage
+}
+
+// indexUsage estimates the indexes delve builds while loading images.
+func (da *dwarfAssembly) indexUsage() uint64 {
+	var size uint64
+	for i := range da.binaryInfo.Functions {
+		size += uint64(unsafe.Sizeof(da.binaryInfo.Functions[i])) + uint64(len(da.binaryInfo.Functions[i].Name))
+	}
+	for _, source := range da.binaryInfo.Sources {
+		size += stringHeaderSize + uint64(len(source))
+	}
+
+	rBinaryInfo := reflect.ValueOf(da.binaryInfo).Elem()
+	if rTypes := rBinaryInfo.FieldByName("types"); rTypes.IsValid() && rTypes.Kind() == reflect.Map {
+		entry := uint64(rTypes.Type().Key().Size() + rTypes.Type().Elem().Size() + mapEntryOverhead)
+		for iter := rTypes.MapRange(); iter.Next(); {
+			size += entry + uint64(iter.Key().Len())
+		}
+	}
+	if rVars := rBinaryInfo.FieldByName("packageVars"); rVars.IsValid() && rVars.Kind() == reflect.Slice {
+		size += uint64(rVars.Cap()) * uint64(rVars.Type().Elem().Size())
+		for i := 0; i < rVars.Len(); i++ {
+			size += uint64(rVars.Index(i).FieldByName("name").Len())
+		}
+	}
+	return size
+}
+
+// namesUsage estimates the name table, names shared with the delve type index only cost their entry.
+func (da *dwarfAssembly) namesUsage() uint64 {
+	rTypes := reflect.ValueOf(da.binaryInfo).Elem().FieldByName("types")
+
+	da.names.mu.Lock()
+	defer da.names.mu.Unlock()
+	var size uint64
+	for name := range da.names.names {
+		size += 2*stringHeaderSize + mapEntryOverhead
+		if !rTypes.IsValid() || !rTypes.MapIndex(reflect.ValueOf(name)).IsValid() {
+			size += uint64(len(name))
+		}
+	}
+	return size
+}
+
+// byteFields sums the capacity of v if it is a byte slice, or of the byte slices of the struct v points to.
+func byteFields(v reflect.Value) uint64 {
+	if !v.IsValid() {
+		return 0
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+		return uint64(v.Cap())
+	}
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return 0
+	}
+	var size uint64
+	v = v.Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if field := v.Field(i); field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Uint8 {
+			size += uint64(field.Cap())
+		}
+	}
+	return size
+}
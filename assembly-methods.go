@@ -0,0 +1,191 @@
+package assembly
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// methodNames lists the function names of the method of typeName, a qualified type name that
+// may be prefixed by "*". The value receiver form comes first unless a pointer type is given.
+func methodNames(typeName, method string) []string {
+	ptr := strings.HasPrefix(typeName, "*")
+	pkg, base := splitPackagePath(strings.TrimPrefix(typeName, "*"))
+	if pkg == "" {
+		return nil
+	}
+	value, pointer := pkg+"."+base+"."+method, pkg+".(*"+base+")."+method
+	if ptr {
+		return []string{pointer, value}
+	}
+	return []string{value, pointer}
+}
+
+// FindMethod resolves the method of a type, declared with a value or a pointer receiver.
+// The function takes the receiver as its first parameter.
+func (da *dwarfAssembly) FindMethod(typeName, method string) (*proc.Function, error) {
+	for _, name := range methodNames(typeName, method) {
+		if f, err := da.findFunc(name); err == nil {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("method %s.%s: %w", typeName, method, ErrNotFound)
+}
+
+// CallMethod calls the method of a type on receiver. A pointer receiver is dereferenced for
+// methods declared on values, and an addressable value is passed by address to methods
+// declared on pointers, as the Go selector expression would.
+func (da *dwarfAssembly) CallMethod(typeName, method string, receiver reflect.Value, args []reflect.Value) ([]reflect.Value, error) {
+	f, err := da.FindMethod(typeName, method)
+	if err != nil {
+		return nil, err
+	}
+	inTyps, _, _, _, err := da.getFunctionArgTypes(f)
+	if err != nil {
+		return nil, err
+	}
+	if len(inTyps) == 0 {
+		return nil, fmt.Errorf("%s: no receiver parameter", f.Name)
+	}
+
+	recv, err := methodReceiver(receiver, inTyps[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Name, err)
+	}
+	return da.CallFunc(f.Name, false, append([]reflect.Value{recv}, args...))
+}
+
+// methodReceiver adapts receiver to the receiver parameter type want.
+func methodReceiver(receiver reflect.Value, want reflect.Type) (reflect.Value, error) {
+	switch {
+	case !receiver.IsValid():
+		return reflect.Value{}, fmt.Errorf("invalid receiver")
+	case receiver.Type().AssignableTo(want):
+		return receiver, nil
+	case receiver.Kind() == reflect.Pointer && receiver.Type().Elem().AssignableTo(want):
+		if receiver.IsNil() {
+			return reflect.Value{}, fmt.Errorf("nil receiver %s", receiver.Type())
+		}
+		return receiver.Elem(), nil
+	case want.Kind() == reflect.Pointer && receiver.Type().AssignableTo(want.Elem()):
+		if !receiver.CanAddr() {
+			return reflect.Value{}, fmt.Errorf("receiver %s is not addressable, method requires %s", receiver.Type(), want)
+		}
+		return receiver.Addr(), nil
+	}
+	return reflect.Value{}, fmt.Errorf("receiver type mismatch, except: %s, got: %s", want, receiver.Type())
+}
+
+// BindMethod returns the method of the live receiver recv as a func value without the receiver
+// parameter, like reflect.Value.Method but for methods resolved through DWARF, including
+// unexported ones. Like a Go method value, a value receiver is copied when binding.
+// The method is bound as a non variadic function. Calls of the func value are subject to the
+// call policy and rate limit like CallFunc, a call refused by them panics with the error.
+func (da *dwarfAssembly) BindMethod(recv reflect.Value, method string) (reflect.Value, error) {
+	c, bound, ftyp, err := da.boundMethod(recv, method)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return reflect.MakeFunc(ftyp, func(args []reflect.Value) []reflect.Value {
+		res, err := da.call(c, append([]reflect.Value{bound}, args...))
+		if err != nil {
+			panic(err)
+		}
+		return res.Values
+	}), nil
+}
+
+// boundMethod resolves method of recv for a call, returning the receiver argument to pass and
+// the signature without it.
+func (da *dwarfAssembly) boundMethod(recv reflect.Value, method string) (*preparedCall, reflect.Value, reflect.Type, error) {
+	if !recv.IsValid() {
+		return nil, reflect.Value{}, nil, fmt.Errorf("bind %s: invalid receiver", method)
+	}
+	typ := recv.Type()
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Name() == "" || typ.PkgPath() == "" {
+		return nil, reflect.Value{}, nil, fmt.Errorf("bind %s: %s is not a named type: %w", method, recv.Type(), ErrNotSupport)
+	}
+
+	f, err := da.FindMethod(typ.PkgPath()+"."+typ.Name(), method)
+	if err != nil {
+		return nil, reflect.Value{}, nil, err
+	}
+	c, err := da.prepareCall(f, false)
+	if err != nil {
+		return nil, reflect.Value{}, nil, err
+	}
+	if len(c.inTyps) == 0 {
+		return nil, reflect.Value{}, nil, fmt.Errorf("%s: no receiver parameter", f.Name)
+	}
+	bound, err := methodReceiver(recv, c.inTyps[0])
+	if err != nil {
+		return nil, reflect.Value{}, nil, fmt.Errorf("%s: %w", f.Name, err)
+	}
+	if bound.Kind() != reflect.Pointer {
+		copied := reflect.New(bound.Type()).Elem()
+		copied.Set(bound)
+		bound = copied
+	}
+	return c, bound, reflect.FuncOf(c.inTyps[1:], c.outTyps, false), nil
+}
+
+// ReceiverMode tells FindMethodType where to put the receiver of a method.
+type ReceiverMode int
+
+const (
+	// ReceiverFirst passes the receiver as the first parameter, the type of the method
+	// expression T.m, as FindFuncType does.
+	ReceiverFirst ReceiverMode = iota
+	// ReceiverStrip leaves the receiver out, the type of the method value t.m.
+	ReceiverStrip
+)
+
+// FindMethodType resolves the signature of the function name with its receiver placed
+// according to mode, and the type of the receiver, nil for functions that are not methods.
+// The signature of a function that is not a method is the same in both modes.
+func (da *dwarfAssembly) FindMethodType(name string, variadic bool, mode ReceiverMode) (reflect.Type, reflect.Type, error) {
+	f, err := da.findFunc(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	inTyps, outTyps, _, _, err := da.getFunctionArgTypes(f)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var recv reflect.Type
+	if isMethodName(f.Name) {
+		if len(inTyps) == 0 {
+			return nil, nil, fmt.Errorf("%s: no receiver parameter", f.Name)
+		}
+		recv = inTyps[0]
+		if mode == ReceiverStrip {
+			inTyps = inTyps[1:]
+		}
+	}
+	if variadic && (len(inTyps) == 0 || inTyps[len(inTyps)-1].Kind() != reflect.Slice) {
+		return nil, nil, fmt.Errorf("%s: last parameter is not a slice: %w", f.Name, ErrNotSupport)
+	}
+	return reflect.FuncOf(inTyps, outTyps, variadic), recv, nil
+}
+
+// isMethodName reports whether the function name is a method, "pkg.T.m" or "pkg.(*T).m",
+// rather than a function or a closure generated within one.
+func isMethodName(name string) bool {
+	if compilerFuncName.MatchString(name) {
+		return false
+	}
+	_, local := splitPackagePath(name)
+	if i := strings.Index(local, "["); i >= 0 {
+		// type arguments may contain dots, they do not separate the receiver
+		if j := strings.LastIndex(local, "]"); j > i {
+			local = local[:i] + local[j+1:]
+		}
+	}
+	return strings.Contains(local, ".")
+}
@@ -0,0 +1,139 @@
+package assembly
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unsafe"
+)
+
+// MethodValue is a global variable holding a method value, a method bound to its receiver,
+// e.g. `var onSave = store.Save`. The function value points to a closure of the compiler
+// generated "-fm" wrapper, which calls Method on the receiver stored in the closure.
+type MethodValue struct {
+	Global  string
+	Method  string
+	Wrapper string
+	// Receiver is the receiver stored in the closure, a copy for value receivers. It is settable,
+	// a change is seen by every holder of the function value.
+	Receiver reflect.Value
+}
+
+// FindMethodValue resolves the global variable name holding a method value.
+func (da *dwarfAssembly) FindMethodValue(name string) (*MethodValue, error) {
+	name, _ = da.resolveName(name, da.hasGlobal)
+	global, err := da.FindGlobal(name)
+	if err != nil {
+		return nil, err
+	}
+	if global.Kind() != reflect.Func || global.IsNil() {
+		return nil, fmt.Errorf("%s: %s is not a method value: %w", name, global.Type(), ErrNotSupport)
+	}
+
+	closure := *(*unsafe.Pointer)(global.Addr().UnsafePointer())
+	wrapper := da.binaryInfo.PCToFunc(uint64(*(*uintptr)(closure)))
+	if wrapper == nil || !strings.HasSuffix(wrapper.Name, "-fm") {
+		return nil, fmt.Errorf("%s: not a method value: %w", name, ErrNotSupport)
+	}
+	method, err := da.FindFuncEntry(strings.TrimSuffix(wrapper.Name, "-fm"))
+	if err != nil {
+		return nil, err
+	}
+	recvTyp, err := da.methodValueReceiver(method.Name, global.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	return &MethodValue{
+		Global:   da.displayName(name),
+		Method:   da.displayName(method.Name),
+		Wrapper:  da.displayName(wrapper.Name),
+		Receiver: reflect.NewAt(methodValueClosure(recvTyp), closure).Elem().Field(1),
+	}, nil
+}
+
+// RebindMethodValue assigns the global variable name a new method value binding receiver, or
+// the current receiver if it is invalid, to method of the receiver type, or the current method
+// if it is empty. The closure is allocated anew, holders of the previous value keep calling it.
+// A method whose method value the program never takes has no wrapper, and is bound like
+// BindMethod does, though calls of the program through it are not rate limited. Writes are
+// subject to the mutation and rate limit policies like SetGlobal.
+func (da *dwarfAssembly) RebindMethodValue(name string, receiver reflect.Value, method string) error {
+	mv, err := da.FindMethodValue(name)
+	if err != nil {
+		return err
+	}
+	if !receiver.IsValid() {
+		receiver = mv.Receiver
+	}
+	target := mv.Method
+	if method != "" {
+		typ := receiver.Type()
+		if typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		f, err := da.FindMethod(typ.PkgPath()+"."+typ.Name(), method)
+		if err != nil {
+			return err
+		}
+		target = f.Name
+	}
+
+	wrapper, err := da.FindFuncEntry(target + "-fm")
+	if err != nil || wrapper.Entry == 0 {
+		c, bound, ftyp, err := da.boundMethod(receiver, method)
+		if err != nil {
+			return err
+		}
+		return da.SetGlobal(name, reflect.MakeFunc(ftyp, func(args []reflect.Value) []reflect.Value {
+			return c.fn.Call(append([]reflect.Value{bound}, args...))
+		}))
+	}
+
+	global, err := da.FindGlobal(name)
+	if err != nil {
+		return err
+	}
+	recvTyp, err := da.methodValueReceiver(target, global.Type())
+	if err != nil {
+		return err
+	}
+	recv, err := methodReceiver(receiver, recvTyp)
+	if err != nil {
+		return fmt.Errorf("%s: %w", target, err)
+	}
+
+	closure := reflect.New(methodValueClosure(recvTyp))
+	closure.Elem().Field(0).SetUint(uint64(wrapper.Entry))
+	closure.Elem().Field(1).Set(recv)
+	fn := reflect.New(global.Type())
+	*(*unsafe.Pointer)(fn.UnsafePointer()) = closure.UnsafePointer()
+	return da.SetGlobal(name, fn.Elem())
+}
+
+// methodValueReceiver returns the receiver type of method, whose method value must be of type ftyp.
+func (da *dwarfAssembly) methodValueReceiver(method string, ftyp reflect.Type) (reflect.Type, error) {
+	f, err := da.FindFuncEntry(method)
+	if err != nil {
+		return nil, err
+	}
+	inTyps, outTyps, _, _, err := da.getFunctionArgTypes(f)
+	if err != nil {
+		return nil, err
+	}
+	if len(inTyps) == 0 {
+		return nil, fmt.Errorf("%s: no receiver parameter", f.Name)
+	}
+	if want := reflect.FuncOf(inTyps[1:], outTyps, ftyp.IsVariadic()); want != ftyp {
+		return nil, fmt.Errorf("%s: method value of type %s, variable is %s", f.Name, want, ftyp)
+	}
+	return inTyps[0], nil
+}
+
+// methodValueClosure returns the layout of the closure of a method value with receiver type recvTyp.
+func methodValueClosure(recvTyp reflect.Type) reflect.Type {
+	return reflect.StructOf([]reflect.StructField{
+		{Name: "F", Type: reflect.TypeOf(uintptr(0))},
+		{Name: "R", Type: recvTyp},
+	})
+}
@@ -0,0 +1,129 @@
+package assembly
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PatchMetrics is a snapshot of the counters of the patch subsystem.
+type PatchMetrics struct {
+	// Active is the number of patches currently applied.
+	Active int
+	// Calls counts the calls of each patched function by target name, for patches applied while
+	// MutationPolicy.CountCalls is set.
+	Calls map[string]uint64
+	// Rollbacks counts patches reverted after being applied.
+	Rollbacks uint64
+	// ValidationFailures counts patch targets and replacements refused by validation.
+	ValidationFailures uint64
+}
+
+// patchStats are the counters behind PatchMetrics.
+type patchStats struct {
+	mu        sync.Mutex
+	active    int
+	calls     map[string]uint64
+	rollbacks uint64
+	failures  uint64
+}
+
+func (s *patchStats) applied() {
+	s.mu.Lock()
+	s.active++
+	s.mu.Unlock()
+}
+
+func (s *patchStats) reverted(rollback bool) {
+	s.mu.Lock()
+	s.active--
+	if rollback {
+		s.rollbacks++
+	}
+	s.mu.Unlock()
+}
+
+func (s *patchStats) called(target string) {
+	s.mu.Lock()
+	if s.calls == nil {
+		s.calls = make(map[string]uint64)
+	}
+	s.calls[target]++
+	s.mu.Unlock()
+}
+
+// counting returns fn counting its calls under target.
+func (s *patchStats) counting(target string, fn reflect.Value) reflect.Value {
+	call := fn.Call
+	if fn.Type().IsVariadic() {
+		call = fn.CallSlice
+	}
+	return reflect.MakeFunc(fn.Type(), func(args []reflect.Value) []reflect.Value {
+		s.called(target)
+		return call(args)
+	})
+}
+
+// validated counts err as a validation failure unless it is nil.
+func (s *patchStats) validated(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.failures++
+	s.mu.Unlock()
+}
+
+func (da *dwarfAssembly) PatchMetrics() PatchMetrics {
+	s := &da.patchStats
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := PatchMetrics{Active: s.active, Rollbacks: s.rollbacks, ValidationFailures: s.failures, Calls: make(map[string]uint64, len(s.calls))}
+	for target, n := range s.calls {
+		m.Calls[target] = n
+	}
+	return m
+}
+
+// WritePrometheus writes m in the Prometheus text exposition format.
+func WritePrometheus(w io.Writer, m PatchMetrics) error {
+	var b strings.Builder
+	metric := func(name, typ, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+
+	metric("assembly_patches_active", "gauge", "Number of patches currently applied.")
+	fmt.Fprintf(&b, "assembly_patches_active %d\n", m.Active)
+
+	metric("assembly_patch_calls_total", "counter", "Calls of patched functions by target.")
+	targets := make([]string, 0, len(m.Calls))
+	for target := range m.Calls {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	for _, target := range targets {
+		fmt.Fprintf(&b, "assembly_patch_calls_total{target=%q} %d\n", target, m.Calls[target])
+	}
+
+	metric("assembly_patch_rollbacks_total", "counter", "Patches reverted after being applied.")
+	fmt.Fprintf(&b, "assembly_patch_rollbacks_total %d\n", m.Rollbacks)
+
+	metric("assembly_patch_validation_failures_total", "counter", "Patch targets and replacements refused by validation.")
+	fmt.Fprintf(&b, "assembly_patch_validation_failures_total %d\n", m.ValidationFailures)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// MetricsHandler serves the patch metrics of asm for a Prometheus scrape, it is optional and
+// only exposed where it is mounted, e.g. http.Handle("/metrics/assembly", MetricsHandler(asm)).
+func MetricsHandler(asm DwarfAssembly) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WritePrometheus(w, asm.PatchMetrics())
+	})
+}
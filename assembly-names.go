@@ -0,0 +1,260 @@
+package assembly
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// NameRewrite maps the package path prefix From of DWARF symbol names to To,
+// e.g. {From: "github.com/acme/app/", To: ""} presents "github.com/acme/app/orders.Recalc" as "orders.Recalc".
+type NameRewrite struct {
+	From string
+	To   string
+}
+
+// NamePolicy governs how symbol names are presented by iteration APIs and accepted by lookups.
+// Lookups accept both the DWARF name and the presented name. Rewrites apply to the package
+// qualified identifiers of a name, also within composite type names such as "[]orders.Item".
+type NamePolicy struct {
+	Rewrites []NameRewrite
+	// StripVendor presents packages vendored in GOPATH mode, "app/vendor/github.com/x/y",
+	// under their original path "github.com/x/y".
+	StripVendor bool
+}
+
+func (da *dwarfAssembly) SetNamePolicy(policy NamePolicy) {
+	da.namePolicy = policy
+	da.vendorRoots = nil
+}
+
+// RegisterAlias makes every lookup of alias resolve target, a fully qualified symbol name
+// of a function, type or global. Aliases do not chain, target is used as is.
+func (da *dwarfAssembly) RegisterAlias(alias string, target string) error {
+	if current, ok := da.aliases[alias]; ok && current != target {
+		return fmt.Errorf("alias %s already registered for %s", alias, current)
+	}
+	if da.aliases == nil {
+		da.aliases = make(map[string]string)
+	}
+	da.aliases[alias] = target
+	return nil
+}
+
+func (da *dwarfAssembly) UnregisterAlias(alias string) {
+	delete(da.aliases, alias)
+}
+
+func (da *dwarfAssembly) Aliases() map[string]string {
+	aliases := make(map[string]string, len(da.aliases))
+	for alias, target := range da.aliases {
+		aliases[alias] = target
+	}
+	return aliases
+}
+
+// resolveName returns the first DWARF name name may stand for that found reports as present.
+func (da *dwarfAssembly) resolveName(name string, found func(name string) bool) (string, bool) {
+	for _, candidate := range da.nameCandidates(name) {
+		if found(candidate) {
+			da.trace(ResolveStep{Step: "candidate", Name: candidate, Detail: "found"})
+			return candidate, true
+		}
+		da.trace(ResolveStep{Step: "candidate", Name: candidate, Detail: "not found"})
+	}
+	return name, false
+}
+
+// nameCandidates lists the DWARF names a name given to a lookup API may stand for, in order of preference.
+func (da *dwarfAssembly) nameCandidates(name string) []string {
+	if target, ok := da.aliases[name]; ok {
+		return []string{target}
+	}
+
+	var candidates = append([]string{name}, alternateForms(name)...)
+	for _, rw := range da.namePolicy.Rewrites {
+		candidate := mapQualified(name, func(ident string) string {
+			if strings.HasPrefix(ident, rw.To) {
+				return rw.From + ident[len(rw.To):]
+			}
+			return ident
+		})
+		if candidate != name {
+			candidates = append(candidates, candidate)
+		}
+	}
+	if da.namePolicy.StripVendor {
+		for _, root := range da.vendorRootList() {
+			candidate := mapQualified(name, func(ident string) string {
+				if strings.Contains(ident, "/vendor/") {
+					return ident
+				}
+				return root + ident
+			})
+			candidates = append(candidates, candidate)
+		}
+	}
+	return candidates
+}
+
+// autogenPrefixes are the spellings of compiler generated symbol prefixes, the current one first.
+var autogenPrefixes = [][]string{
+	{"type:.eq.", "type..eq."},
+	{"type:.hash.", "type..hash."},
+	{"go:itab.", "go.itab."},
+}
+
+// CanonicalName returns the display form of a symbol name as encoded by any Go toolchain:
+// middle dots and %2e escapes of package paths are decoded, go.shape type arguments are
+// replaced by the types they stand for and compiler generated prefixes use the current spelling.
+func CanonicalName(name string) string {
+	name = strings.ReplaceAll(name, "\u00b7", ".")
+	name = strings.ReplaceAll(name, "%2e", ".")
+	name = strings.ReplaceAll(name, "go.shape.", "")
+	for _, spellings := range autogenPrefixes {
+		for _, old := range spellings[1:] {
+			if strings.HasPrefix(name, old) {
+				name = spellings[0] + name[len(old):]
+			}
+		}
+	}
+	return name
+}
+
+// alternateForms lists the other encodings a symbol name may be recorded with in DWARF.
+func alternateForms(name string) []string {
+	var forms []string
+	add := func(form string) {
+		if form != name && !slices.Contains(forms, form) {
+			forms = append(forms, form)
+		}
+	}
+
+	canonical := CanonicalName(name)
+	add(canonical)
+
+	for _, spellings := range autogenPrefixes {
+		if strings.HasPrefix(canonical, spellings[0]) {
+			for _, old := range spellings[1:] {
+				add(old + canonical[len(spellings[0]):])
+			}
+		}
+	}
+
+	// generic instantiations are recorded with shape type arguments.
+	if open := strings.Index(canonical, "["); open >= 0 {
+		add(canonical[:open] + mapTypeArgs(canonical[open:], func(arg string) string { return "go.shape." + arg }))
+	}
+
+	// older toolchains escape the dots of the last package path element, any dot of it
+	// but the last may be part of the path rather than separate the package from the name.
+	slash := strings.LastIndex(canonical, "/")
+	if slash >= 0 {
+		last := canonical[slash+1:]
+		for i := 0; i < len(last); i++ {
+			if last[i] == '.' && strings.Contains(last[i+1:], ".") {
+				pkg, rest, _ := strings.Cut(last[i+1:], ".")
+				add(canonical[:slash+1] + strings.ReplaceAll(last[:i+1+len(pkg)], ".", "%2e") + "." + rest)
+			}
+		}
+	}
+	return forms
+}
+
+// mapTypeArgs applies fn to the top level type arguments of the bracketed list args.
+func mapTypeArgs(args string, fn func(arg string) string) string {
+	var b strings.Builder
+	var depth, start int
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case '[':
+			if depth == 0 {
+				b.WriteString(args[start : i+1])
+				start = i + 1
+			}
+			depth++
+		case ']', ',':
+			if args[i] == ']' {
+				depth--
+			}
+			if depth == 0 || (depth == 1 && args[i] == ',') {
+				b.WriteString(fn(args[start:i]))
+				b.WriteByte(args[i])
+				start = i + 1
+			}
+		}
+	}
+	b.WriteString(args[start:])
+	return b.String()
+}
+
+// displayName returns the name a DWARF symbol name is presented with by iteration APIs.
+func (da *dwarfAssembly) displayName(name string) string {
+	if !da.namePolicy.StripVendor && len(da.namePolicy.Rewrites) == 0 {
+		return name
+	}
+	return mapQualified(name, func(ident string) string {
+		if da.namePolicy.StripVendor {
+			if i := strings.LastIndex(ident, "/vendor/"); i >= 0 {
+				ident = ident[i+len("/vendor/"):]
+			}
+		}
+		for _, rw := range da.namePolicy.Rewrites {
+			if strings.HasPrefix(ident, rw.From) {
+				return rw.To + ident[len(rw.From):]
+			}
+		}
+		return ident
+	})
+}
+
+// vendorRootList returns the "app/vendor/" prefixes of the vendored packages in the loaded images.
+func (da *dwarfAssembly) vendorRootList() []string {
+	if da.vendorRoots != nil {
+		return da.vendorRoots
+	}
+
+	var roots = make(map[string]bool)
+	functions := da.snapshot().functions
+	for i := range functions {
+		if j := strings.LastIndex(functions[i].Name, "/vendor/"); j >= 0 {
+			roots[functions[i].Name[:j+len("/vendor/")]] = true
+		}
+	}
+	da.vendorRoots = make([]string, 0, len(roots))
+	for root := range roots {
+		da.vendorRoots = append(da.vendorRoots, root)
+	}
+	return da.vendorRoots
+}
+
+// mapQualified applies fn to every package qualified identifier of name, leaving
+// the predeclared identifiers and the punctuation of composite type names untouched.
+func mapQualified(name string, fn func(ident string) string) string {
+	var b strings.Builder
+	var start = -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		if ident := name[start:end]; strings.Contains(ident, ".") && ident[0] != '.' {
+			b.WriteString(fn(ident))
+		} else {
+			b.WriteString(ident)
+		}
+		start = -1
+	}
+	for i := 0; i < len(name); i++ {
+		switch name[i] {
+		case '[', ']', '*', '(', ')', ',', ' ', '{', '}', ';':
+			flush(i)
+			b.WriteByte(name[i])
+		default:
+			if start < 0 {
+				start = i
+			}
+		}
+	}
+	flush(len(name))
+	return b.String()
+}
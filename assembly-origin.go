@@ -0,0 +1,84 @@
+package assembly
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// wrapperName matches the functions the compiler generates around a single call of another
+// function: method values, go/defer statement wrappers and ABI0 wrappers.
+var wrapperName = regexp.MustCompile(`-fm$|\.(gowrap|deferwrap)\d+$|·dwrap·\d+$|\.abi0$`)
+
+// maxWrapperDepth bounds the chain of wrappers followed by FuncOrigin.
+const maxWrapperDepth = 4
+
+// FuncOrigin returns the function a compiler generated wrapper forwards to, so hooks apply to
+// the real implementation, following nested wrappers. Other functions are returned unchanged.
+func (da *dwarfAssembly) FuncOrigin(name string) (*proc.Function, error) {
+	f, err := da.FindFuncEntry(name)
+	if err != nil {
+		return nil, err
+	}
+	for depth := 0; isWrapper(f); depth++ {
+		if depth == maxWrapperDepth {
+			return nil, fmt.Errorf("%w: wrapper chain of %s too deep", ErrNotFound, name)
+		}
+		origin, err := da.wrapperTarget(f)
+		if err != nil {
+			return nil, err
+		}
+		f = origin
+	}
+	return f, nil
+}
+
+// isWrapper reports whether f is a compiler generated wrapper.
+func isWrapper(f *proc.Function) bool {
+	trampoline := reflect.ValueOf(f).Elem().FieldByName("trampoline")
+	return wrapperName.MatchString(f.Name) || (trampoline.IsValid() && trampoline.Bool())
+}
+
+// wrapperTarget derives the target of the wrapper f from its name where the compiler encodes
+// it, otherwise it is the first function outside the runtime f calls or jumps to.
+func (da *dwarfAssembly) wrapperTarget(f *proc.Function) (*proc.Function, error) {
+	for _, name := range wrappedNames(f.Name) {
+		if origin := da.binaryInfo.LookupFunc()[name]; len(origin) > 0 && origin[0].Entry != 0 {
+			return origin[0], nil
+		}
+	}
+
+	if f.Entry == 0 || f.End <= f.Entry {
+		return nil, fmt.Errorf("%w: %s has no code to find its target in", ErrNotFound, f.Name)
+	}
+	instructions, err := proc.Disassemble(new(localMemory), nil, &proc.BreakpointMap{}, da.binaryInfo, f.Entry, f.End)
+	if err != nil {
+		return nil, fmt.Errorf("disassemble %s: %w", f.Name, err)
+	}
+	for _, inst := range instructions {
+		if !inst.IsCall() && !inst.IsJmp() || inst.DestLoc == nil || inst.DestLoc.Fn == nil {
+			continue
+		}
+		if target := inst.DestLoc.Fn; target != f && target.PackageName() != "runtime" {
+			return target, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: target of wrapper %s", ErrNotFound, f.Name)
+}
+
+// wrappedNames lists the names the target of a wrapper named name may have.
+func wrappedNames(name string) []string {
+	switch {
+	case strings.HasSuffix(name, "-fm"):
+		return []string{strings.TrimSuffix(name, "-fm")}
+	case strings.HasSuffix(name, ".abi0"):
+		return []string{strings.TrimSuffix(name, ".abi0")}
+	case strings.Contains(name, ".(*") && !wrapperName.MatchString(name):
+		// pointer receiver wrappers of value receiver methods.
+		return []string{strings.Replace(strings.Replace(name, ".(*", ".", 1), ").", ".", 1)}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package assembly
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// packageIndex holds the symbol names sorted, so the symbols of a package are one range of them.
+// Each index is built on first use and dropped when the images change.
+type packageIndex struct {
+	mu        sync.Mutex
+	functions []proc.Function // snapshot the positions in funcs refer to
+	funcs     []int
+	types     []string
+	globals   []string
+}
+
+// ForeachFuncInPackage yields the functions of the packages under the import path prefix, see
+// inPackage, sorted by name. The visibility policy is not applied.
+func (da *dwarfAssembly) ForeachFuncInPackage(prefix string, f func(name string, pc uint64) bool) {
+	functions, funcs := da.packageFuncs()
+	start := sort.Search(len(funcs), func(i int) bool { return functions[funcs[i]].Name >= prefix })
+	for _, i := range funcs[start:] {
+		fn := &functions[i]
+		if !strings.HasPrefix(fn.Name, prefix) {
+			break
+		}
+		if inPackage(fn.Name, prefix) && !f(da.displayName(fn.Name), fn.Entry) {
+			break
+		}
+	}
+}
+
+// ForeachTypeInPackage yields the types declared by the packages under the import path prefix,
+// see inPackage, sorted by name. The visibility policy is not applied.
+func (da *dwarfAssembly) ForeachTypeInPackage(prefix string, f func(name string) bool) error {
+	types, err := da.packageTypes()
+	if err != nil {
+		return err
+	}
+	for _, name := range prefixRange(types, prefix) {
+		if inPackage(name, prefix) && !f(da.displayName(name)) {
+			break
+		}
+	}
+	return nil
+}
+
+// ForeachGlobalInPackage yields the global variables of the packages under the import path
+// prefix, see inPackage, sorted by name. Only the variables yielded are resolved.
+func (da *dwarfAssembly) ForeachGlobalInPackage(prefix string, fn func(name string, value reflect.Value) bool) {
+	for _, name := range prefixRange(da.packageGlobals(), prefix) {
+		if !inPackage(name, prefix) {
+			continue
+		}
+		defs := da.namedGlobals(name)
+		if len(defs) == 0 {
+			continue
+		}
+		if !fn(da.displayName(name), da.preferredGlobal(defs).value) {
+			break
+		}
+	}
+}
+
+// inPackage reports whether the symbol name is declared by the package prefix or a package
+// below it. A prefix ending in "/" admits every package path starting with it.
+func inPackage(name, prefix string) bool {
+	pkg := symbolPackage(name)
+	if strings.HasSuffix(prefix, "/") {
+		return strings.HasPrefix(pkg, prefix)
+	}
+	return pkg == prefix || strings.HasPrefix(pkg, prefix+"/")
+}
+
+// prefixRange returns the names of the sorted names starting with prefix.
+func prefixRange(names []string, prefix string) []string {
+	start := sort.SearchStrings(names, prefix)
+	end := start
+	for end < len(names) && strings.HasPrefix(names[end], prefix) {
+		end++
+	}
+	return names[start:end]
+}
+
+// packageFuncs returns the function snapshot and the positions of its functions with code,
+// sorted by name.
+func (da *dwarfAssembly) packageFuncs() ([]proc.Function, []int) {
+	functions := da.snapshot().functions
+	index := &da.packages
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	if index.funcs == nil || len(index.functions) != len(functions) || (len(functions) > 0 && &index.functions[0] != &functions[0]) {
+		funcs := make([]int, 0, len(functions))
+		for i := range functions {
+			if functions[i].Entry != 0 {
+				funcs = append(funcs, i)
+			}
+		}
+		sort.Slice(funcs, func(i, j int) bool { return functions[funcs[i]].Name < functions[funcs[j]].Name })
+		index.functions, index.funcs = functions, funcs
+	}
+	return index.functions, index.funcs
+}
+
+func (da *dwarfAssembly) packageTypes() ([]string, error) {
+	index := &da.packages
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	if index.types == nil {
+		types, err := da.binaryInfo.Types()
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(types)
+		index.types = types
+	}
+	return index.types, nil
+}
+
+func (da *dwarfAssembly) packageGlobals() []string {
+	index := &da.packages
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	if index.globals == nil {
+		index.globals = da.globalNames()
+	}
+	return index.globals
+}
+
+// resetPackageIndex drops the package indexes after the images changed.
+func (da *dwarfAssembly) resetPackageIndex() {
+	da.packages.mu.Lock()
+	da.packages.functions, da.packages.funcs, da.packages.types, da.packages.globals = nil, nil, nil, nil
+	da.packages.mu.Unlock()
+}
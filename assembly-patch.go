@@ -0,0 +1,271 @@
+package assembly
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// Patch is a function whose entry PatchFunc rewrote with a jump to a replacement.
+type Patch struct {
+	// Site is the resolved target and replacement.
+	Site PatchSite
+	// Applied is when the jump was written.
+	Applied time.Time
+
+	da        *dwarfAssembly
+	displaced []byte
+	// funcval is the function value of the replacement the jump loads into the closure context
+	// register, it must stay allocated while the jump is in place.
+	funcval     unsafe.Pointer
+	replacement reflect.Value
+	reverted    bool
+	// safe rewrites the target while it cannot execute, see PatchFuncSafe.
+	safe bool
+
+	// trampoline runs the original function, trampolineErr tells why there is none.
+	trampoline    *trampoline
+	trampolineErr error
+	original      reflect.Value
+}
+
+// patchSet is the patches applied in the process, code is shared by every assembly
+// so a target cannot be patched twice through different assemblies either.
+type patchSet struct {
+	mu     sync.Mutex
+	active map[uint64]*Patch
+}
+
+var patches patchSet
+
+// PatchFunc redirects every call of target to replacement by rewriting the entry of target with
+// a jump, see PatchSite for the requirements on target and replacement. Calls inlined into their
+// callers are not redirected, InlineSites lists them. The jump is written while other goroutines
+// may execute target, PatchFuncSafe avoids that. Patches outlive Close of the assembly, they are
+// only undone by Revert. The original implementation stays callable through Original. Unlike
+// PatchSite, replacement may be any function value: a closure, a reflect.MakeFunc function or a
+// method value, the jump loads its function value as a call through it does.
+func (da *dwarfAssembly) PatchFunc(target string, replacement reflect.Value) (*Patch, error) {
+	return da.patchFunc(target, replacement, false)
+}
+
+func (da *dwarfAssembly) patchFunc(target string, replacement reflect.Value, safe bool) (*Patch, error) {
+	if err := da.limit(&da.limits.patches, "patch", target); err != nil {
+		return nil, err
+	}
+	site, err := da.patchSite(target, replacement, true)
+	if err != nil {
+		return nil, err
+	}
+
+	patches.mu.Lock()
+	defer patches.mu.Unlock()
+	if _, ok := patches.active[site.TargetPC]; ok {
+		err = fmt.Errorf("%s: %w", site.Target, ErrAlreadyPatched)
+		da.patchStats.validated(err)
+		return nil, err
+	}
+
+	if da.mutPolicy.CountCalls {
+		replacement = da.patchStats.counting(site.Target, replacement)
+	}
+	p := &Patch{Site: *site, da: da, funcval: funcValuePointer(replacement), replacement: replacement, safe: safe}
+	code, err := jumpCode(da.binaryInfo.Arch.Name, uintptr(p.funcval))
+	if err != nil {
+		return nil, err
+	}
+	p.displaced = make([]byte, len(code))
+	copy(p.displaced, entryAddress(uintptr(site.TargetPC), len(code)))
+
+	f := da.binaryInfo.PCToFunc(site.TargetPC)
+	if p.trampolineErr = da.checkClosure(f); p.trampolineErr == nil {
+		p.trampoline, p.trampolineErr = da.buildTrampoline(f, len(code))
+	}
+	if p.trampoline != nil {
+		for i := range p.trampoline.restarts {
+			r := &p.trampoline.restarts[i]
+			r.original = make([]byte, len(r.code))
+			copy(r.original, entryAddress(r.pc, len(r.code)))
+		}
+	}
+	if err = p.write(func(write codeWriter) error { return p.apply(write, code) }); err != nil {
+		if !errors.Is(err, ErrPatchBusy) {
+			err = fmt.Errorf("%s: %w", site.Target, err)
+		}
+		return nil, err
+	}
+
+	if patches.active == nil {
+		patches.active = make(map[uint64]*Patch)
+	}
+	patches.active[site.TargetPC] = p
+	p.Applied = time.Now()
+	da.patchStats.applied()
+	return p, nil
+}
+
+// Revert restores the original entry of the target, reverting a reverted patch does nothing.
+func (p *Patch) Revert() error {
+	patches.mu.Lock()
+	defer patches.mu.Unlock()
+	if p.reverted {
+		return nil
+	}
+	if err := p.write(p.restore); err != nil {
+		if !errors.Is(err, ErrPatchBusy) {
+			err = fmt.Errorf("%s: %w", p.Site.Target, err)
+		}
+		return err
+	}
+	p.reverted = true
+	delete(patches.active, p.Site.TargetPC)
+	p.da.patchStats.reverted(true)
+	return nil
+}
+
+// Info describes the patch, Original is a copy of the bytes the jump replaced.
+func (p *Patch) Info() PatchInfo {
+	info := PatchInfo{
+		Target:   p.Site.Target,
+		TargetPC: p.Site.TargetPC,
+		Original: append([]byte(nil), p.displaced...),
+		Applied:  p.Applied,
+	}
+	if f := p.da.binaryInfo.PCToFunc(p.Site.ReplacementPC); f != nil {
+		info.Replacement = p.da.displayName(f.Name)
+	}
+	return info
+}
+
+// Reverted reports whether the patch was reverted.
+func (p *Patch) Reverted() bool {
+	patches.mu.Lock()
+	defer patches.mu.Unlock()
+	return p.reverted
+}
+
+// apply writes the restarts redirected to the trampoline, then the jump code at the entry.
+func (p *Patch) apply(write codeWriter, code []byte) error {
+	if p.trampoline != nil {
+		for _, r := range p.trampoline.restarts {
+			if err := write(r.pc, r.code); err != nil {
+				p.restoreRestarts(write)
+				return err
+			}
+		}
+	}
+	if err := write(uintptr(p.Site.TargetPC), code); err != nil {
+		p.restoreRestarts(write)
+		return err
+	}
+	return nil
+}
+
+// restore writes back the displaced entry, then the original restarts.
+func (p *Patch) restore(write codeWriter) error {
+	if err := write(uintptr(p.Site.TargetPC), p.displaced); err != nil {
+		return err
+	}
+	return p.restoreRestarts(write)
+}
+
+// write runs a rewrite of the target, with a safe patch while no goroutine executes it. The
+// pages of the target are made writable before and restored after, the rewrite only copies.
+func (p *Patch) write(rewrite func(write codeWriter) error) error {
+	if !p.safe {
+		return rewrite(writeCode)
+	}
+	windows := []codeWindow{{start: 0, end: uint64(len(p.displaced))}}
+	code := []codePatch{{pc: uintptr(p.Site.TargetPC), code: p.displaced}}
+	if p.trampoline != nil {
+		for _, r := range p.trampoline.restarts {
+			start := uint64(r.pc) - p.Site.TargetPC
+			windows = append(windows, codeWindow{start: start, end: start + uint64(len(r.code))})
+			code = append(code, r)
+		}
+	}
+	release, err := holdWritable(code)
+	if err != nil {
+		return err
+	}
+	err = quiesce(runtime.FuncForPC(uintptr(p.Site.TargetPC)).Name(), windows, func() error { return rewrite(copyCode) })
+	if releaseErr := release(); err == nil {
+		err = releaseErr
+	}
+	return err
+}
+
+// restoreRestarts undoes the redirection of the restarts of the target to the trampoline.
+func (p *Patch) restoreRestarts(write codeWriter) error {
+	if p.trampoline == nil {
+		return nil
+	}
+	for _, r := range p.trampoline.restarts {
+		if r.original == nil {
+			continue
+		}
+		if err := write(r.pc, r.original); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// funcValuePointer returns the function value fn points to, the code pointer and closure
+// context, including the one a method value of reflect has allocated, which funcValue misses.
+func funcValuePointer(fn reflect.Value) unsafe.Pointer {
+	holder := reflect.New(fn.Type())
+	holder.Elem().Set(fn)
+	return *(*unsafe.Pointer)(holder.UnsafePointer())
+}
+
+// codeWriter writes code into a function, writeCode or copyCode.
+type codeWriter func(pc uintptr, code []byte) error
+
+// writeCode writes code at pc and makes it visible to instruction fetch.
+func writeCode(pc uintptr, code []byte) error {
+	if _, err := writeLocal(pc, code); err != nil {
+		return err
+	}
+	flushInstructionCache(pc, pc+uintptr(len(code)))
+	return nil
+}
+
+// copyCode is writeCode for pages holdWritable made writable, it makes no system call.
+func copyCode(pc uintptr, code []byte) error {
+	copy(entryAddress(pc, len(code)), code)
+	flushInstructionCache(pc, pc+uintptr(len(code)))
+	return nil
+}
+
+// jumpCode returns the patchJumpSize bytes jumping to the code pointer of the function value
+// at funcval. The function value is loaded into the closure context register, DX on amd64 and
+// R26 on arm64, as the compiler does for a call through a function value.
+func jumpCode(arch string, funcval uintptr) ([]byte, error) {
+	switch arch {
+	case "amd64":
+		code := []byte{0x48, 0xba, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0x22} // MOVQ $funcval, DX; JMP (DX)
+		binary.LittleEndian.PutUint64(code[2:], uint64(funcval))
+		return code, nil
+	case "arm64":
+		const ctxt, tmp = 26, 27
+		var code []byte
+		for hw := uint32(0); hw < 4; hw++ {
+			op := uint32(0xf2800000) // MOVK
+			if hw == 0 {
+				op = 0xd2800000 // MOVZ
+			}
+			imm := uint32(funcval>>(16*hw)) & 0xffff
+			code = binary.LittleEndian.AppendUint32(code, op|hw<<21|imm<<5|ctxt)
+		}
+		code = binary.LittleEndian.AppendUint32(code, 0xf9400000|ctxt<<5|tmp) // MOVD (R26), R27
+		code = binary.LittleEndian.AppendUint32(code, 0xd61f0000|tmp<<5)      // JMP (R27)
+		return code, nil
+	}
+	return nil, fmt.Errorf("%w: patching on %s", ErrNotSupport, arch)
+}
@@ -0,0 +1,288 @@
+package assembly
+
+import (
+	"debug/dwarf"
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
+	"github.com/go-delve/delve/pkg/proc"
+	"golang.org/x/arch/arm64/arm64asm"
+	"golang.org/x/arch/x86/x86asm"
+)
+
+func TestJumpCode(t *testing.T) {
+	const funcval = 0x123456789abc
+
+	code, err := jumpCode("amd64", funcval)
+	if nil != err || uint64(len(code)) != patchJumpSize["amd64"] {
+		t.Fatalf("jumpCode(amd64) got = %x, %v", code, err)
+	}
+	var text []string
+	for pc := 0; pc < len(code); {
+		inst, err := x86asm.Decode(code[pc:], 64)
+		if nil != err {
+			t.Fatalf("jumpCode(amd64) decode at %d: %v", pc, err)
+		}
+		text = append(text, x86asm.GoSyntax(inst, 0, nil))
+		pc += inst.Len
+	}
+	if want := []string{"MOVQ $0x123456789abc, DX", "JMP 0(DX)"}; !slices.Equal(text, want) {
+		t.Fatalf("jumpCode(amd64) got = %q, want %q", text, want)
+	}
+
+	code, err = jumpCode("arm64", funcval)
+	if nil != err || uint64(len(code)) != patchJumpSize["arm64"] {
+		t.Fatalf("jumpCode(arm64) got = %x, %v", code, err)
+	}
+	text = text[:0]
+	for pc := 0; pc < len(code); pc += 4 {
+		inst, err := arm64asm.Decode(code[pc:])
+		if nil != err {
+			t.Fatalf("jumpCode(arm64) decode at %d: %v", pc, err)
+		}
+		text = append(text, inst.String())
+	}
+	want := []string{"MOV X26, #0x9abc", "MOVK X26, #0x5678, LSL #16", "MOVK X26, #0x1234, LSL #32", "MOVK X26, #0x0, LSL #48", "LDR X27, [X26]", "BR X27"}
+	if !slices.Equal(text, want) {
+		t.Fatalf("jumpCode(arm64) got = %q, want %q", text, want)
+	}
+
+	if _, err = jumpCode("386", funcval); nil == err {
+		t.Fatalf("jumpCode(386) accepted an unsupported architecture")
+	}
+}
+
+func TestRelocateARM64(t *testing.T) {
+	const entry = 0x10000
+	words := []uint32{
+		0xf9400b90, // MOVD 16(R28), R16
+		0xeb3063ff, // CMP R16, RSP
+		0x540000a9, // BLS 5(PC)
+		0xf81e0ffe, // MOVD.W R30, -32(RSP)
+		0xf81f83fd, // MOVD R29, -8(RSP)
+		0xd10023fd, // SUB $8, RSP, R29
+	}
+	var displaced []proc.AsmInstruction
+	for i, word := range words {
+		displaced = append(displaced, proc.AsmInstruction{
+			Loc:   proc.Location{PC: entry + uint64(4*i)},
+			Bytes: binary.LittleEndian.AppendUint32(nil, word),
+			Size:  4,
+		})
+	}
+
+	code, err := relocateARM64(displaced, entry+24)
+	if nil != err {
+		t.Fatalf("relocateARM64() error: %v", err)
+	}
+	literal := func(at int) uint64 {
+		if word := binary.LittleEndian.Uint32(code[at:]); 0x5800005b != word {
+			t.Fatalf("relocateARM64() got %#x at %d, want LDR 8(PC), R27", word, at)
+		}
+		return binary.LittleEndian.Uint64(code[at+8:])
+	}
+	if resume := literal(24); entry+24 != resume {
+		t.Fatalf("relocateARM64() resumes at %#x, want %#x", resume, entry+24)
+	}
+	branch, err := arm64asm.Decode(code[8:])
+	if nil != err || arm64asm.B != branch.Op {
+		t.Fatalf("relocateARM64() got %v, %v at 8, want BLS", branch, err)
+	}
+	stub := 8 + int(branch.Args[1].(arm64asm.PCRel))
+	if dest := literal(stub); entry+28 != dest {
+		t.Fatalf("relocateARM64() branches to %#x, want %#x", dest, entry+28)
+	}
+}
+
+func TestInWindows(t *testing.T) {
+	const stacks = `goroutine 1 [running]:
+main.(*T).run(0xc000010000, {0x4b2a10, 0x3})
+	/src/main.go:12 +0x1d
+main.hot(...)
+	/src/main.go:20
+main.main()
+	/src/main.go:30 +0x5
+
+goroutine 7 [runnable]:
+main.(*T).run(0xc000010000, {0x4b2a10, 0x3})
+	/src/main.go:10 +0x4
+created by main.main in goroutine 1
+	/src/main.go:29 +0x40
+`
+	if got := frameOffsets([]byte(stacks), "main.(*T).run"); !slices.Equal(got, []uint64{0x1d, 0x4}) {
+		t.Fatalf("frameOffsets() got = %x, want [1d 4]", got)
+	}
+	if got := frameOffsets([]byte(stacks), "main.hot"); len(got) != 0 {
+		t.Fatalf("frameOffsets() of an inlined frame got = %x, want none", got)
+	}
+	if got := frameOffsets([]byte(stacks), "main.main"); !slices.Equal(got, []uint64{0x5, 0x40}) {
+		t.Fatalf("frameOffsets() with a creator got = %x, want [5 40]", got)
+	}
+
+	if !inWindows([]byte(stacks), "main.(*T).run", []codeWindow{{start: 0, end: 12}}) {
+		t.Fatal("inWindows() missed a frame inside the entry")
+	}
+	if inWindows([]byte(stacks), "main.(*T).run", []codeWindow{{start: 0, end: 4}, {start: 0x1d, end: 0x22}}) {
+		t.Fatal("inWindows() reported frames at the start or past the end of windows")
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newTokenBucket(RateLimit{Rate: 2, Burst: 3}, now)
+	for i := 0; i < 3; i++ {
+		if err := b.take(now); err != nil {
+			t.Fatalf("take() %d within the burst error: %v", i, err)
+		}
+	}
+	if err := b.take(now); err == nil {
+		t.Fatal("take() past the burst error = nil")
+	}
+	if err := b.take(now.Add(500 * time.Millisecond)); err != nil {
+		t.Fatalf("take() after refilling one token error: %v", err)
+	}
+	if err := b.take(now.Add(time.Hour)); err != nil {
+		t.Fatalf("take() after a long pause error: %v", err)
+	}
+	if b.tokens != 2 {
+		t.Fatalf("tokens after a long pause = %g, want the burst less one", b.tokens)
+	}
+
+	q := newTokenBucket(RateLimit{Quota: 1}, now)
+	if err := q.take(now); err != nil {
+		t.Fatalf("take() within the quota error: %v", err)
+	}
+	if err := q.take(now.Add(time.Hour)); err == nil {
+		t.Fatal("take() past the quota error = nil")
+	}
+}
+
+func TestCompareResults(t *testing.T) {
+	type item struct {
+		Name  string
+		count int
+	}
+	type order struct {
+		Items []item
+		Tags  map[string]int
+		Next  *order
+	}
+	a := &order{Items: []item{{"a", 1}, {"b", 2}}, Tags: map[string]int{"x": 1, "y": 2}}
+	b := &order{Items: []item{{"a", 1}, {"b", 3}, {"c", 0}}, Tags: map[string]int{"x": 1, "z": 2}}
+	a.Next, b.Next = a, b
+
+	values := func(v ...any) []reflect.Value {
+		var out []reflect.Value
+		for _, x := range v {
+			out = append(out, reflect.ValueOf(x))
+		}
+		return out
+	}
+	var paths []string
+	for _, d := range CompareResults(values(a, 1), values(b, 1, errors.New("failed"))) {
+		paths = append(paths, d.Path)
+	}
+	want := []string{`[0].Items[1].count`, `[0].Items[2]`, `[0].Tags["y"]`, `[0].Tags["z"]`, `[2]`}
+	if !slices.Equal(paths, want) {
+		t.Fatalf("CompareResults() paths = %q, want %q", paths, want)
+	}
+	if diffs := CompareResults(values(a, math.NaN()), values(a, math.NaN())); len(diffs) != 0 {
+		t.Fatalf("CompareResults() of equal results got = %v", diffs)
+	}
+}
+
+func TestCompareLayouts(t *testing.T) {
+	integer := func(name string, size int64) godwarf.Type {
+		return &godwarf.IntType{BasicType: godwarf.BasicType{CommonType: godwarf.CommonType{Name: name, ByteSize: size, ReflectKind: reflect.Int}}}
+	}
+	order := func(offset dwarf.Offset, fields ...*godwarf.StructField) godwarf.Type {
+		last := fields[len(fields)-1]
+		return &godwarf.StructType{
+			CommonType: godwarf.CommonType{Name: "orders.Order", ByteSize: last.ByteOffset + last.Type.Size(), ReflectKind: reflect.Struct, Offset: offset},
+			StructName: "orders.Order",
+			Kind:       "struct",
+			Field:      fields,
+		}
+	}
+	a := order(1, &godwarf.StructField{Name: "ID", Type: integer("int", 8)}, &godwarf.StructField{Name: "Total", Type: integer("int", 8), ByteOffset: 8})
+	b := order(2, &godwarf.StructField{Name: "ID", Type: integer("int64", 8)}, &godwarf.StructField{Name: "Total", Type: integer("int", 8), ByteOffset: 8}, &godwarf.StructField{Name: "Paid", Type: integer("int", 8), ByteOffset: 16})
+
+	var mismatches []TypeMismatch
+	compareLayouts(&mismatches, "orders.Order", a, b, make(map[[2]dwarf.Offset]bool))
+	var got []string
+	for _, m := range mismatches {
+		got = append(got, m.Path+" "+m.Kind)
+	}
+	want := []string{"orders.Order size", "orders.Order.ID type", "orders.Order.Paid field"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("compareLayouts() got = %q, want %q", got, want)
+	}
+
+	mismatches = nil
+	compareLayouts(&mismatches, "orders.Order", a, a, make(map[[2]dwarf.Offset]bool))
+	if 0 != len(mismatches) {
+		t.Fatalf("compareLayouts() of the same type got = %v", mismatches)
+	}
+}
+
+func TestConvertArg(t *testing.T) {
+	for _, tt := range []struct {
+		arg  any
+		typ  reflect.Type
+		want any
+	}{
+		{arg: 3, typ: reflect.TypeOf(uint(0)), want: uint(3)},
+		{arg: int8(-3), typ: reflect.TypeOf(int64(0)), want: int64(-3)},
+		{arg: uint64(200), typ: reflect.TypeOf(uint8(0)), want: uint8(200)},
+		{arg: 9.0, typ: reflect.TypeOf(int32(0)), want: int32(9)},
+		{arg: -1, typ: reflect.TypeOf(uint(0))},
+		{arg: -1, typ: reflect.TypeOf(uint8(0))},
+		{arg: int64(-1), typ: reflect.TypeOf(uintptr(0))},
+		{arg: 256, typ: reflect.TypeOf(uint8(0))},
+		{arg: 128, typ: reflect.TypeOf(int8(0))},
+		{arg: uint64(math.MaxUint64), typ: reflect.TypeOf(int64(0))},
+		{arg: -1.0, typ: reflect.TypeOf(uint(0))},
+		{arg: 1e20, typ: reflect.TypeOf(int64(0))},
+		{arg: 9.5, typ: reflect.TypeOf(int(0))},
+		{arg: 1e300, typ: reflect.TypeOf(float32(0))},
+	} {
+		got, err := convertArg(tt.arg, tt.typ)
+		if nil == tt.want {
+			if nil == err {
+				t.Errorf("convertArg(%T(%v), %s) got = %v, want an error", tt.arg, tt.arg, tt.typ, got)
+			}
+			continue
+		}
+		if nil != err || got.Interface() != tt.want {
+			t.Errorf("convertArg(%T(%v), %s) got = %v, %v, want %v", tt.arg, tt.arg, tt.typ, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseGoBuildIDNote(t *testing.T) {
+	note := func(namesz, descsz uint32, payload string) []byte {
+		data := binary.LittleEndian.AppendUint32(nil, namesz)
+		data = binary.LittleEndian.AppendUint32(data, descsz)
+		data = binary.LittleEndian.AppendUint32(data, goBuildIDTag)
+		return append(data, payload...)
+	}
+	if id, err := parseGoBuildIDNote(note(4, 5, "Go\x00\x00abcde"), binary.LittleEndian); err != nil || id != "abcde" {
+		t.Fatalf("parseGoBuildIDNote() got = %q, %v, want abcde", id, err)
+	}
+	// sizes whose sum wraps around in uint32 must not pass the bounds check.
+	for _, data := range [][]byte{
+		note(math.MaxUint32-3, 8, "Go\x00\x00abcd"),
+		note(4, math.MaxUint32-15, "Go\x00\x00abcd"),
+		note(4, 9, "Go\x00\x00abcd"),
+	} {
+		if id, err := parseGoBuildIDNote(data, binary.LittleEndian); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("parseGoBuildIDNote(%x) got = %q, %v, want ErrNotFound", data[:8], id, err)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package assembly
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// PatchInfo describes an applied patch.
+type PatchInfo struct {
+	Target      string
+	TargetPC    uint64
+	Replacement string // function the target jumps to, empty if it has no debug information
+	Original    []byte // bytes at the entry of the target the jump replaced
+	Applied     time.Time
+}
+
+// PatchManager keeps the bookkeeping of the patches applied through it, so they can be listed
+// and rolled back one by one or all together.
+type PatchManager struct {
+	asm     FuncResolver
+	mu      sync.Mutex
+	applied []*Patch
+}
+
+// NewPatchManager returns a manager applying patches through asm.
+func NewPatchManager(asm FuncResolver) *PatchManager {
+	return &PatchManager{asm: asm}
+}
+
+// Apply patches target with replacement, see DwarfAssembly.PatchFunc.
+func (m *PatchManager) Apply(target string, replacement reflect.Value) (*Patch, error) {
+	p, err := m.asm.PatchFunc(target, replacement)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	m.applied = append(m.applied, p)
+	m.mu.Unlock()
+	return p, nil
+}
+
+// ListPatches describes the patches in place, in the order they were applied.
+// Patches reverted directly through Patch.Revert are dropped.
+func (m *PatchManager) ListPatches() []PatchInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var infos = make([]PatchInfo, 0, len(m.applied))
+	var kept = m.applied[:0]
+	for _, p := range m.applied {
+		if p.Reverted() {
+			continue
+		}
+		kept = append(kept, p)
+		infos = append(infos, p.Info())
+	}
+	m.applied = kept
+	return infos
+}
+
+// Rollback reverts the patch of the function name.
+func (m *PatchManager) Rollback(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pc, _ := m.asm.FindFuncPc(name)
+	for i, p := range m.applied {
+		if p.Site.Target != name && p.Site.TargetPC != pc {
+			continue
+		}
+		if err := p.Revert(); err != nil {
+			return err
+		}
+		m.applied = append(m.applied[:i], m.applied[i+1:]...)
+		return nil
+	}
+	return fmt.Errorf("%s is not patched: %w", name, ErrNotFound)
+}
+
+// RollbackAll reverts every patch, the most recent first. Patches failing to revert are kept
+// and their errors joined.
+func (m *PatchManager) RollbackAll() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var errs []error
+	var kept []*Patch
+	for i := len(m.applied) - 1; i >= 0; i-- {
+		if err := m.applied[i].Revert(); err != nil {
+			errs = append(errs, err)
+			kept = append([]*Patch{m.applied[i]}, kept...)
+		}
+	}
+	m.applied = kept
+	return errors.Join(errs...)
+}
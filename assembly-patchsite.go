@@ -0,0 +1,143 @@
+package assembly
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// patchJumpSize is the size of the absolute jump monkey patching libraries write at the entry
+// of a target, gomonkey style: a move of the address into a register and an indirect branch.
+var patchJumpSize = map[string]uint64{
+	"amd64": 12,
+	"arm64": 24,
+}
+
+// PatchSite is what a monkey patching library needs to redirect a function resolved from DWARF:
+// the entry and size of the target and the entry of its replacement.
+type PatchSite struct {
+	Target        string
+	TargetPC      uint64
+	TargetSize    uint64
+	ReplacementPC uint64
+	Image         *proc.Image
+}
+
+// PatchSite resolves the function target, unexported or not, for a monkey patching library to
+// redirect it to replacement. replacement must be a function of the signature of target and not
+// capture variables, the jump written by the library carries no closure context, so neither
+// reflect.MakeFunc functions nor method values are accepted. The target is refused when the
+// mutation policy protects it or its code is shorter than the jump.
+func (da *dwarfAssembly) PatchSite(target string, replacement reflect.Value) (site *PatchSite, err error) {
+	return da.patchSite(target, replacement, false)
+}
+
+// patchSite resolves the site of PatchSite, withContext accepts a replacement reading its closure
+// context, for a jump loading the function value of the replacement.
+func (da *dwarfAssembly) patchSite(target string, replacement reflect.Value, withContext bool) (site *PatchSite, err error) {
+	defer func() { da.patchStats.validated(err) }()
+	if !replacement.IsValid() || replacement.Kind() != reflect.Func || replacement.IsNil() {
+		return nil, fmt.Errorf("%s: replacement %v is not a function", target, replacement)
+	}
+	site, f, err := da.patchTarget(target)
+	if err != nil {
+		return nil, err
+	}
+
+	ftyp, err := da.FindFuncType(target, replacement.Type().IsVariadic())
+	if err != nil {
+		return nil, err
+	}
+	if ftyp != replacement.Type() {
+		return nil, fmt.Errorf("%s: replacement of type %s, except %s", target, replacement.Type(), ftyp)
+	}
+
+	site.ReplacementPC = uint64(replacement.Pointer())
+	repl := da.binaryInfo.PCToFunc(site.ReplacementPC)
+	if repl == nil {
+		return nil, fmt.Errorf("%s: replacement at %#x: %w", target, site.ReplacementPC, ErrNotFound)
+	}
+	if repl == f {
+		return nil, fmt.Errorf("%s: replacement is the target itself", target)
+	}
+	if withContext {
+		return site, nil
+	}
+	if contextStub(repl.Name) {
+		return nil, fmt.Errorf("%w: %s: replacement through %s reads its closure context", ErrClosureNotSupported, target, repl.Name)
+	}
+	if err = da.checkClosure(repl); err != nil {
+		return nil, fmt.Errorf("%s: replacement: %w", target, err)
+	}
+	return site, nil
+}
+
+// contextStub reports whether the code named name is a stub every function value of a kind
+// shares, finding the actual function in its closure context: that of reflect.MakeFunc, of
+// method values of reflect and of compiler generated method values.
+func contextStub(name string) bool {
+	return name == "reflect.makeFuncStub" || name == "reflect.methodValueCall" || strings.HasSuffix(name, "-fm")
+}
+
+// PatchSiteNamed is PatchSite with the replacement resolved by name, e.g. from a patch plugin.
+func (da *dwarfAssembly) PatchSiteNamed(target string, replacement string) (site *PatchSite, err error) {
+	defer func() { da.patchStats.validated(err) }()
+	site, f, err := da.patchTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	repl, err := da.findFunc(replacement)
+	if err != nil {
+		return nil, err
+	}
+	if repl == f {
+		return nil, fmt.Errorf("%s: replacement is the target itself", target)
+	}
+	if err = da.checkClosure(repl); err != nil {
+		return nil, fmt.Errorf("%s: replacement: %w", target, err)
+	}
+
+	ftyp, err := da.FindFuncType(target, false)
+	if err != nil {
+		return nil, err
+	}
+	replTyp, err := da.FindFuncType(replacement, false)
+	if err != nil {
+		return nil, err
+	}
+	if ftyp != replTyp {
+		return nil, fmt.Errorf("%s: replacement %s of type %s, except %s", target, replacement, replTyp, ftyp)
+	}
+	site.ReplacementPC = repl.Entry
+	return site, nil
+}
+
+// patchTarget resolves and validates the target of a patch site.
+func (da *dwarfAssembly) patchTarget(target string) (*PatchSite, *proc.Function, error) {
+	f, err := da.findFunc(target)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = da.checkMutation(f.Name); err != nil {
+		return nil, nil, err
+	}
+	if f.Entry == 0 || f.End <= f.Entry {
+		return nil, nil, fmt.Errorf("%w: %s has no code, it is inlined at every call", ErrNotFound, f.Name)
+	}
+	size, arch := f.End-f.Entry, da.binaryInfo.Arch.Name
+	jump, ok := patchJumpSize[arch]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: patch sites on %s", ErrNotSupport, arch)
+	}
+	if size < jump {
+		return nil, nil, fmt.Errorf("%w: %s is %d bytes, shorter than the %d byte jump", ErrNotSupport, f.Name, size, jump)
+	}
+	return &PatchSite{
+		Target:     da.displayName(f.Name),
+		TargetPC:   f.Entry,
+		TargetSize: size,
+		Image:      funcToImage(da.binaryInfo, f),
+	}, f, nil
+}
@@ -0,0 +1,100 @@
+package assembly
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+// Pinned keeps a Go object at its address while native or patched code holds the address,
+// backed by a runtime.Pinner. Objects outside the Go heap, such as global variables, never
+// move and pinning them has no effect. The assembly tracks pins and releases them on Close.
+type Pinned struct {
+	da     *dwarfAssembly
+	addr   uintptr
+	pinner runtime.Pinner
+}
+
+// pinSet is the set of outstanding pins of an assembly.
+type pinSet struct {
+	mu   sync.Mutex
+	pins map[*Pinned]struct{}
+}
+
+// Addr returns the address of the pinned object, zero once unpinned.
+func (p *Pinned) Addr() uintptr {
+	return p.addr
+}
+
+// Unpin releases the object, it is safe to call Unpin more than once.
+func (p *Pinned) Unpin() {
+	da := p.da
+	if da == nil {
+		return
+	}
+	da.pins.mu.Lock()
+	defer da.pins.mu.Unlock()
+	if _, ok := da.pins.pins[p]; ok {
+		delete(da.pins.pins, p)
+		p.release()
+	}
+}
+
+func (p *Pinned) release() {
+	p.pinner.Unpin()
+	p.da = nil
+	p.addr = 0
+}
+
+// Pin pins the object value points to: a pointer, an unsafe.Pointer, the backing array of a
+// slice or the bytes of a string. Addressable values are pinned through their address.
+func (da *dwarfAssembly) Pin(value reflect.Value) (*Pinned, error) {
+	var ptr unsafe.Pointer
+	switch {
+	case !value.IsValid():
+		return nil, fmt.Errorf("pin: invalid value: %w", ErrNotSupport)
+	case value.Kind() == reflect.Pointer || value.Kind() == reflect.UnsafePointer:
+		ptr = value.UnsafePointer()
+	case value.Kind() == reflect.Slice:
+		ptr = value.UnsafePointer()
+	case value.Kind() == reflect.String:
+		ptr = unsafe.Pointer(unsafe.StringData(value.String()))
+	case value.CanAddr():
+		ptr = value.Addr().UnsafePointer()
+	default:
+		return nil, fmt.Errorf("pin: unaddressable %s: %w", value.Type(), ErrNotSupport)
+	}
+	if ptr == nil {
+		return nil, fmt.Errorf("pin: nil %s", value.Type())
+	}
+
+	p := &Pinned{da: da, addr: uintptr(ptr)}
+	p.pinner.Pin(ptr)
+
+	da.pins.mu.Lock()
+	if da.pins.pins == nil {
+		da.pins.pins = make(map[*Pinned]struct{})
+	}
+	da.pins.pins[p] = struct{}{}
+	da.pins.mu.Unlock()
+	return p, nil
+}
+
+// PinCount returns the number of outstanding pins.
+func (da *dwarfAssembly) PinCount() int {
+	da.pins.mu.Lock()
+	defer da.pins.mu.Unlock()
+	return len(da.pins.pins)
+}
+
+// unpinAll releases every outstanding pin.
+func (da *dwarfAssembly) unpinAll() {
+	da.pins.mu.Lock()
+	defer da.pins.mu.Unlock()
+	for p := range da.pins.pins {
+		p.release()
+	}
+	da.pins.pins = nil
+}
@@ -2,42 +2,117 @@ package assembly
 
 import (
 	"bytes"
+	"context"
+	"debug/buildinfo"
+	"debug/dwarf"
+	"debug/elf"
+	"debug/pe"
 	"encoding/binary"
 	"fmt"
 	"io"
+	"runtime"
 	"strings"
 
 	"github.com/go-delve/delve/pkg/proc"
 )
 
+// PluginInfo describes a library mapped into the process. The Go fields are empty for libraries
+// not built by the Go toolchain.
+type PluginInfo struct {
+	Path      string
+	Addr      uint64 // base address the library is mapped at
+	BuildID   string // Go build ID
+	GoVersion string
+	Packages  int // number of Go packages compiled into the library, zero without DWARF
+	// Module is the module data the runtime registered for the library, nil unless it was
+	// opened as a plugin.
+	Module *RuntimeModule
+	// PkgHashes are the link time package hashes plugin.Open compares with the host, by
+	// package path. ELF libraries only.
+	PkgHashes map[string]string
+}
+
 func (da *dwarfAssembly) SearchPluginByName(name string) (string, uint64, error) {
-	libs, addr, err := da.SearchPlugins()
+	libs, err := da.libraries()
 	if err != nil {
 		return "", 0, err
 	}
-	for i := 0; i < len(libs); i++ {
-		if strings.LastIndex(libs[i], name) >= 0 {
-			return libs[i], addr[i], nil
+	for _, lm := range libs {
+		if strings.LastIndex(lm.name, name) >= 0 {
+			return lm.name, lm.addr, nil
 		}
 	}
 	return "", 0, ErrNotFound
 }
 
-func (da *dwarfAssembly) SearchPlugins() ([]string, []uint64, error) {
+// SearchPlugins describes the libraries mapped into the process, in load order.
+func (da *dwarfAssembly) SearchPlugins() ([]PluginInfo, error) {
+	return da.SearchPluginsContext(context.Background())
+}
+
+// SearchPluginsContext is SearchPlugins stopping with the error of ctx once it is done,
+// reading the build information of the libraries is the costly part of the scan.
+func (da *dwarfAssembly) SearchPluginsContext(ctx context.Context) ([]PluginInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	libs, err := da.libraries()
+	if err != nil {
+		return nil, err
+	}
+
+	modules, _ := da.RuntimeModules()
+	var plugins = make([]PluginInfo, 0, len(libs))
+	for _, lm := range libs {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		plugin := PluginInfo{Path: lm.name, Addr: lm.addr}
+		if info, err := buildinfo.ReadFile(lm.name); err == nil {
+			plugin.GoVersion = info.GoVersion
+			plugin.BuildID, _ = readGoBuildID(lm.name)
+			plugin.Packages = countGoPackages(lm.name)
+			plugin.Module = libraryModule(lm, modules)
+			plugin.PkgHashes, _ = readPkgHashes(lm.name)
+		}
+		plugins = append(plugins, plugin)
+	}
+	return plugins, nil
+}
+
+// libraries lists the libraries mapped into the process besides the executable: the link map
+// of the dynamic loader, or the modules EnumProcessModules reports on Windows.
+func (da *dwarfAssembly) libraries() ([]*linkMap, error) {
+	if runtime.GOOS != "windows" {
+		return da.linkMaps()
+	}
+	modules, err := processModules()
+	if err != nil {
+		return nil, err
+	}
+	var libs []*linkMap
+	for _, m := range modules[min(1, len(modules)):] {
+		libs = append(libs, &linkMap{name: m.Path, addr: m.Base})
+	}
+	return libs, nil
+}
+
+// linkMaps walks the link map of the dynamic loader, skipping the unnamed entry of the executable.
+func (da *dwarfAssembly) linkMaps() ([]*linkMap, error) {
 
 	bi := da.binaryInfo
 
 	if bi.ElfDynamicSection.Addr == 0 {
 		// no dynamic section, therefore nothing to do here
-		return nil, nil, nil
+		return nil, nil
 	}
 	debugAddr, err := dynamicSearchDebug(bi)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	if debugAddr == 0 {
 		// no DT_DEBUG entry
-		return nil, nil, nil
+		return nil, nil
 	}
 
 	// Offsets of the fields of the r_debug and link_map structs,
@@ -46,30 +121,92 @@ func (da *dwarfAssembly) SearchPlugins() ([]string, []uint64, error) {
 
 	r_map, err := readPtr(bi, debugAddr+debugMapOffset)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	var libs []string
-	var addr []uint64
+	var libs []*linkMap
 
-	for {
-		if r_map == 0 {
-			break
-		}
-		if len(libs) > maxNumLibraries {
-			return nil, nil, ErrTooManyLibraries
+	for n := 0; r_map != 0; n++ {
+		if n > maxNumLibraries {
+			return nil, ErrTooManyLibraries
 		}
 		lm, err := readLinkMapNode(bi, r_map)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 
-		libs = append(libs, lm.name)
-		addr = append(addr, lm.addr)
+		if lm.name != "" {
+			libs = append(libs, lm)
+		}
 		r_map = lm.next
 	}
 
-	return libs, addr, nil
+	return libs, nil
+}
+
+// libraryModule returns the module of modules whose text lies in the text section of the
+// library mapped by lm, nil if none does.
+func libraryModule(lm *linkMap, modules []RuntimeModule) *RuntimeModule {
+	addr, size, ok := textSection(lm.name)
+	if !ok {
+		return nil
+	}
+	start := lm.addr + addr
+	for i := range modules {
+		if m := &modules[i]; m.Text >= start && m.Text < start+size {
+			return m
+		}
+	}
+	return nil
+}
+
+// textSection returns the address of the text section of the ELF or PE file at path relative to
+// the address the file is mapped at, and its size.
+func textSection(path string) (addr, size uint64, ok bool) {
+	if f, err := elf.Open(path); err == nil {
+		defer f.Close()
+		if text := f.Section(".text"); text != nil {
+			return text.Addr, text.Size, true
+		}
+		return 0, 0, false
+	}
+	if f, err := pe.Open(path); err == nil {
+		defer f.Close()
+		if text := f.Section(".text"); text != nil {
+			return uint64(text.VirtualAddress), uint64(text.VirtualSize), true
+		}
+	}
+	return 0, 0, false
+}
+
+// countGoPackages counts the Go compile units of the DWARF of the ELF or PE file at path, one per package.
+func countGoPackages(path string) int {
+	var data *dwarf.Data
+	if f, err := elf.Open(path); err == nil {
+		defer f.Close()
+		data, _ = f.DWARF()
+	} else if f, err := pe.Open(path); err == nil {
+		defer f.Close()
+		data, _ = f.DWARF()
+	}
+	if data == nil {
+		return 0
+	}
+
+	var packages int
+	reader := data.Reader()
+	for {
+		entry, err := reader.Next()
+		if err != nil || entry == nil {
+			return packages
+		}
+		if entry.Tag == dwarf.TagCompileUnit {
+			if lang, _ := entry.Val(dwarf.AttrLanguage).(int64); lang == dwarfLangGo {
+				packages++
+			}
+		}
+		reader.SkipChildren()
+	}
 }
 
 const (
@@ -77,6 +214,8 @@ const (
 	maxLibraryPathLength = 1000000 // maximum length for the path of a library, to avoid loading forever on corrupted memory
 )
 
+const dwarfLangGo = 0x16 // DW_LANG_Go as defined by the DWARF specification
+
 const (
 	_DT_NULL  = 0  // DT_NULL as defined by SysV ABI specification
 	_DT_DEBUG = 21 // DT_DEBUG as defined by SysV ABI specification
@@ -0,0 +1,142 @@
+package assembly
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// PluginSymbol is a function or variable a plugin exports to plugin.Lookup.
+type PluginSymbol struct {
+	Name string     // qualified name, the plugin path followed by the exported name
+	Kind SymbolKind // SymbolFunc or SymbolGlobal
+	Type reflect.Type
+	Addr uint64 // function entry or variable address from DWARF, zero if DWARF lacks the symbol
+}
+
+// ListPluginSymbols enumerates the symbols exported by the plugin loaded from a path containing
+// nameOrPath, or built with that plugin path. The names and types come from the plugin table the
+// runtime hands to plugin.Open, the addresses from the DWARF of the plugin.
+func (da *dwarfAssembly) ListPluginSymbols(nameOrPath string) ([]PluginSymbol, error) {
+	md, module, err := da.pluginModule(nameOrPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var img *proc.Image
+	if info, err := da.ResolveAddress(module.Text); err == nil {
+		for _, image := range da.binaryInfo.Images {
+			if image.Path == info.Image.Path {
+				img = image
+			}
+		}
+	}
+
+	ptab, err := md.field("ptab")
+	if err != nil {
+		return nil, err
+	}
+	n, err := ptab.intField("len")
+	if err != nil {
+		return nil, err
+	}
+	array, err := ptab.field("array")
+	if err != nil {
+		return nil, err
+	}
+	if array, err = array.deref(); err != nil {
+		return nil, err
+	}
+
+	var symbols = make([]PluginSymbol, 0, n)
+	for i := int64(0); i < n; i++ {
+		entry := runtimeVar{name: fmt.Sprintf("%s.ptab[%d]", module.PluginPath, i), addr: array.addr + uint64(i)*uint64(array.typ.Size()), typ: array.typ}
+		nameOff, err := entry.intField("name")
+		if err != nil {
+			return nil, err
+		}
+		typeOff, err := entry.intField("typ")
+		if err != nil {
+			return nil, err
+		}
+
+		symbol := PluginSymbol{
+			Name: module.PluginPath + "." + resolveNameOff(module.Types, nameOff),
+			Kind: SymbolFunc,
+			Type: runtimeType(module.Types + uint64(typeOff)),
+		}
+		if symbol.Type.Kind() == reflect.Func {
+			symbol.Addr = da.imageFuncEntry(img, symbol.Name)
+		} else {
+			// variables are exported as pointers to them.
+			symbol.Kind, symbol.Type = SymbolGlobal, symbol.Type.Elem()
+			symbol.Addr = da.imageGlobalAddr(img, symbol.Name)
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols, nil
+}
+
+// pluginModule finds the runtime module data of a plugin by plugin path or by the path it was loaded from.
+func (da *dwarfAssembly) pluginModule(nameOrPath string) (runtimeVar, RuntimeModule, error) {
+	var found runtimeVar
+	var module RuntimeModule
+	err := da.walkModules(func(md runtimeVar, m RuntimeModule) bool {
+		if m.PluginPath == "" {
+			return true
+		}
+		path := m.Name
+		if info, err := da.ResolveAddress(m.Text); err == nil {
+			path = info.Image.Path
+		}
+		if m.PluginPath == nameOrPath || strings.Contains(path, nameOrPath) {
+			found, module = md, m
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return runtimeVar{}, RuntimeModule{}, err
+	}
+	if found.addr == 0 {
+		return runtimeVar{}, RuntimeModule{}, fmt.Errorf("plugin %s: %w", nameOrPath, ErrNotFound)
+	}
+	return found, module, nil
+}
+
+// resolveNameOff decodes the runtime name at off from the start of the types of a module,
+// a flag byte followed by the varint encoded length and the bytes of the name.
+func resolveNameOff(types uint64, off int64) string {
+	p := uintptr(types + uint64(off) + 1)
+	length, n := binary.Uvarint(entryAddress(p, binary.MaxVarintLen64))
+	if n <= 0 {
+		return ""
+	}
+	return string(entryAddress(p+uintptr(n), int(length)))
+}
+
+// imageFuncEntry returns the entry of the function name defined by img, or zero.
+func (da *dwarfAssembly) imageFuncEntry(img *proc.Image, name string) uint64 {
+	for _, f := range da.binaryInfo.LookupFunc()[name] {
+		if f.Entry != 0 && (img == nil || funcToImage(da.binaryInfo, f) == img) {
+			return f.Entry
+		}
+	}
+	return 0
+}
+
+// imageGlobalAddr returns the address of the variable name defined by img, or zero.
+func (da *dwarfAssembly) imageGlobalAddr(img *proc.Image, name string) uint64 {
+	var addr uint64
+	da.walkPackageVars(func(v packageVar) bool {
+		if v.name == name && (img == nil || v.image == img) {
+			addr = v.addr
+			return false
+		}
+		return true
+	})
+	return addr
+}
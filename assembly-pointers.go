@@ -0,0 +1,106 @@
+package assembly
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// CallPolicy governs the raw address arguments CallFunc passes to the target function.
+type CallPolicy struct {
+	// AllowUnsafePointer permits unsafe.Pointer arguments, each non-nil pointer must
+	// point into a loaded image or a mapping of the process.
+	AllowUnsafePointer bool
+	// AllowUintptr permits uintptr arguments, which are passed unchecked since the
+	// target may treat them as plain integers.
+	AllowUintptr bool
+}
+
+func (da *dwarfAssembly) SetCallPolicy(policy CallPolicy) {
+	da.callPolicy = policy
+}
+
+// checkAddressArg applies the call policy to an argument of type typ.
+func (da *dwarfAssembly) checkAddressArg(typ reflect.Type, arg reflect.Value) error {
+	switch typ.Kind() {
+	case reflect.UnsafePointer:
+		if !da.callPolicy.AllowUnsafePointer {
+			return fmt.Errorf("%s argument: %w", typ, ErrUnsafeArgument)
+		}
+		if addr := uint64(arg.Pointer()); addr != 0 {
+			return da.checkMapped(addr)
+		}
+	case reflect.Uintptr:
+		if !da.callPolicy.AllowUintptr {
+			return fmt.Errorf("%s argument: %w", typ, ErrUnsafeArgument)
+		}
+	}
+	return nil
+}
+
+// checkMapped verifies addr lies in a section of a loaded image or, where the
+// platform exposes it, in a memory mapping of the process.
+func (da *dwarfAssembly) checkMapped(addr uint64) error {
+	if _, err := da.ResolveAddress(addr); err == nil {
+		return nil
+	}
+
+	mappings, err := processMappings()
+	if err != nil {
+		return fmt.Errorf("%#x: %w", addr, err)
+	}
+	for _, m := range mappings {
+		if m.Contains(addr) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%#x: %w", addr, ErrUnmappedAddress)
+}
+
+// processMappings reads the memory mappings of the process from /proc/self/maps.
+func processMappings() ([]Section, error) {
+	if runtime.GOOS != "linux" {
+		return nil, ErrNotSupport
+	}
+
+	f, err := os.Open("/proc/self/maps")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mappings []Section
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// 00400000-0048a000 r-xp 00000000 fd:03 960637 /bin/prog
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		start, end, ok := strings.Cut(fields[0], "-")
+		if !ok {
+			continue
+		}
+		var m Section
+		if m.Start, err = strconv.ParseUint(start, 16, 64); err != nil {
+			continue
+		}
+		if m.End, err = strconv.ParseUint(end, 16, 64); err != nil {
+			continue
+		}
+		for i, perm := range []SectionPerm{PermRead, PermWrite, PermExec} {
+			if i < len(fields[1]) && fields[1][i] != '-' {
+				m.Perm |= perm
+			}
+		}
+		if len(fields) >= 6 {
+			m.Name = fields[5]
+		}
+		mappings = append(mappings, m)
+	}
+	return mappings, scanner.Err()
+}
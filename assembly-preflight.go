@@ -0,0 +1,134 @@
+package assembly
+
+import (
+	"debug/buildinfo"
+	"debug/elf"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// pluginBuildSettings are the build settings that must match between a host and its plugins.
+var pluginBuildSettings = []string{"-trimpath", "-race", "-msan", "-asan", "-tags", "CGO_ENABLED", "GOOS", "GOARCH", "GOEXPERIMENT", "GOAMD64", "GOARM", "GOARM64", "GO386"}
+
+const pkgHashPrefix = "go:link.pkghashbytes."
+
+// PluginMismatch is a difference between the host and a plugin that makes plugin.Open fail.
+type PluginMismatch struct {
+	Kind   string // "go version", "build setting", "package" or "plugin support"
+	Name   string // the build setting or package path
+	Host   string
+	Plugin string
+}
+
+func (m PluginMismatch) String() string {
+	if m.Name == "" {
+		return fmt.Sprintf("%s: host %s, plugin %s", m.Kind, m.Host, m.Plugin)
+	}
+	return fmt.Sprintf("%s %s: host %s, plugin %s", m.Kind, m.Name, m.Host, m.Plugin)
+}
+
+// PluginCompatibilityError reports why the runtime would refuse to open a plugin.
+type PluginCompatibilityError struct {
+	Path       string
+	Mismatches []PluginMismatch
+}
+
+func (e *PluginCompatibilityError) Error() string {
+	mismatches := make([]string, len(e.Mismatches))
+	for i, m := range e.Mismatches {
+		mismatches[i] = m.String()
+	}
+	return fmt.Sprintf("incompatible plugin: %s: [%s]", e.Path, strings.Join(mismatches, ", "))
+}
+
+// PreflightPlugin compares the metadata of the plugin at path with the host before it is opened,
+// diagnosing the Go version, build setting and package hash differences the runtime only reports
+// as "plugin was built with a different version of package". Package hashes are compared for
+// ELF binaries only.
+func (da *dwarfAssembly) PreflightPlugin(path string) error {
+	host, err := da.BuildInfo(da.binaryInfo.Images[0])
+	if err != nil {
+		return err
+	}
+	lib, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read build info failed: %s: %w", path, err)
+	}
+
+	var mismatches []PluginMismatch
+	if host.GoVersion != lib.GoVersion {
+		mismatches = append(mismatches, PluginMismatch{Kind: "go version", Host: host.GoVersion, Plugin: lib.GoVersion})
+	}
+	hostSettings, libSettings := buildSettings(host), buildSettings(lib)
+	for _, key := range pluginBuildSettings {
+		if hostSettings[key] != libSettings[key] {
+			mismatches = append(mismatches, PluginMismatch{Kind: "build setting", Name: key, Host: hostSettings[key], Plugin: libSettings[key]})
+		}
+	}
+
+	hostHashes, err := readPkgHashes(da.binaryInfo.Images[0].Path)
+	if err != nil {
+		return err
+	}
+	libHashes, err := readPkgHashes(path)
+	if err != nil {
+		return err
+	}
+	if len(hostHashes) == 0 && len(libHashes) > 0 {
+		mismatches = append(mismatches, PluginMismatch{Kind: "plugin support", Host: "not linked with package plugin", Plugin: "plugin"})
+	}
+	var pkgs []string
+	for pkg, hash := range libHashes {
+		if hostHash, ok := hostHashes[pkg]; ok && hostHash != hash {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	sort.Strings(pkgs)
+	for _, pkg := range pkgs {
+		mismatches = append(mismatches, PluginMismatch{Kind: "package", Name: pkg, Host: hex.EncodeToString([]byte(hostHashes[pkg])), Plugin: hex.EncodeToString([]byte(libHashes[pkg]))})
+	}
+
+	if len(mismatches) > 0 {
+		return &PluginCompatibilityError{Path: path, Mismatches: mismatches}
+	}
+	return nil
+}
+
+// buildSettings indexes the build settings of info, an unset boolean setting is false.
+func buildSettings(info *buildinfo.BuildInfo) map[string]string {
+	settings := map[string]string{"-trimpath": "false", "-race": "false", "-msan": "false", "-asan": "false"}
+	for _, s := range info.Settings {
+		settings[s.Key] = s.Value
+	}
+	return settings
+}
+
+// readPkgHashes reads the link time hash of every package the ELF binary at path records for
+// plugin checks, nil for other formats.
+func readPkgHashes(path string) (map[string]string, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, nil
+	}
+	defer f.Close()
+
+	syms, err := f.Symbols()
+	if err != nil {
+		return nil, nil
+	}
+	var hashes = make(map[string]string)
+	for _, sym := range syms {
+		if !strings.HasPrefix(sym.Name, pkgHashPrefix) || int(sym.Section) >= len(f.Sections) {
+			continue
+		}
+		sec := f.Sections[sym.Section]
+		buf := make([]byte, sym.Size)
+		if _, err = sec.ReadAt(buf, int64(sym.Value-sec.Addr)); err != nil {
+			return nil, fmt.Errorf("read %s: %s: %w", sym.Name, path, err)
+		}
+		hashes[strings.TrimPrefix(sym.Name, pkgHashPrefix)] = string(buf)
+	}
+	return hashes, nil
+}
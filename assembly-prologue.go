@@ -0,0 +1,119 @@
+package assembly
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/proc"
+	"golang.org/x/arch/arm64/arm64asm"
+	"golang.org/x/arch/x86/x86asm"
+)
+
+// PrologueInstruction is an instruction at the entry of a function, Offset is relative to the entry.
+type PrologueInstruction struct {
+	Offset uint64
+	Bytes  []byte
+	Text   string
+}
+
+// Prologue describes the entry of a function up to its stack check branch. Size is the number
+// of bytes a jump written at the entry may overwrite: the instructions before the stack check
+// branch, and for functions without stack check those before the first control transfer or
+// branch target. Those instructions are free of branches, but may still address memory relative
+// to the pc and need fixing up when relocated.
+type Prologue struct {
+	Name         string
+	Entry        uint64
+	FuncSize     uint64
+	StackCheck   bool
+	Size         int
+	Instructions []PrologueInstruction
+}
+
+// AnalyzePrologue disassembles the entry of the function name, see Prologue.
+// Only amd64 and arm64 are supported.
+func (da *dwarfAssembly) AnalyzePrologue(name string) (*Prologue, error) {
+	f, err := da.findFunc(name)
+	if err != nil {
+		return nil, err
+	}
+	arch := da.binaryInfo.Arch.Name
+	if arch != "amd64" && arch != "arm64" {
+		return nil, fmt.Errorf("%w: prologue analysis on %s", ErrNotSupport, arch)
+	}
+	if f.Entry == 0 || f.End <= f.Entry {
+		return nil, fmt.Errorf("%w: %s has no code", ErrNotFound, f.Name)
+	}
+	instructions, err := proc.Disassemble(new(localMemory), nil, &proc.BreakpointMap{}, da.binaryInfo, f.Entry, f.End)
+	if err != nil {
+		return nil, fmt.Errorf("disassemble %s: %w", f.Name, err)
+	}
+
+	prologue := &Prologue{Name: da.displayName(f.Name), Entry: f.Entry, FuncSize: f.End - f.Entry}
+	var targets = make(map[uint64]bool)
+	for i := range instructions {
+		inst := &instructions[i]
+		if inst.IsCall() && inst.DestLoc != nil && inst.DestLoc.Fn != nil && strings.HasPrefix(inst.DestLoc.Fn.Name, "runtime.morestack") {
+			prologue.StackCheck = true
+		}
+		if target, _, ok := branchTarget(arch, inst); ok {
+			targets[target] = true
+		}
+	}
+
+	for i := range instructions {
+		inst := &instructions[i]
+		_, conditional, _ := branchTarget(arch, inst)
+		if prologue.StackCheck && conditional {
+			break
+		}
+		if !prologue.StackCheck && (inst.IsCall() || inst.IsRet() || inst.IsJmp() || conditional || (i > 0 && targets[inst.Loc.PC])) {
+			break
+		}
+		prologue.Instructions = append(prologue.Instructions, PrologueInstruction{
+			Offset: inst.Loc.PC - f.Entry,
+			Bytes:  inst.Bytes,
+			Text:   inst.Text(proc.GoFlavour, da.binaryInfo),
+		})
+		prologue.Size += inst.Size
+	}
+	return prologue, nil
+}
+
+// branchTarget decodes the destination of a pc relative branch or call, conditional reports whether
+// the branch depends on a condition.
+func branchTarget(arch string, inst *proc.AsmInstruction) (target uint64, conditional bool, ok bool) {
+	switch arch {
+	case "amd64":
+		decoded, err := x86asm.Decode(inst.Bytes, 64)
+		if err != nil {
+			return 0, false, false
+		}
+		rel, ok := decoded.Args[0].(x86asm.Rel)
+		if !ok {
+			return 0, false, false
+		}
+		return uint64(int64(inst.Loc.PC) + int64(decoded.Len) + int64(rel)), decoded.Op != x86asm.JMP && decoded.Op != x86asm.CALL, true
+	case "arm64":
+		decoded, err := arm64asm.Decode(inst.Bytes)
+		if err != nil {
+			return 0, false, false
+		}
+		switch decoded.Op {
+		case arm64asm.B, arm64asm.BL, arm64asm.CBZ, arm64asm.CBNZ, arm64asm.TBZ, arm64asm.TBNZ:
+		default:
+			return 0, false, false
+		}
+		for _, arg := range decoded.Args {
+			switch arg := arg.(type) {
+			case arm64asm.Cond:
+				conditional = true
+			case arm64asm.PCRel:
+				target, ok = uint64(int64(inst.Loc.PC)+int64(arg)), true
+			}
+		}
+		conditional = conditional || (decoded.Op != arm64asm.B && decoded.Op != arm64asm.BL)
+		return target, conditional, ok
+	}
+	return 0, false, false
+}
@@ -0,0 +1,58 @@
+package assembly
+
+import (
+	"fmt"
+	"strings"
+)
+
+// protectedPrefixes are the symbol name prefixes mutation paths refuse by default: overwriting
+// runtime, reflect or internal ABI state corrupts the scheduler or GC rather than fixing a bug.
+var protectedPrefixes = []string{
+	"runtime.",
+	"reflect.",
+	"internal/",
+	"sync/atomic.",
+	"go:",
+	"type:",
+}
+
+// MutationPolicy governs which symbols SetGlobal and other mutation paths may write.
+type MutationPolicy struct {
+	// AllowUnsafe disables the protection of runtime, reflect and internal ABI symbols.
+	AllowUnsafe bool
+	// Protected lists additional symbol name prefixes to refuse.
+	Protected []string
+	// CountCalls counts the calls of the targets patched by PatchFunc in PatchMetrics.Calls, the
+	// replacement is called through a reflect.MakeFunc wrapper incrementing the counter.
+	CountCalls bool
+}
+
+func (p MutationPolicy) protected(name string) bool {
+	if p.AllowUnsafe {
+		return false
+	}
+	for _, prefix := range protectedPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	for _, prefix := range p.Protected {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	// ABI wrappers are generated by the toolchain and never the target of a hotfix.
+	return strings.HasSuffix(name, ".abi0")
+}
+
+func (da *dwarfAssembly) SetMutationPolicy(policy MutationPolicy) {
+	da.mutPolicy = policy
+}
+
+// checkMutation reports ErrProtectedSymbol if the mutation policy refuses writing name.
+func (da *dwarfAssembly) checkMutation(name string) error {
+	if da.mutPolicy.protected(name) {
+		return fmt.Errorf("%s: %w", name, ErrProtectedSymbol)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package assembly
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// quiesceAttempts is how often a safe patch looks for a quiet moment before giving up.
+const quiesceAttempts = 10
+
+// codeWindow is a range of instructions of a function about to be rewritten, as
+// offsets from its entry.
+type codeWindow struct {
+	start, end uint64
+}
+
+// PatchFuncSafe is PatchFunc writing the jump while no goroutine can execute the target: the
+// process is limited to a single P, and the write only happens once no goroutine is stopped
+// inside the instructions being rewritten. Reverting the patch takes the same precautions.
+// If goroutines keep being stopped there the patch fails with ErrPatchBusy.
+func (da *dwarfAssembly) PatchFuncSafe(target string, replacement reflect.Value) (*Patch, error) {
+	return da.patchFunc(target, replacement, true)
+}
+
+// quiesce runs write while no other goroutine executes Go code and none is stopped inside the
+// windows of the function name. The world is stopped twice to switch GOMAXPROCS, in between
+// the calling goroutine keeps the only P as long as it does not block, is not preempted and
+// does not yield, the scheduler preempts it after 10ms so the check and write follow a yield.
+// write must therefore not block nor make system calls, Patch.write makes the pages it copies
+// to writable beforehand.
+func quiesce(name string, windows []codeWindow, write func() error) error {
+	prev := runtime.GOMAXPROCS(1)
+	defer runtime.GOMAXPROCS(prev)
+
+	var buf []byte
+	for attempt := 0; attempt < quiesceAttempts; attempt++ {
+		runtime.Gosched()
+		buf = allStacks(buf)
+		if !inWindows(buf, name, windows) {
+			return write()
+		}
+	}
+	return fmt.Errorf("%s: %w", name, ErrPatchBusy)
+}
+
+// allStacks returns the traceback of every goroutine, in buf if it is large enough.
+func allStacks(buf []byte) []byte {
+	if buf == nil {
+		buf = make([]byte, 64<<10)
+	}
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// inWindows reports whether a frame of the function name in the traceback stacks is stopped
+// inside one of windows. A frame stopped at the start of a window is safe, it resumes with
+// the first of the rewritten instructions.
+func inWindows(stacks []byte, name string, windows []codeWindow) bool {
+	for _, off := range frameOffsets(stacks, name) {
+		for _, w := range windows {
+			if off > w.start && off < w.end {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// frameOffsets parses the offsets from the entry of the pc of every frame of the function
+// name in a traceback, inlined frames carry no offset and are skipped.
+func frameOffsets(stacks []byte, name string) []uint64 {
+	var offsets []uint64
+	var current string
+	scanner := bufio.NewScanner(bytes.NewReader(stacks))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "\t") {
+			current = frameFunc(line)
+			continue
+		}
+		if current != name {
+			continue
+		}
+		i := strings.LastIndex(line, " +0x")
+		if i < 0 {
+			continue
+		}
+		if off, err := strconv.ParseUint(line[i+4:], 16, 64); err == nil {
+			offsets = append(offsets, off)
+		}
+	}
+	return offsets
+}
+
+// frameFunc returns the function of a frame line of a traceback, "pkg.(*T).m(0x1, ...)"
+// and "created by pkg.f in goroutine 1" are frames of pkg.(*T).m and pkg.f.
+func frameFunc(line string) string {
+	if strings.HasPrefix(line, "created by ") {
+		line = strings.TrimPrefix(line, "created by ")
+		if i := strings.Index(line, " in goroutine "); i >= 0 {
+			line = line[:i]
+		}
+		return line
+	}
+	if i := strings.LastIndex(line, "("); i > 0 {
+		return line[:i]
+	}
+	return ""
+}
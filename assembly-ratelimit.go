@@ -0,0 +1,92 @@
+package assembly
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimit bounds an operation to Rate per second on average, in bursts of up to Burst, and
+// to Quota operations in total since the policy was set. Zero fields do not limit.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+	Quota uint64
+}
+
+// RateLimitPolicy limits the operations changing the state of the process, so automation
+// driving the assembly, e.g. through a remote control surface, cannot hammer it. Operations over
+// a limit fail with ErrRateLimited before they run. Reverting patches is never limited.
+type RateLimitPolicy struct {
+	// Calls limits CallFunc, CallFuncResult, CallFuncNamed, CallFuncErr, CallMethod,
+	// CallInterfaceMethod, SymbolHandle.Call, Executor.Run, one token per call, and the calls of
+	// the func values returned by BindMethod. The func values of FindFunc are not limited.
+	Calls RateLimit
+	// Globals limits SetGlobal.
+	Globals RateLimit
+	// Patches limits PatchFunc and PatchFuncSafe.
+	Patches RateLimit
+}
+
+// tokenBucket enforces one RateLimit.
+type tokenBucket struct {
+	limit  RateLimit
+	tokens float64
+	used   uint64
+	last   time.Time
+}
+
+func newTokenBucket(limit RateLimit, now time.Time) tokenBucket {
+	if limit.Rate > 0 && limit.Burst <= 0 {
+		limit.Burst = 1
+	}
+	return tokenBucket{limit: limit, tokens: float64(limit.Burst), last: now}
+}
+
+// take consumes one operation at now, reporting the limit it exceeds if any.
+func (b *tokenBucket) take(now time.Time) error {
+	if b.limit.Quota > 0 && b.used >= b.limit.Quota {
+		return fmt.Errorf("quota of %d exhausted", b.limit.Quota)
+	}
+	if b.limit.Rate > 0 {
+		b.tokens += now.Sub(b.last).Seconds() * b.limit.Rate
+		if burst := float64(b.limit.Burst); b.tokens > burst {
+			b.tokens = burst
+		}
+		b.last = now
+		if b.tokens < 1 {
+			return fmt.Errorf("more than %g per second", b.limit.Rate)
+		}
+		b.tokens--
+	}
+	b.used++
+	return nil
+}
+
+// rateLimiter holds the buckets of the rate limit policy.
+type rateLimiter struct {
+	mu      sync.Mutex
+	calls   tokenBucket
+	globals tokenBucket
+	patches tokenBucket
+}
+
+// SetRateLimitPolicy replaces the rate limits, the bursts and quotas start over full.
+func (da *dwarfAssembly) SetRateLimitPolicy(policy RateLimitPolicy) {
+	now := time.Now()
+	da.limits.mu.Lock()
+	da.limits.calls = newTokenBucket(policy.Calls, now)
+	da.limits.globals = newTokenBucket(policy.Globals, now)
+	da.limits.patches = newTokenBucket(policy.Patches, now)
+	da.limits.mu.Unlock()
+}
+
+// limit takes one operation on name from bucket, reporting ErrRateLimited over the limit.
+func (da *dwarfAssembly) limit(bucket *tokenBucket, op, name string) error {
+	da.limits.mu.Lock()
+	defer da.limits.mu.Unlock()
+	if err := bucket.take(time.Now()); err != nil {
+		return fmt.Errorf("%s %s: %w: %v", op, name, ErrRateLimited, err)
+	}
+	return nil
+}
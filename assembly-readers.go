@@ -0,0 +1,76 @@
+package assembly
+
+import (
+	"sync"
+
+	"github.com/go-delve/delve/pkg/dwarf/reader"
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// readerPools recycles the DWARF readers of every image, cache construction seeks thousands
+// of entries and would otherwise allocate a reader for each of them.
+type readerPools struct {
+	mu    sync.Mutex
+	pools map[*proc.Image]*sync.Pool
+}
+
+// acquireReader returns a reader of the debug information of img, positioned nowhere in
+// particular: callers seek first. It is handed back with releaseReader.
+func (da *dwarfAssembly) acquireReader(img *proc.Image) *reader.Reader {
+	return da.readerPool(img).Get().(*reader.Reader)
+}
+
+func (da *dwarfAssembly) releaseReader(img *proc.Image, r *reader.Reader) {
+	da.readerPool(img).Put(r)
+}
+
+func (da *dwarfAssembly) readerPool(img *proc.Image) *sync.Pool {
+	da.readers.mu.Lock()
+	defer da.readers.mu.Unlock()
+	pool, ok := da.readers.pools[img]
+	if !ok {
+		if da.readers.pools == nil {
+			da.readers.pools = make(map[*proc.Image]*sync.Pool)
+		}
+		pool = &sync.Pool{New: func() any { return img.DwarfReader() }}
+		da.readers.pools[img] = pool
+	}
+	return pool
+}
+
+// resetReaders drops the pooled readers, of img or of every image if img is nil.
+func (da *dwarfAssembly) resetReaders(img *proc.Image) {
+	da.readers.mu.Lock()
+	defer da.readers.mu.Unlock()
+	if img == nil {
+		da.readers.pools = nil
+	} else {
+		delete(da.readers.pools, img)
+	}
+}
+
+// imageReaders leases one pooled reader per image for a walk reading the entries of several
+// images in turn, release hands them all back.
+type imageReaders struct {
+	da      *dwarfAssembly
+	readers map[*proc.Image]*reader.Reader
+}
+
+func (r *imageReaders) get(img *proc.Image) *reader.Reader {
+	rdr, ok := r.readers[img]
+	if !ok {
+		if r.readers == nil {
+			r.readers = make(map[*proc.Image]*reader.Reader)
+		}
+		rdr = r.da.acquireReader(img)
+		r.readers[img] = rdr
+	}
+	return rdr
+}
+
+func (r *imageReaders) release() {
+	for img, rdr := range r.readers {
+		r.da.releaseReader(img, rdr)
+	}
+	r.readers = nil
+}
@@ -0,0 +1,114 @@
+package assembly
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// ReloadMainImage replaces the debug information of the main executable with the one of
+// symbolFile, an unstripped build of the running executable, so a process started from a
+// stripped binary gains DWARF based lookups once its symbols become available. The Go build
+// IDs of both files must match. The libraries loaded before are loaded again, function values,
+// handles and patches issued before stay valid: the code they point to has not moved.
+func (da *dwarfAssembly) ReloadMainImage(symbolFile string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	want, err := readGoBuildID(exe)
+	if err != nil {
+		return fmt.Errorf("read build id failed: %s: %w", exe, err)
+	}
+	got, err := readGoBuildID(symbolFile)
+	if err != nil {
+		return fmt.Errorf("read build id failed: %s: %w", symbolFile, err)
+	}
+	if got != want {
+		return fmt.Errorf("%s: build id %q, executable has %q: %w", symbolFile, got, want, ErrBuildIDMismatch)
+	}
+
+	entryPoint, err := getEntrypoint(exe)
+	if err != nil {
+		return err
+	}
+	bi := proc.NewBinaryInfo(da.binaryInfo.GOOS, da.binaryInfo.Arch.Name)
+	if err = bi.LoadBinaryInfo(symbolFile, uint64(entryPoint), nil); err != nil {
+		bi.Close()
+		return err
+	}
+
+	// images maps the images of the previous debug information to their reloaded counterparts,
+	// images found unmapped or unloaded are not loaded again.
+	images := map[*proc.Image]*proc.Image{da.binaryInfo.Images[0]: bi.Images[0]}
+	for _, img := range da.binaryInfo.Images[1:] {
+		if da.imageStale(img) != nil || !imageLoaded(img) {
+			continue
+		}
+		if err = bi.AddImage(img.Path, imageAddr(img)); err != nil {
+			bi.Close()
+			return fmt.Errorf("reload %s: %w", img.Path, err)
+		}
+		images[img] = bi.Images[len(bi.Images)-1]
+	}
+
+	da.background.Wait()
+	da.indexMu.Lock()
+	old := da.binaryInfo
+	da.binaryInfo = bi
+	da.indexMu.Unlock()
+
+	da.rebindImages(images)
+	da.typesMu.Lock()
+	da.imageTypes = nil
+	da.typesMu.Unlock()
+	da.cacheMu.Lock()
+	da.sections = nil
+	da.abi0Funcs = nil
+	da.buildIDs = nil
+	da.buildInfos = nil
+	da.cacheMu.Unlock()
+	da.resetReaders(nil)
+	da.dropIndex(nil)
+	da.layout.mu.Lock()
+	da.layout.stale = nil
+	da.layout.mu.Unlock()
+	if err = da.refreshModules(); err != nil {
+		return err
+	}
+	return old.Close()
+}
+
+// rebindImages moves the function values and handles issued for the images of the previous
+// debug information to the reloaded images, those of images not reloaded are invalidated.
+func (da *dwarfAssembly) rebindImages(images map[*proc.Image]*proc.Image) {
+	da.cacheMu.Lock()
+	for key, f := range da.funcs {
+		if img, ok := images[f.image]; ok {
+			f.image = img
+		} else {
+			f.unloaded.Store(true)
+			funcValue(f.value).codePtr = f.stubPtr
+			delete(da.funcs, key)
+		}
+	}
+	da.cacheMu.Unlock()
+
+	handles := make(map[*proc.Image]map[*Handle]struct{}, len(da.handles))
+	for old, set := range da.handles {
+		img, ok := images[old]
+		for h := range set {
+			if !ok {
+				h.invalidate(ErrImageUnloaded)
+				continue
+			}
+			h.image = img
+			if handles[img] == nil {
+				handles[img] = make(map[*Handle]struct{})
+			}
+			handles[img][h] = struct{}{}
+		}
+	}
+	da.handles = handles
+}
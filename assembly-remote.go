@@ -0,0 +1,146 @@
+package assembly
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"unsafe"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// RemoteAssembly inspects another running Go process: symbols are resolved from the debug
+// information of its executable and values are read through a proc.MemoryReadWriter. Values
+// of the process cannot be handed out as reflect values, globals are copied out instead.
+type RemoteAssembly struct {
+	da  *dwarfAssembly
+	mem proc.MemoryReadWriter
+}
+
+// NewRemoteAssembly loads the debug information of the executable at path, mapped at entryPoint
+// in the process mem reads from. mem is closed by Close if it implements io.Closer.
+func NewRemoteAssembly(path string, entryPoint uint64, mem proc.MemoryReadWriter) (*RemoteAssembly, error) {
+	da := &dwarfAssembly{binaryInfo: proc.NewBinaryInfo(runtime.GOOS, runtime.GOARCH)}
+	if err := da.binaryInfo.LoadBinaryInfo(path, entryPoint, nil); err != nil {
+		return nil, err
+	}
+	return &RemoteAssembly{da: da, mem: mem}, nil
+}
+
+// AttachProcess inspects the process pid, see the attachProcess of the platform for the
+// permissions it requires.
+func AttachProcess(pid int) (*RemoteAssembly, error) {
+	path, entryPoint, mem, err := attachProcess(pid)
+	if err != nil {
+		return nil, fmt.Errorf("attach %d: %w", pid, err)
+	}
+	remote, err := NewRemoteAssembly(path, entryPoint, mem)
+	if err != nil {
+		if closer, ok := mem.(io.Closer); ok {
+			closer.Close()
+		}
+		return nil, err
+	}
+	return remote, nil
+}
+
+func (ra *RemoteAssembly) BinaryInfo() *proc.BinaryInfo {
+	return ra.da.binaryInfo
+}
+
+// GlobalAddr returns the address of the global variable name in the process.
+func (ra *RemoteAssembly) GlobalAddr(name string) (uint64, error) {
+	v, err := ra.global(name)
+	if err != nil {
+		return 0, err
+	}
+	return v.addr, nil
+}
+
+// ReadGlobal copies the global variable name into dst, a pointer to a value of the size of
+// the variable. Only values free of pointers are copied, addresses of the process are
+// meaningless in this one.
+func (ra *RemoteAssembly) ReadGlobal(name string, dst any) error {
+	ptr := reflect.ValueOf(dst)
+	if ptr.Kind() != reflect.Pointer || ptr.IsNil() {
+		return fmt.Errorf("%s: ReadGlobal requires a non nil pointer, got %T", name, dst)
+	}
+	if hasPointers(ptr.Elem().Type()) {
+		return fmt.Errorf("%s: %s holds pointers: %w", name, ptr.Elem().Type(), ErrUnsafeArgument)
+	}
+	v, err := ra.global(name)
+	if err != nil {
+		return err
+	}
+	if size := ptr.Elem().Type().Size(); int64(size) != v.typ.Size() {
+		return fmt.Errorf("%s: size mismatch: %s is %d bytes, %s is %d", name, v.typ, v.typ.Size(), ptr.Elem().Type(), size)
+	}
+
+	data, err := v.read(v.typ.Size())
+	if err != nil {
+		return err
+	}
+	if len(data) > 0 {
+		copy(unsafe.Slice((*byte)(ptr.UnsafePointer()), len(data)), data)
+	}
+	return nil
+}
+
+// ReadMemory copies size bytes at addr of the process.
+func (ra *RemoteAssembly) ReadMemory(addr uint64, size int) ([]byte, error) {
+	return runtimeVar{name: fmt.Sprintf("%#x", addr), addr: addr, mem: ra.mem}.read(int64(size))
+}
+
+// RuntimeModules reads the module data list of the process.
+func (ra *RemoteAssembly) RuntimeModules() ([]RuntimeModule, error) {
+	md, err := ra.global("runtime.firstmoduledata")
+	if err != nil {
+		return nil, err
+	}
+	var modules []RuntimeModule
+	err = walkModuleList(md, func(_ runtimeVar, m RuntimeModule) bool {
+		modules = append(modules, m)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return modules, nil
+}
+
+// Close releases the debug information and the memory of the process.
+func (ra *RemoteAssembly) Close() error {
+	if closer, ok := ra.mem.(io.Closer); ok {
+		closer.Close()
+	}
+	return ra.da.binaryInfo.Close()
+}
+
+func (ra *RemoteAssembly) global(name string) (runtimeVar, error) {
+	v, err := ra.da.runtimeGlobal(name)
+	if err != nil {
+		return runtimeVar{}, err
+	}
+	v.mem = ra.mem
+	return v, nil
+}
+
+// hasPointers reports whether values of typ hold pointers.
+func hasPointers(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Array:
+		return typ.Len() > 0 && hasPointers(typ.Elem())
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			if hasPointers(typ.Field(i).Type) {
+				return true
+			}
+		}
+		return false
+	case reflect.Pointer, reflect.UnsafePointer, reflect.Map, reflect.Chan, reflect.Func,
+		reflect.Interface, reflect.Slice, reflect.String:
+		return true
+	}
+	return false
+}
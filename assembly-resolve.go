@@ -0,0 +1,194 @@
+package assembly
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// ResolveOrder selects which loaded image wins when a symbol is defined in more than one image.
+type ResolveOrder int
+
+const (
+	// ResolveDefault keeps the historical behaviour: functions and globals resolve
+	// to the most recently loaded image, types to the first image that declared them.
+	ResolveDefault ResolveOrder = iota
+	// ResolveNewestFirst prefers the most recently loaded image.
+	ResolveNewestFirst
+	// ResolveMainFirst prefers the main executable, then images in load order.
+	ResolveMainFirst
+)
+
+// TypeFallback selects where FindType looks for the runtime type of a DWARF type whose image
+// does not record one, typically a type only instantiated by a library.
+type TypeFallback int
+
+const (
+	// TypeFallbackScan takes the first library registering a runtime type of that name, in load order.
+	TypeFallbackScan TypeFallback = iota
+	// TypeFallbackStrict fails the lookup instead of using a type of the same name from an unrelated image.
+	TypeFallbackStrict
+	// TypeFallbackPreferred chooses among every library registering the name by Order and Priority.
+	TypeFallbackPreferred
+	// TypeFallbackSynthesize scans the libraries like TypeFallbackScan, and if none registers the
+	// name builds the type from DWARF like SynthesizeType. ResolveType returns a synthetic type
+	// without image and with an error wrapping ErrSyntheticType.
+	TypeFallbackSynthesize
+)
+
+// ResolvePolicy governs FindFunc/FindType/FindGlobal resolution across loaded images.
+type ResolvePolicy struct {
+	Order ResolveOrder
+	// Priority lists image paths, matched by suffix, that take precedence over Order.
+	// Earlier entries win over later ones.
+	Priority []string
+	// TypeFallback governs runtime types missing from the image defining the DWARF type.
+	TypeFallback TypeFallback
+}
+
+func (da *dwarfAssembly) SetResolvePolicy(policy ResolvePolicy) {
+	da.policy = policy
+}
+
+// imageIndex returns the load order index of img in the binary info, or -1.
+func (da *dwarfAssembly) imageIndex(img *proc.Image) int {
+	for i, image := range da.binaryInfo.Images {
+		if image == img {
+			return i
+		}
+	}
+	return -1
+}
+
+// preferredImage chooses one of the candidate images according to the resolve policy,
+// legacy is the candidate selected by ResolveDefault.
+func (da *dwarfAssembly) preferredImage(candidates []*proc.Image, legacy int) int {
+	return da.preferredImageOf(candidates, legacy, da.policy.Priority)
+}
+
+// preferredImageOf is preferredImage with priority in place of the priority of the policy.
+func (da *dwarfAssembly) preferredImageOf(candidates []*proc.Image, legacy int, priority []string) int {
+	if len(candidates) <= 1 {
+		return legacy
+	}
+
+	for _, path := range priority {
+		for i, img := range candidates {
+			if img != nil && strings.HasSuffix(img.Path, path) {
+				return i
+			}
+		}
+	}
+
+	var chosen = legacy
+	switch da.policy.Order {
+	case ResolveNewestFirst:
+		for i, img := range candidates {
+			if da.imageIndex(img) > da.imageIndex(candidates[chosen]) {
+				chosen = i
+			}
+		}
+	case ResolveMainFirst:
+		for i, img := range candidates {
+			if da.imageIndex(img) < da.imageIndex(candidates[chosen]) {
+				chosen = i
+			}
+		}
+	}
+	return chosen
+}
+
+// SymbolKind classifies the symbols resolved by the assembly.
+type SymbolKind int
+
+const (
+	SymbolFunc SymbolKind = iota
+	SymbolType
+	SymbolGlobal
+)
+
+func (k SymbolKind) String() string {
+	switch k {
+	case SymbolFunc:
+		return "func"
+	case SymbolType:
+		return "type"
+	case SymbolGlobal:
+		return "global"
+	}
+	return fmt.Sprintf("SymbolKind(%d)", int(k))
+}
+
+// ShadowedSymbol is a symbol defined in more than one loaded image,
+// Images and Addrs are parallel and sorted by image load order.
+type ShadowedSymbol struct {
+	Kind   SymbolKind
+	Name   string
+	Images []*proc.Image
+	Addrs  []uint64
+}
+
+func (da *dwarfAssembly) ShadowedSymbols() []ShadowedSymbol {
+	var shadowed []ShadowedSymbol
+
+	for name, fns := range da.binaryInfo.LookupFunc() {
+		images := make([]*proc.Image, len(fns))
+		addrs := make([]uint64, len(fns))
+		for i, fn := range fns {
+			images[i], addrs[i] = funcToImage(da.binaryInfo, fn), fn.Entry
+		}
+		if s, ok := da.shadowed(SymbolFunc, name, images, addrs); ok {
+			shadowed = append(shadowed, s)
+		}
+	}
+
+	var typeImages = make(map[string][]*proc.Image)
+	var typeAddrs = make(map[string][]uint64)
+	for _, img := range da.binaryInfo.Images {
+		for name, addr := range da.imageTypeCache(img) {
+			typeImages[name] = append(typeImages[name], img)
+			typeAddrs[name] = append(typeAddrs[name], addr)
+		}
+	}
+	for name, images := range typeImages {
+		if s, ok := da.shadowed(SymbolType, name, images, typeAddrs[name]); ok {
+			shadowed = append(shadowed, s)
+		}
+	}
+
+	for name, defs := range da.globalsCache() {
+		images := make([]*proc.Image, len(defs))
+		addrs := make([]uint64, len(defs))
+		for i, def := range defs {
+			images[i], addrs[i] = def.image, uint64(def.value.UnsafeAddr())
+		}
+		if s, ok := da.shadowed(SymbolGlobal, name, images, addrs); ok {
+			shadowed = append(shadowed, s)
+		}
+	}
+
+	sort.Slice(shadowed, func(i, j int) bool {
+		if shadowed[i].Kind != shadowed[j].Kind {
+			return shadowed[i].Kind < shadowed[j].Kind
+		}
+		return shadowed[i].Name < shadowed[j].Name
+	})
+	return shadowed
+}
+
+// shadowed builds a ShadowedSymbol if the definitions span more than one image.
+func (da *dwarfAssembly) shadowed(kind SymbolKind, name string, images []*proc.Image, addrs []uint64) (ShadowedSymbol, bool) {
+	s := ShadowedSymbol{Kind: kind, Name: name}
+	for _, img := range da.binaryInfo.Images {
+		for i := range images {
+			if images[i] == img {
+				s.Images = append(s.Images, img)
+				s.Addrs = append(s.Addrs, addrs[i])
+				break
+			}
+		}
+	}
+	return s, len(s.Images) > 1
+}
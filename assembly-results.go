@@ -0,0 +1,70 @@
+package assembly
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// CallResult holds the results of a call along with their DWARF names, Names and Values are parallel.
+// Unnamed results are reported by the compiler as ~r0, ~r1, ...
+type CallResult struct {
+	Values []reflect.Value
+	Names  []string
+}
+
+// Get returns the result named name.
+func (r *CallResult) Get(name string) (reflect.Value, bool) {
+	for i, n := range r.Names {
+		if n == name {
+			return r.Values[i], true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// Err returns the last result as a Go error if the function's last result is of type error.
+func (r *CallResult) Err() error {
+	if len(r.Values) == 0 {
+		return nil
+	}
+	last := r.Values[len(r.Values)-1]
+	if last.Type() != errorType || last.IsNil() {
+		return nil
+	}
+	return last.Interface().(error)
+}
+
+// CallFuncErr calls the function name following the convention of a trailing error result:
+// the error is returned as err and removed from the results. A failure to make the call is
+// reported through err as well. The last parameter of a variadic function takes a slice.
+func (da *dwarfAssembly) CallFuncErr(name string, args ...reflect.Value) ([]reflect.Value, error) {
+	res, err := da.CallFuncResult(name, false, args)
+	if err != nil {
+		return nil, err
+	}
+	if n := len(res.Values); n > 0 && res.Values[n-1].Type() == errorType {
+		return res.Values[:n-1], res.Err()
+	}
+	return res.Values, nil
+}
+
+// ArgumentError reports the parameter names missing from or unknown to a named call.
+type ArgumentError struct {
+	Func    string
+	Missing []string
+	Extra   []string
+}
+
+func (e *ArgumentError) Error() string {
+	var problems []string
+	if len(e.Missing) > 0 {
+		problems = append(problems, "missing "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Extra) > 0 {
+		problems = append(problems, "unexpected "+strings.Join(e.Extra, ", "))
+	}
+	return fmt.Sprintf("%s: arguments %s", e.Func, strings.Join(problems, "; "))
+}
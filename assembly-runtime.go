@@ -0,0 +1,438 @@
+package assembly
+
+import (
+	"bytes"
+	"debug/dwarf"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// SchedStats is a snapshot of the runtime scheduler counters, read without taking sched.lock.
+type SchedStats struct {
+	GOMAXPROCS       int
+	IdleProcs        int
+	Threads          int
+	IdleThreads      int
+	SpinningThreads  int
+	MaxThreads       int
+	SystemGoroutines int
+	GlobalRunQueue   int
+	GoroutineIDGen   uint64
+}
+
+// RuntimeModule is an entry of the runtime module list, one per loaded Go image.
+type RuntimeModule struct {
+	Name       string
+	PluginPath string
+	Text       uint64
+	EText      uint64
+	Types      uint64
+	ETypes     uint64
+}
+
+// GoroutineStatus mirrors the runtime _G* status constants.
+type GoroutineStatus uint32
+
+const (
+	GoroutineIdle GoroutineStatus = iota
+	GoroutineRunnable
+	GoroutineRunning
+	GoroutineSyscall
+	GoroutineWaiting
+	_
+	GoroutineDead
+	_
+	GoroutineCopyStack
+	GoroutinePreempted
+
+	// GoroutineScan is combined with one of the above while the GC scans the stack.
+	GoroutineScan GoroutineStatus = 0x1000
+)
+
+var goroutineStatusNames = [...]string{
+	GoroutineIdle:      "idle",
+	GoroutineRunnable:  "runnable",
+	GoroutineRunning:   "running",
+	GoroutineSyscall:   "syscall",
+	GoroutineWaiting:   "waiting",
+	GoroutineDead:      "dead",
+	GoroutineCopyStack: "copystack",
+	GoroutinePreempted: "preempted",
+}
+
+func (s GoroutineStatus) String() string {
+	var prefix string
+	if s&GoroutineScan != 0 {
+		prefix, s = "scan", s&^GoroutineScan
+	}
+	if int(s) < len(goroutineStatusNames) && goroutineStatusNames[s] != "" {
+		return prefix + goroutineStatusNames[s]
+	}
+	return fmt.Sprintf("GoroutineStatus(%#x)", uint32(s))
+}
+
+// Goroutine identifies a runtime.g, Addr is the address of the g structure.
+type Goroutine struct {
+	ID     uint64
+	Status GoroutineStatus
+	Addr   uint64
+}
+
+// runtimeVar is a value of the host runtime located through its DWARF type, so field
+// offsets always match the Go release the executable was built with. mem is the memory of
+// a remote process the value lives in, nil for the running process.
+type runtimeVar struct {
+	name string
+	addr uint64
+	typ  godwarf.Type
+	mem  proc.MemoryReadWriter
+}
+
+func (da *dwarfAssembly) GOMAXPROCS() (int, error) {
+	n, err := da.RuntimeStat("gomaxprocs")
+	return int(n), err
+}
+
+func (da *dwarfAssembly) SchedStats() (SchedStats, error) {
+	var stats SchedStats
+	var mnext, nmfreed, goidgen int64
+
+	var fields = []struct {
+		dst  interface{}
+		stat string
+	}{
+		{&stats.GOMAXPROCS, "gomaxprocs"},
+		{&stats.IdleProcs, "sched.npidle"},
+		{&stats.IdleThreads, "sched.nmidle"},
+		{&stats.SpinningThreads, "sched.nmspinning"},
+		{&stats.MaxThreads, "sched.maxmcount"},
+		{&stats.SystemGoroutines, "sched.ngsys"},
+		{&stats.GlobalRunQueue, "sched.runqsize"},
+		{&mnext, "sched.mnext"},
+		{&nmfreed, "sched.nmfreed"},
+		{&goidgen, "sched.goidgen"},
+	}
+	for _, f := range fields {
+		n, err := da.RuntimeStat(f.stat)
+		if err != nil {
+			return stats, err
+		}
+		switch dst := f.dst.(type) {
+		case *int:
+			*dst = int(n)
+		case *int64:
+			*dst = n
+		}
+	}
+
+	stats.Threads = int(mnext - nmfreed)
+	stats.GoroutineIDGen = uint64(goidgen)
+	return stats, nil
+}
+
+func (da *dwarfAssembly) RuntimeModules() ([]RuntimeModule, error) {
+	var modules []RuntimeModule
+	err := da.walkModules(func(_ runtimeVar, m RuntimeModule) bool {
+		modules = append(modules, m)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return modules, nil
+}
+
+// walkModules reads the runtime module data list from runtime.firstmoduledata, until fn returns false.
+func (da *dwarfAssembly) walkModules(fn func(md runtimeVar, m RuntimeModule) bool) error {
+	md, err := da.runtimeGlobal("runtime.firstmoduledata")
+	if err != nil {
+		return err
+	}
+	return walkModuleList(md, fn)
+}
+
+// walkModuleList follows the module data list starting at md, until fn returns false.
+func walkModuleList(md runtimeVar, fn func(md runtimeVar, m RuntimeModule) bool) (err error) {
+	for md.addr != 0 {
+		var m RuntimeModule
+		if m.Name, err = md.stringField("modulename"); err != nil {
+			return err
+		}
+		if m.PluginPath, err = md.stringField("pluginpath"); err != nil {
+			return err
+		}
+		for _, f := range []struct {
+			dst  *uint64
+			path string
+		}{{&m.Text, "text"}, {&m.EText, "etext"}, {&m.Types, "types"}, {&m.ETypes, "etypes"}} {
+			var n int64
+			if n, err = md.intField(f.path); err != nil {
+				return err
+			}
+			*f.dst = uint64(n)
+		}
+		if !fn(md, m) {
+			return nil
+		}
+
+		if md, err = md.field("next"); err != nil {
+			return err
+		}
+		if md, err = md.deref(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CurrentGoroutine resolves the runtime.g of the calling goroutine. The goroutine id reported by
+// the runtime is matched against runtime.allgs, whose layout is read from DWARF.
+func (da *dwarfAssembly) CurrentGoroutine() (Goroutine, error) {
+	goid, err := currentGoid()
+	if err != nil {
+		return Goroutine{}, err
+	}
+
+	allgs, err := da.runtimeGlobal("runtime.allgs")
+	if err != nil {
+		return Goroutine{}, err
+	}
+	n, err := allgs.intField("len")
+	if err != nil {
+		return Goroutine{}, err
+	}
+	array, err := allgs.field("array")
+	if err != nil {
+		return Goroutine{}, err
+	}
+	if array, err = array.deref(); err != nil {
+		return Goroutine{}, err
+	}
+
+	for i := int64(0); i < n; i++ {
+		elem := runtimeVar{name: fmt.Sprintf("runtime.allgs[%d]", i), addr: array.addr + uint64(i)*uint64(array.typ.Size()), typ: array.typ}
+		g, err := elem.deref()
+		if err != nil {
+			return Goroutine{}, err
+		}
+		id, err := g.intField("goid")
+		if err != nil {
+			return Goroutine{}, err
+		}
+		if uint64(id) != goid {
+			continue
+		}
+		status, err := g.intField("atomicstatus")
+		if err != nil {
+			return Goroutine{}, err
+		}
+		return Goroutine{ID: goid, Status: GoroutineStatus(status), Addr: g.addr}, nil
+	}
+	return Goroutine{}, fmt.Errorf("goroutine %d: %w", goid, ErrNotFound)
+}
+
+// currentGoid parses the id of the calling goroutine from the header of its stack trace.
+func currentGoid() (uint64, error) {
+	var buf [64]byte
+	header := buf[:runtime.Stack(buf[:], false)]
+	id := bytes.TrimPrefix(header, []byte("goroutine "))
+	if i := bytes.IndexByte(id, ' '); i > 0 {
+		return strconv.ParseUint(string(id[:i]), 10, 64)
+	}
+	return 0, fmt.Errorf("unexpected stack header %q", header)
+}
+
+// runtimeGlobal locates a package variable of the runtime, which is always linked into the main executable.
+func (da *dwarfAssembly) runtimeGlobal(name string) (runtimeVar, error) {
+	var v = runtimeVar{name: name}
+	var err = fmt.Errorf("%s: %w", name, ErrNotFound)
+
+	da.walkPackageVars(func(pv packageVar) bool {
+		if pv.name != name || pv.image != da.binaryInfo.Images[0] {
+			return true
+		}
+		rdr := da.acquireReader(pv.image)
+		entry, e := pv.entry(rdr)
+		da.releaseReader(pv.image, rdr)
+		if e != nil {
+			err = e
+			return false
+		}
+		off, ok := entry.Val(dwarf.AttrType).(dwarf.Offset)
+		if !ok {
+			err = fmt.Errorf("%s: unable to find type offset for entry", name)
+			return false
+		}
+		if v.typ, err = pv.image.Type(off); err != nil {
+			return false
+		}
+		v.addr = pv.addr
+		return false
+	})
+	return v, err
+}
+
+// field returns the struct field at the dotted path, following atomic and typedef wrappers.
+func (v runtimeVar) field(path string) (runtimeVar, error) {
+	for _, name := range strings.Split(path, ".") {
+		st := structType(v.typ)
+		if st == nil {
+			return runtimeVar{}, fmt.Errorf("%s: not a struct: %w", v.name, ErrNotSupport)
+		}
+		var found bool
+		for _, f := range st.Field {
+			if f.Name == name {
+				v = runtimeVar{name: v.name + "." + name, addr: v.addr + uint64(f.ByteOffset), typ: f.Type, mem: v.mem}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return runtimeVar{}, fmt.Errorf("%s.%s: %w", v.name, name, ErrNotFound)
+		}
+	}
+	return v, nil
+}
+
+// structType returns the struct layout of typ, including the layout of slices and strings.
+func structType(typ godwarf.Type) *godwarf.StructType {
+	switch typ := resolveTypedef(typ).(type) {
+	case *godwarf.StructType:
+		return typ
+	case *godwarf.SliceType:
+		return &typ.StructType
+	case *godwarf.StringType:
+		return &typ.StructType
+	}
+	return nil
+}
+
+// intField reads the integer at the first of paths present in the struct; several
+// paths cover fields renamed or moved between Go releases.
+func (v runtimeVar) intField(paths ...string) (int64, error) {
+	var err error
+	for _, path := range paths {
+		var f runtimeVar
+		if f, err = v.field(path); err == nil {
+			return f.int()
+		}
+	}
+	return 0, err
+}
+
+func (v runtimeVar) stringField(path string) (string, error) {
+	f, err := v.field(path)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := resolveTypedef(f.typ).(*godwarf.StringType); !ok {
+		return "", fmt.Errorf("%s: not a string: %w", f.name, ErrNotSupport)
+	}
+	if f.mem == nil {
+		return *(*string)(unsafe.Pointer(uintptr(f.addr))), nil
+	}
+	header, err := f.read(int64(2 * unsafe.Sizeof(uintptr(0))))
+	if err != nil {
+		return "", err
+	}
+	words := (*[2]uintptr)(unsafe.Pointer(&header[0]))
+	data, err := runtimeVar{name: f.name, addr: uint64(words[0]), mem: f.mem}.read(int64(words[1]))
+	return string(data), err
+}
+
+// read copies size bytes at the address of v from the memory v lives in.
+func (v runtimeVar) read(size int64) ([]byte, error) {
+	buf := make([]byte, size)
+	if size == 0 {
+		return buf, nil
+	}
+	var mem proc.MemoryReadWriter = new(localMemory)
+	if v.mem != nil {
+		mem = v.mem
+	}
+	if _, err := mem.ReadMemory(buf, v.addr); err != nil {
+		return nil, fmt.Errorf("%s: read %d bytes at %#x: %w", v.name, size, v.addr, err)
+	}
+	return buf, nil
+}
+
+// int reads an integer or boolean, unwrapping atomic style structs holding a single sized field.
+func (v runtimeVar) int() (int64, error) {
+	if v.addr == 0 {
+		return 0, fmt.Errorf("%s: nil pointer dereference", v.name)
+	}
+	p := unsafe.Pointer(uintptr(v.addr))
+	if v.mem != nil {
+		buf, err := v.read(v.typ.Size())
+		if err != nil || len(buf) == 0 {
+			return 0, err
+		}
+		p = unsafe.Pointer(&buf[0])
+	}
+	switch typ := resolveTypedef(v.typ).(type) {
+	case *godwarf.IntType:
+		switch typ.ByteSize {
+		case 1:
+			return int64(*(*int8)(p)), nil
+		case 2:
+			return int64(*(*int16)(p)), nil
+		case 4:
+			return int64(*(*int32)(p)), nil
+		case 8:
+			return *(*int64)(p), nil
+		}
+	case *godwarf.UintType:
+		switch typ.ByteSize {
+		case 1:
+			return int64(*(*uint8)(p)), nil
+		case 2:
+			return int64(*(*uint16)(p)), nil
+		case 4:
+			return int64(*(*uint32)(p)), nil
+		case 8:
+			return int64(*(*uint64)(p)), nil
+		}
+	case *godwarf.BoolType:
+		if *(*bool)(p) {
+			return 1, nil
+		}
+		return 0, nil
+	case *godwarf.StructType:
+		var value *godwarf.StructField
+		for _, f := range typ.Field {
+			if f.Type.Size() != 0 {
+				if value != nil {
+					value = nil
+					break
+				}
+				value = f
+			}
+		}
+		if value != nil {
+			return runtimeVar{name: v.name, addr: v.addr + uint64(value.ByteOffset), typ: value.Type, mem: v.mem}.int()
+		}
+	}
+	return 0, fmt.Errorf("%s: %s is not an integer: %w", v.name, v.typ, ErrNotSupport)
+}
+
+// deref follows a pointer, a nil pointer yields a runtimeVar with a zero address.
+func (v runtimeVar) deref() (runtimeVar, error) {
+	ptr, ok := resolveTypedef(v.typ).(*godwarf.PtrType)
+	if !ok {
+		return runtimeVar{}, fmt.Errorf("%s: not a pointer: %w", v.name, ErrNotSupport)
+	}
+	if v.mem != nil {
+		buf, err := v.read(int64(unsafe.Sizeof(uintptr(0))))
+		if err != nil {
+			return runtimeVar{}, err
+		}
+		return runtimeVar{name: v.name, addr: uint64(*(*uintptr)(unsafe.Pointer(&buf[0]))), typ: ptr.Type, mem: v.mem}, nil
+	}
+	return runtimeVar{name: v.name, addr: uint64(*(*uintptr)(unsafe.Pointer(uintptr(v.addr)))), typ: ptr.Type}, nil
+}
@@ -0,0 +1,234 @@
+package assembly
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// Scope is the code a scoped view of an assembly may see and touch.
+type Scope struct {
+	// Packages lists the import paths in scope, a path ending in "/..." also admits the packages below it.
+	Packages []string
+	// Images lists the paths or base names of the images in scope, empty admits every image.
+	Images []string
+}
+
+// ScopedAssembly is a view of an assembly restricted to a Scope, handed out so that a caller only
+// sees and touches the code of its own packages. Symbols outside of the scope are reported as
+// ErrOutOfScope by lookups and skipped by iteration. The view does not expose the assembly it
+// was created from, which must stay open while the view is used.
+type ScopedAssembly struct {
+	da    *dwarfAssembly
+	scope Scope
+}
+
+// Scoped returns a view of the assembly restricted to scope, scope is copied.
+func (da *dwarfAssembly) Scoped(scope Scope) *ScopedAssembly {
+	return &ScopedAssembly{da: da, scope: Scope{
+		Packages: append([]string(nil), scope.Packages...),
+		Images:   append([]string(nil), scope.Images...),
+	}}
+}
+
+// Scope returns the scope of the view.
+func (s *ScopedAssembly) Scope() Scope {
+	return Scope{Packages: append([]string(nil), s.scope.Packages...), Images: append([]string(nil), s.scope.Images...)}
+}
+
+func (s *ScopedAssembly) FindType(name string) (reflect.Type, error) {
+	typ, img, err := s.da.ResolveType(name)
+	if err != nil && !errors.Is(err, ErrSyntheticType) {
+		return nil, err
+	}
+	if pkg := typePkgPath(typ); (pkg != "" && !s.packageInScope(pkg)) || !s.imageInScope(img) {
+		return nil, fmt.Errorf("type %s: %w", name, ErrOutOfScope)
+	}
+	return typ, err
+}
+
+// ForeachType yields the types declared by the packages in scope.
+func (s *ScopedAssembly) ForeachType(f func(name string) bool) error {
+	return s.da.ForeachType(func(name string) bool {
+		base := strings.TrimLeft(name, "*[]0123456789")
+		if !s.symbolInScope(base) {
+			return true
+		}
+		return f(name)
+	})
+}
+
+func (s *ScopedAssembly) FindFuncType(name string, variadic bool) (reflect.Type, error) {
+	f, err := s.resolveFunc(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.da.FindFuncType(f.Name, variadic)
+}
+
+func (s *ScopedAssembly) FindFunc(name string, variadic bool) (reflect.Value, error) {
+	f, err := s.resolveFunc(name)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return s.da.FindFunc(f.Name, variadic)
+}
+
+func (s *ScopedAssembly) CallFunc(name string, variadic bool, args []reflect.Value) ([]reflect.Value, error) {
+	f, err := s.resolveFunc(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.da.CallFunc(f.Name, variadic, args)
+}
+
+// ForeachFunc yields the functions in scope.
+func (s *ScopedAssembly) ForeachFunc(f func(name string, pc uint64) bool) {
+	visible := s.da.visibleFilter()
+	functions := s.da.snapshot().functions
+	for i := range functions {
+		fn := &functions[i]
+		if fn.Entry == 0 || !visible(fn.Name) || !s.symbolInScope(fn.Name) || !s.imageInScope(funcToImage(s.da.binaryInfo, fn)) {
+			continue
+		}
+		if !f(s.da.displayName(fn.Name), fn.Entry) {
+			break
+		}
+	}
+}
+
+// PatchFunc patches target, which must be in scope, see DwarfAssembly.PatchFunc.
+func (s *ScopedAssembly) PatchFunc(target string, replacement reflect.Value) (*Patch, error) {
+	f, err := s.resolveFunc(target)
+	if err != nil {
+		return nil, err
+	}
+	return s.da.PatchFunc(f.Name, replacement)
+}
+
+// PatchFuncSafe patches target, which must be in scope, see DwarfAssembly.PatchFuncSafe.
+func (s *ScopedAssembly) PatchFuncSafe(target string, replacement reflect.Value) (*Patch, error) {
+	f, err := s.resolveFunc(target)
+	if err != nil {
+		return nil, err
+	}
+	return s.da.PatchFuncSafe(f.Name, replacement)
+}
+
+func (s *ScopedAssembly) FindGlobal(name string) (reflect.Value, error) {
+	name, err := s.resolveGlobal(name)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return s.da.FindGlobal(name)
+}
+
+func (s *ScopedAssembly) SetGlobal(name string, value reflect.Value) error {
+	name, err := s.resolveGlobal(name)
+	if err != nil {
+		return err
+	}
+	return s.da.SetGlobal(name, value)
+}
+
+// ForeachGlobal yields the global variables in scope.
+func (s *ScopedAssembly) ForeachGlobal(fn func(name string, value reflect.Value) bool) {
+	for name, defs := range s.da.globalsCache() {
+		g := s.da.preferredGlobal(defs)
+		if !s.symbolInScope(name) || !s.imageInScope(g.image) {
+			continue
+		}
+		if !fn(s.da.displayName(name), g.value) {
+			break
+		}
+	}
+}
+
+// resolveFunc resolves name and checks the function it resolves to, aliases cannot escape the scope.
+func (s *ScopedAssembly) resolveFunc(name string) (*proc.Function, error) {
+	f, img, err := s.da.ResolveFunc(name)
+	if err != nil {
+		return nil, err
+	}
+	if !s.symbolInScope(f.Name) || !s.imageInScope(img) {
+		return nil, fmt.Errorf("func %s: %w", name, ErrOutOfScope)
+	}
+	return f, nil
+}
+
+// resolveGlobal returns the DWARF name of the global variable name after checking it is in scope.
+func (s *ScopedAssembly) resolveGlobal(name string) (string, error) {
+	resolved, ok := s.da.resolveName(name, s.da.hasGlobal)
+	if !ok {
+		return "", ErrNotFound
+	}
+	if !s.symbolInScope(resolved) || !s.imageInScope(s.da.preferredGlobal(s.da.namedGlobals(resolved)).image) {
+		return "", fmt.Errorf("global %s: %w", name, ErrOutOfScope)
+	}
+	return resolved, nil
+}
+
+// symbolInScope reports whether the package declaring the symbol name is in scope, by its DWARF
+// or its display name.
+func (s *ScopedAssembly) symbolInScope(name string) bool {
+	return s.packageInScope(symbolPackage(name)) || s.packageInScope(symbolPackage(s.da.displayName(name)))
+}
+
+func (s *ScopedAssembly) packageInScope(pkg string) bool {
+	if pkg == "" {
+		return false
+	}
+	for _, p := range s.scope.Packages {
+		if base, ok := strings.CutSuffix(p, "/..."); ok {
+			if pkg == base || strings.HasPrefix(pkg, base+"/") {
+				return true
+			}
+		} else if pkg == p {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *ScopedAssembly) imageInScope(img *proc.Image) bool {
+	if len(s.scope.Images) == 0 {
+		return true
+	}
+	if img == nil {
+		return false
+	}
+	for _, path := range s.scope.Images {
+		if path == img.Path || path == filepath.Base(img.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// symbolPackage returns the package path of a function, global or type name, the type
+// arguments of an instantiation are not considered.
+func symbolPackage(name string) string {
+	if i := strings.IndexByte(name, '['); i >= 0 {
+		name = name[:i]
+	}
+	pkg, _ := splitPackagePath(name)
+	return pkg
+}
+
+// typePkgPath returns the package of the named type at the end of the element chain of typ,
+// empty for predeclared types.
+func typePkgPath(typ reflect.Type) string {
+	for typ.Name() == "" {
+		switch typ.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map, reflect.Chan:
+			typ = typ.Elem()
+		default:
+			return ""
+		}
+	}
+	return typ.PkgPath()
+}
@@ -0,0 +1,68 @@
+package assembly
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// GlobalMatch is a global variable found by FindGlobals.
+type GlobalMatch struct {
+	Name  string
+	Value reflect.Value
+	Image *proc.Image
+}
+
+// FindGlobals returns the global variables whose DWARF or display name matches pattern, sorted
+// by name. A pattern prefixed with "re:" is a regular expression matching anywhere in the name.
+// Otherwise it is a glob in which '*' matches any run and '?' any single character except '/',
+// and which matches the end of the name starting at a path element, so "mypkg/*.config*" finds
+// "github.com/acme/mypkg/server.configPath". Only the matching variables are resolved.
+func (da *dwarfAssembly) FindGlobals(pattern string) ([]GlobalMatch, error) {
+	match, err := compileNamePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []GlobalMatch
+	for _, name := range da.globalNames() {
+		display := da.displayName(name)
+		if !match.MatchString(name) && !match.MatchString(display) {
+			continue
+		}
+		if defs := da.namedGlobals(name); len(defs) > 0 {
+			g := da.preferredGlobal(defs)
+			matches = append(matches, GlobalMatch{Name: display, Value: g.value, Image: g.image})
+		}
+	}
+	return matches, nil
+}
+
+// compileNamePattern compiles a FindGlobals pattern into a regular expression.
+func compileNamePattern(pattern string) (*regexp.Regexp, error) {
+	if expr, ok := strings.CutPrefix(pattern, "re:"); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %s: %w", pattern, err)
+		}
+		return re, nil
+	}
+
+	var expr strings.Builder
+	expr.WriteString(`(^|/)`)
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString(`[^/]*`)
+		case '?':
+			expr.WriteString(`[^/]`)
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString(`$`)
+	return regexp.MustCompile(expr.String()), nil
+}
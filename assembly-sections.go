@@ -0,0 +1,213 @@
+package assembly
+
+import (
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"fmt"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+const (
+	_VM_PROT_READ    = 0x1 // VM_PROT_READ as defined by mach/vm_prot.h
+	_VM_PROT_WRITE   = 0x2 // VM_PROT_WRITE as defined by mach/vm_prot.h
+	_VM_PROT_EXECUTE = 0x4 // VM_PROT_EXECUTE as defined by mach/vm_prot.h
+)
+
+// AddressInfo describes where an address of the running process lives.
+type AddressInfo struct {
+	Image   ImageInfo
+	Section string
+	Offset  uint64 // offset of the address from the start of the section
+}
+
+// SectionPerm is the memory protection of a section.
+type SectionPerm uint8
+
+const (
+	PermRead SectionPerm = 1 << iota
+	PermWrite
+	PermExec
+)
+
+func (p SectionPerm) String() string {
+	perm := []byte("---")
+	if p&PermRead != 0 {
+		perm[0] = 'r'
+	}
+	if p&PermWrite != 0 {
+		perm[1] = 'w'
+	}
+	if p&PermExec != 0 {
+		perm[2] = 'x'
+	}
+	return string(perm)
+}
+
+// Section is a section of an image relocated to its load address.
+type Section struct {
+	Name       string
+	Start, End uint64
+	Perm       SectionPerm
+}
+
+func (s Section) Executable() bool {
+	return s.Perm&PermExec != 0
+}
+
+func (s Section) Writable() bool {
+	return s.Perm&PermWrite != 0
+}
+
+func (s Section) Contains(addr uint64) bool {
+	return addr >= s.Start && addr < s.End
+}
+
+func (da *dwarfAssembly) ResolveAddress(addr uint64) (AddressInfo, error) {
+	for _, img := range da.binaryInfo.Images {
+		sections, err := da.imageSections(img)
+		if err != nil {
+			continue
+		}
+		for _, sec := range sections {
+			if sec.Contains(addr) {
+				return AddressInfo{Image: da.imageInfo(img), Section: sec.Name, Offset: addr - sec.Start}, nil
+			}
+		}
+	}
+	return AddressInfo{}, ErrNotFound
+}
+
+func (da *dwarfAssembly) Sections(image *proc.Image) ([]Section, error) {
+	sections, err := da.imageSections(image)
+	if err != nil {
+		return nil, err
+	}
+	return append([]Section(nil), sections...), nil
+}
+
+func (da *dwarfAssembly) imageSections(img *proc.Image) ([]Section, error) {
+	da.cacheMu.Lock()
+	sections, ok := da.sections[img]
+	da.cacheMu.Unlock()
+	if ok {
+		return sections, nil
+	}
+
+	sections, err := readImageSections(da.binaryInfo.GOOS, img)
+	if err != nil {
+		return nil, err
+	}
+
+	da.cacheMu.Lock()
+	defer da.cacheMu.Unlock()
+	if da.sections == nil {
+		da.sections = make(map[*proc.Image][]Section)
+	}
+	da.sections[img] = sections
+	return sections, nil
+}
+
+// readImageSections reads the sections of img from its object file of the format of goos.
+func readImageSections(goos string, img *proc.Image) ([]Section, error) {
+	var sections []Section
+	var err error
+	switch goos {
+	case "windows":
+		sections, err = readPESections(img)
+	case "darwin":
+		sections, err = readMachOSections(img)
+	default:
+		sections, err = readELFSections(img)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read sections failed: %s: %w", img.Path, err)
+	}
+	return sections, nil
+}
+
+func readELFSections(img *proc.Image) ([]Section, error) {
+	f, err := elf.Open(img.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var sections []Section
+	for _, sec := range f.Sections {
+		if sec.Flags&elf.SHF_ALLOC == 0 || sec.Addr == 0 {
+			continue
+		}
+		perm := PermRead
+		if sec.Flags&elf.SHF_WRITE != 0 {
+			perm |= PermWrite
+		}
+		if sec.Flags&elf.SHF_EXECINSTR != 0 {
+			perm |= PermExec
+		}
+		start := sec.Addr + img.StaticBase
+		sections = append(sections, Section{Name: sec.Name, Start: start, End: start + sec.Size, Perm: perm})
+	}
+	return sections, nil
+}
+
+func readPESections(img *proc.Image) ([]Section, error) {
+	f, err := pe.Open(img.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var imageBase uint64
+	switch opth := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		imageBase = uint64(opth.ImageBase)
+	case *pe.OptionalHeader64:
+		imageBase = opth.ImageBase
+	}
+
+	var sections []Section
+	for _, sec := range f.Sections {
+		var perm SectionPerm
+		if sec.Characteristics&pe.IMAGE_SCN_MEM_READ != 0 {
+			perm |= PermRead
+		}
+		if sec.Characteristics&pe.IMAGE_SCN_MEM_WRITE != 0 {
+			perm |= PermWrite
+		}
+		if sec.Characteristics&pe.IMAGE_SCN_MEM_EXECUTE != 0 {
+			perm |= PermExec
+		}
+		start := imageBase + uint64(sec.VirtualAddress) + img.StaticBase
+		sections = append(sections, Section{Name: sec.Name, Start: start, End: start + uint64(sec.VirtualSize), Perm: perm})
+	}
+	return sections, nil
+}
+
+func readMachOSections(img *proc.Image) ([]Section, error) {
+	f, err := macho.Open(img.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var sections []Section
+	for _, sec := range f.Sections {
+		var perm SectionPerm
+		if seg := f.Segment(sec.Seg); seg != nil {
+			if seg.Prot&_VM_PROT_READ != 0 {
+				perm |= PermRead
+			}
+			if seg.Prot&_VM_PROT_WRITE != 0 {
+				perm |= PermWrite
+			}
+			if seg.Prot&_VM_PROT_EXECUTE != 0 {
+				perm |= PermExec
+			}
+		}
+		start := sec.Addr + img.StaticBase
+		sections = append(sections, Section{Name: sec.Seg + "," + sec.Name, Start: start, End: start + sec.Size, Perm: perm})
+	}
+	return sections, nil
+}
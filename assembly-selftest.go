@@ -0,0 +1,113 @@
+package assembly
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"time"
+)
+
+// SelfTestReport is the outcome of SelfTest on the running toolchain and platform.
+type SelfTestReport struct {
+	GoVersion string
+	GOOS      string
+	GOARCH    string
+	Checks    []SelfTestCheck
+}
+
+// SelfTestCheck is one path exercised by SelfTest, Err is nil if it works.
+type SelfTestCheck struct {
+	Name     string
+	Duration time.Duration
+	Err      error
+}
+
+// selfTestPoint, selfTestGlobal and selfTestAdd are the symbols SelfTest looks up, the references
+// of SelfTest keep them in every binary using the package.
+type selfTestPoint struct {
+	X, Y int
+	Name string
+}
+
+var selfTestGlobal = selfTestPoint{X: 1, Y: 2, Name: "self test"}
+
+//go:noinline
+func selfTestAdd(a, b int) int {
+	return a + b
+}
+
+// SelfTest exercises the unsafe paths of the assembly on symbols of this package: it creates and
+// calls a function, reads a global variable and resolves a type, comparing each to what the
+// compiler produced. Run at service start, it surfaces an unsupported toolchain or platform
+// before the first hotfix does. The error joins the failed checks, the report lists them all.
+func (da *dwarfAssembly) SelfTest() (*SelfTestReport, error) {
+	pkg := reflect.TypeOf(selfTestPoint{}).PkgPath()
+	report := &SelfTestReport{GoVersion: runtime.Version(), GOOS: runtime.GOOS, GOARCH: runtime.GOARCH}
+
+	report.run("call function", func() error {
+		name := pkg + ".selfTestAdd"
+		pc, err := da.FindFuncPc(name)
+		if err != nil {
+			return err
+		}
+		if want := reflect.ValueOf(selfTestAdd).Pointer(); uintptr(pc) != want {
+			return fmt.Errorf("%s: entry %#x, compiled at %#x", name, pc, want)
+		}
+		out, err := da.CallFunc(name, false, []reflect.Value{reflect.ValueOf(2), reflect.ValueOf(3)})
+		if err != nil {
+			return err
+		}
+		if len(out) != 1 || out[0].Int() != 5 {
+			return fmt.Errorf("%s(2, 3) returned %v, want 5", name, out)
+		}
+		return nil
+	})
+	report.run("read global", func() error {
+		name := pkg + ".selfTestGlobal"
+		global, err := da.FindGlobal(name)
+		if err != nil {
+			return err
+		}
+		if global.UnsafeAddr() != reflect.ValueOf(&selfTestGlobal).Pointer() {
+			return fmt.Errorf("%s: address %#x, compiled at %p", name, global.UnsafeAddr(), &selfTestGlobal)
+		}
+		if p, ok := global.Interface().(selfTestPoint); !ok || p != selfTestGlobal {
+			return fmt.Errorf("%s: read %v, want %v", name, global, selfTestGlobal)
+		}
+		return nil
+	})
+	report.run("resolve type", func() error {
+		name := pkg + ".selfTestPoint"
+		typ, err := da.FindType(name)
+		if err != nil {
+			return err
+		}
+		if want := reflect.TypeOf(selfTestPoint{}); typ != want {
+			return fmt.Errorf("%s: resolved %s, want %s", name, typ, want)
+		}
+		return nil
+	})
+
+	var errs []error
+	for _, check := range report.Checks {
+		if check.Err != nil {
+			errs = append(errs, fmt.Errorf("self test %s: %w", check.Name, check.Err))
+		}
+	}
+	return report, errors.Join(errs...)
+}
+
+// run records the check named name, a panic of check fails it.
+func (r *SelfTestReport) run(name string, check func() error) {
+	start := time.Now()
+	err := func() (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = fmt.Errorf("panic: %v", p)
+			}
+		}()
+		return check()
+	}()
+	r.Checks = append(r.Checks, SelfTestCheck{Name: name, Duration: time.Since(start), Err: err})
+}
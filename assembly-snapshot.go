@@ -0,0 +1,57 @@
+package assembly
+
+import (
+	"reflect"
+	"slices"
+	"unsafe"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// indexSnapshot is the function and package variable indexes of delve at one point in time.
+// LoadImage replaces the indexes instead of letting delve grow and sort them in place, so an
+// iteration over a snapshot stays consistent while images are loaded by another goroutine.
+type indexSnapshot struct {
+	functions   []proc.Function
+	packageVars reflect.Value
+}
+
+// snapshot returns the current indexes, waiting for a LoadImage in progress.
+func (da *dwarfAssembly) snapshot() indexSnapshot {
+	da.indexMu.RLock()
+	defer da.indexMu.RUnlock()
+	snap := indexSnapshot{functions: da.binaryInfo.Functions}
+	if field := packageVarsField(da.binaryInfo); field.IsValid() {
+		snap.packageVars = reflect.New(field.Type()).Elem()
+		snap.packageVars.Set(field)
+	}
+	return snap
+}
+
+// extendIndexes runs load, which adds an image to delve, on copies of the indexes: snapshots
+// keep the previous backing arrays to themselves.
+func (da *dwarfAssembly) extendIndexes(load func() error) error {
+	da.indexMu.Lock()
+	defer da.indexMu.Unlock()
+	da.binaryInfo.Functions = slices.Clone(da.binaryInfo.Functions)
+	if field := packageVarsField(da.binaryInfo); field.IsValid() {
+		vars := reflect.MakeSlice(field.Type(), field.Len(), field.Len())
+		reflect.Copy(vars, field)
+		field.Set(vars)
+	}
+	return load()
+}
+
+// packageVarsField returns the settable packageVars field of bi.
+func packageVarsField(bi *proc.BinaryInfo) reflect.Value {
+	return unexportedField(bi, "packageVars")
+}
+
+// unexportedField returns the settable field name of bi.
+func unexportedField(bi *proc.BinaryInfo, name string) reflect.Value {
+	field := reflect.ValueOf(bi).Elem().FieldByName(name)
+	if !field.IsValid() {
+		return field
+	}
+	return reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
+}
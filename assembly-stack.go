@@ -0,0 +1,72 @@
+package assembly
+
+import (
+	"debug/dwarf"
+
+	"github.com/go-delve/delve/pkg/dwarf/reader"
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// StackFrame is a call of a symbolized stack, Inlined if the body of Func was inlined into the
+// function of the next frame.
+type StackFrame struct {
+	Func    string
+	File    string
+	Line    int
+	Inlined bool
+}
+
+// SymbolizedPC is a PC of a stack with the frames it stands for, innermost first. A PC outside
+// the functions of the loaded images has no frames.
+type SymbolizedPC struct {
+	PC     uint64
+	Frames []StackFrame
+	Image  *proc.Image
+}
+
+// SymbolizeStack decodes the PCs of a stack, as recorded by runtime.Callers, against the loaded
+// images including plugins, expanding the calls inlined at each PC like runtime.CallersFrames.
+// The PCs are return addresses, each is looked up one byte back, at its call instruction.
+func (da *dwarfAssembly) SymbolizeStack(pcs []uint64) []SymbolizedPC {
+	stack := make([]SymbolizedPC, len(pcs))
+	for i, pc := range pcs {
+		stack[i].PC = pc
+		if pc == 0 {
+			continue
+		}
+		file, line, fn := da.binaryInfo.PCToLine(pc - 1)
+		if fn == nil {
+			continue
+		}
+		img := funcToImage(da.binaryInfo, fn)
+		if da.imageStale(img) != nil {
+			continue
+		}
+		stack[i].Image = img
+		stack[i].Frames = da.inlineFrames(fn, img, pc-1, file, line)
+	}
+	return stack
+}
+
+// inlineFrames returns the frames of pc in fn, located at file and line: the calls inlined at pc
+// innermost first, then fn.
+func (da *dwarfAssembly) inlineFrames(fn *proc.Function, img *proc.Image, pc uint64, file string, line int) []StackFrame {
+	var frames []StackFrame
+	if tree, err := getDwarfTree(img, funcOffset(fn)); err == nil {
+		for _, entry := range reader.InlineStack(tree, pc) {
+			name, okName := entry.Val(dwarf.AttrName).(string)
+			fileidx, okFile := entry.Val(dwarf.AttrCallFile).(int64)
+			callLine, okLine := entry.Val(dwarf.AttrCallLine).(int64)
+			if !okName || !okFile || !okLine {
+				break
+			}
+			callFile, ok := funcFilePath(fn, int(fileidx))
+			if !ok {
+				break
+			}
+			frames = append(frames, StackFrame{Func: da.displayName(name), File: file, Line: line, Inlined: true})
+			file, line = callFile, int(callLine)
+		}
+	}
+	return append(frames, StackFrame{Func: da.displayName(fn.Name), File: file, Line: line})
+}
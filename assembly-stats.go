@@ -0,0 +1,80 @@
+package assembly
+
+import (
+	"fmt"
+	"sort"
+)
+
+// runtimeStatField locates a curated runtime statistic as a field path within a runtime global.
+type runtimeStatField struct {
+	global string
+	path   string
+}
+
+// runtimeStats lists the runtime statistics readable through RuntimeStat. Statistics that were moved
+// or renamed between Go releases list one location per layout, newest first; the first location
+// present in the loaded runtime's DWARF is read.
+var runtimeStats = map[string][]runtimeStatField{
+	"gomaxprocs": {{"runtime.gomaxprocs", ""}},
+	"allglen":    {{"runtime.allglen", ""}},
+
+	"sched.goidgen":      {{"runtime.sched", "goidgen"}},
+	"sched.npidle":       {{"runtime.sched", "npidle"}},
+	"sched.nmidle":       {{"runtime.sched", "nmidle"}},
+	"sched.nmidlelocked": {{"runtime.sched", "nmidlelocked"}},
+	"sched.nmspinning":   {{"runtime.sched", "nmspinning"}},
+	"sched.mnext":        {{"runtime.sched", "mnext"}},
+	"sched.maxmcount":    {{"runtime.sched", "maxmcount"}},
+	"sched.nmsys":        {{"runtime.sched", "nmsys"}},
+	"sched.nmfreed":      {{"runtime.sched", "nmfreed"}},
+	"sched.ngsys":        {{"runtime.sched", "ngsys"}},
+	"sched.runqsize":     {{"runtime.sched", "runq.size"}, {"runtime.sched", "runqsize"}},
+	"sched.gcwaiting":    {{"runtime.sched", "gcwaiting"}},
+	"sched.stopwait":     {{"runtime.sched", "stopwait"}},
+	"sched.profilehz":    {{"runtime.sched", "profilehz"}},
+
+	"memstats.numgc":          {{"runtime.memstats", "numgc"}},
+	"memstats.numforcedgc":    {{"runtime.memstats", "numforcedgc"}},
+	"memstats.pause_total_ns": {{"runtime.memstats", "pause_total_ns"}},
+	"memstats.last_gc_unix":   {{"runtime.memstats", "last_gc_unix"}},
+	"memstats.enablegc":       {{"runtime.memstats", "enablegc"}},
+
+	// go1.18 moved the pacer state out of memstats into gcController.
+	"gc.heap_live":   {{"runtime.gcController", "heapLive"}, {"runtime.memstats", "heap_live"}},
+	"gc.heap_marked": {{"runtime.gcController", "heapMarked"}, {"runtime.memstats", "heap_marked"}},
+	"gc.percent":     {{"runtime.gcController", "gcPercent"}, {"runtime.gcpercent", ""}},
+}
+
+// RuntimeStatNames returns the sorted names accepted by RuntimeStat.
+func RuntimeStatNames() []string {
+	names := make([]string, 0, len(runtimeStats))
+	for name := range runtimeStats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RuntimeStat reads a curated runtime statistic, see RuntimeStatNames. Values are read without
+// synchronization and may be torn while the runtime is updating them.
+func (da *dwarfAssembly) RuntimeStat(name string) (int64, error) {
+	fields, ok := runtimeStats[name]
+	if !ok {
+		return 0, fmt.Errorf("runtime stat %s: %w", name, ErrNotFound)
+	}
+
+	var err error
+	for _, f := range fields {
+		var v runtimeVar
+		if v, err = da.runtimeGlobal(f.global); err != nil {
+			continue
+		}
+		if f.path != "" {
+			if v, err = v.field(f.path); err != nil {
+				continue
+			}
+		}
+		return v.int()
+	}
+	return 0, fmt.Errorf("runtime stat %s: %w", name, err)
+}
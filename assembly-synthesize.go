@@ -0,0 +1,185 @@
+package assembly
+
+import (
+	"debug/dwarf"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+	"unsafe"
+
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
+)
+
+// basicTypes are the runtime types of the kinds synthesized without a DWARF description.
+var basicTypes = map[reflect.Kind]reflect.Type{
+	reflect.Bool:          reflect.TypeOf(false),
+	reflect.Int:           reflect.TypeOf(int(0)),
+	reflect.Int8:          reflect.TypeOf(int8(0)),
+	reflect.Int16:         reflect.TypeOf(int16(0)),
+	reflect.Int32:         reflect.TypeOf(int32(0)),
+	reflect.Int64:         reflect.TypeOf(int64(0)),
+	reflect.Uint:          reflect.TypeOf(uint(0)),
+	reflect.Uint8:         reflect.TypeOf(uint8(0)),
+	reflect.Uint16:        reflect.TypeOf(uint16(0)),
+	reflect.Uint32:        reflect.TypeOf(uint32(0)),
+	reflect.Uint64:        reflect.TypeOf(uint64(0)),
+	reflect.Uintptr:       reflect.TypeOf(uintptr(0)),
+	reflect.Float32:       reflect.TypeOf(float32(0)),
+	reflect.Float64:       reflect.TypeOf(float64(0)),
+	reflect.Complex64:     reflect.TypeOf(complex64(0)),
+	reflect.Complex128:    reflect.TypeOf(complex128(0)),
+	reflect.String:        reflect.TypeOf(""),
+	reflect.UnsafePointer: reflect.TypeOf(unsafe.Pointer(nil)),
+}
+
+// SynthesizeType builds a reflect.Type with the layout of the DWARF type name out of
+// reflect.StructOf, ArrayOf, MapOf and the like, for a type the compiler recorded no runtime
+// type of. The type is synthetic: it is unnamed, has no methods, and its unexported fields are
+// exported with their first letter in upper case. The types it refers to are the runtime ones
+// where they exist, a pointer back to a type being synthesized is an unsafe.Pointer. Function
+// types and non empty interfaces without a runtime type are not supported.
+func (da *dwarfAssembly) SynthesizeType(name string) (reflect.Type, error) {
+	var dwarfType godwarf.Type
+	var err error
+	name, _ = da.resolveName(name, func(name string) bool {
+		dwarfType, err = findType(da.binaryInfo, name)
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	typ, err := newTypeSynthesizer(da).synthesize(resolveTypedef(dwarfType))
+	if err != nil {
+		return nil, fmt.Errorf("synthesize %s: %w", name, err)
+	}
+	if da.tracing() {
+		da.trace(ResolveStep{Step: "synthesized", Name: name, Offset: dwarfType.Common().Offset, Detail: typ.String()})
+	}
+	return typ, nil
+}
+
+// typeSynthesizer builds the types of one SynthesizeType, visiting the types in progress.
+type typeSynthesizer struct {
+	da       *dwarfAssembly
+	visiting map[dwarf.Offset]bool
+}
+
+func newTypeSynthesizer(da *dwarfAssembly) *typeSynthesizer {
+	return &typeSynthesizer{da: da, visiting: make(map[dwarf.Offset]bool)}
+}
+
+// typeOf returns the runtime type of typ if it has one, otherwise synthesizes it.
+func (s *typeSynthesizer) typeOf(typ godwarf.Type) (reflect.Type, error) {
+	if name := typ.Common().Name; name != "" {
+		if typeAddr, _, err := s.da.dwarfToRuntimeType(typ, name); err == nil {
+			return runtimeType(typeAddr), nil
+		}
+	}
+	return s.synthesize(resolveTypedef(typ))
+}
+
+func (s *typeSynthesizer) synthesize(typ godwarf.Type) (reflect.Type, error) {
+	offset := typ.Common().Offset
+	if s.visiting[offset] {
+		return nil, fmt.Errorf("%s refers to itself other than through a pointer: %w", godwarfTypeName(typ), ErrNotSupport)
+	}
+	s.visiting[offset] = true
+	defer delete(s.visiting, offset)
+
+	switch t := typ.(type) {
+	case *godwarf.StructType:
+		return s.synthesizeStruct(t)
+	case *godwarf.ArrayType:
+		elem, err := s.typeOf(t.Type)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.ArrayOf(int(t.Count), elem), nil
+	case *godwarf.SliceType:
+		elem, err := s.typeOf(t.ElemType)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.SliceOf(elem), nil
+	case *godwarf.MapType:
+		key, err := s.typeOf(t.KeyType)
+		if err != nil {
+			return nil, err
+		}
+		elem, err := s.typeOf(t.ElemType)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.MapOf(key, elem), nil
+	case *godwarf.ChanType:
+		elem, err := s.typeOf(t.ElemType)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.ChanOf(reflect.BothDir, elem), nil
+	case *godwarf.PtrType:
+		if s.visiting[resolveTypedef(t.Type).Common().Offset] {
+			return basicTypes[reflect.UnsafePointer], nil
+		}
+		elem, err := s.typeOf(t.Type)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.PointerTo(elem), nil
+	case *godwarf.InterfaceType:
+		if strings.TrimSpace(t.Name) == "interface {}" || t.Name == "any" {
+			return reflect.TypeOf((*any)(nil)).Elem(), nil
+		}
+	}
+
+	if basic, ok := basicTypes[typ.Common().ReflectKind]; ok && basic.Size() == uintptr(typ.Size()) {
+		return basic, nil
+	}
+	return nil, fmt.Errorf("%s of kind %s has no runtime type: %w", godwarfTypeName(typ), typ.Common().ReflectKind, ErrNotSupport)
+}
+
+// synthesizeStruct builds the struct t, checking the fields land at the offsets DWARF records.
+func (s *typeSynthesizer) synthesizeStruct(t *godwarf.StructType) (reflect.Type, error) {
+	fields := make([]reflect.StructField, 0, len(t.Field))
+	names := make(map[string]bool, len(t.Field))
+	for _, field := range t.Field {
+		if field.BitSize != 0 {
+			return nil, fmt.Errorf("%s.%s is a bit field: %w", godwarfTypeName(t), field.Name, ErrNotSupport)
+		}
+		ftyp, err := s.typeOf(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", godwarfTypeName(t), field.Name, err)
+		}
+		name := exportedName(field.Name)
+		if names[name] {
+			return nil, fmt.Errorf("%s: field %s collides with exported %s: %w", godwarfTypeName(t), field.Name, name, ErrNotSupport)
+		}
+		names[name] = true
+		fields = append(fields, reflect.StructField{Name: name, Type: ftyp})
+	}
+
+	typ := reflect.StructOf(fields)
+	for i, field := range t.Field {
+		if offset := typ.Field(i).Offset; offset != uintptr(field.ByteOffset) {
+			return nil, fmt.Errorf("%s.%s: synthesized at offset %d, DWARF records %d", godwarfTypeName(t), field.Name, offset, field.ByteOffset)
+		}
+	}
+	if typ.Size() != uintptr(t.ByteSize) {
+		return nil, fmt.Errorf("%s: synthesized size %d, DWARF records %d", godwarfTypeName(t), typ.Size(), t.ByteSize)
+	}
+	return typ, nil
+}
+
+// exportedName returns name with its first letter in upper case, "_" as "X_".
+func exportedName(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	if unicode.IsUpper(r) {
+		return name
+	}
+	if !unicode.IsLetter(r) {
+		return "X" + name
+	}
+	return string(unicode.ToUpper(r)) + name[size:]
+}
@@ -0,0 +1,56 @@
+package assembly
+
+import (
+	"debug/dwarf"
+	"fmt"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// ResolveStep is one step taken by a lookup, reported to the tracer installed by SetTracer.
+type ResolveStep struct {
+	Step   string       // "candidate", "dwarf type", "runtime type", "fallback" or "chosen"
+	Name   string       // the name looked up by the step
+	Image  *proc.Image  // the image consulted, if any
+	Offset dwarf.Offset // the debug_info entry visited, if any
+	Detail string
+}
+
+func (s ResolveStep) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", s.Step, s.Name)
+	if s.Image != nil {
+		fmt.Fprintf(&b, " image=%s", s.Image.Path)
+	}
+	if s.Offset != 0 {
+		fmt.Fprintf(&b, " offset=%#x", s.Offset)
+	}
+	if s.Detail != "" {
+		fmt.Fprintf(&b, ": %s", s.Detail)
+	}
+	return b.String()
+}
+
+// SetTracer installs a function receiving the steps every lookup takes: the name candidates
+// tried, the images and DWARF entries consulted and the fallbacks used. A nil tracer disables tracing.
+func (da *dwarfAssembly) SetTracer(tracer func(step ResolveStep)) {
+	da.tracer = tracer
+}
+
+func (da *dwarfAssembly) tracing() bool {
+	return da.tracer != nil
+}
+
+func (da *dwarfAssembly) trace(step ResolveStep) {
+	if da.tracer != nil {
+		da.tracer(step)
+	}
+}
+
+// traceChosen reports the image a lookup picked among the images defining name.
+func (da *dwarfAssembly) traceChosen(name string, images []*proc.Image, chosen int) {
+	if da.tracing() {
+		da.trace(ResolveStep{Step: "chosen", Name: name, Image: images[chosen], Detail: fmt.Sprintf("%d of %d images", chosen+1, len(images))})
+	}
+}
@@ -0,0 +1,348 @@
+package assembly
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+
+	"github.com/go-delve/delve/pkg/proc"
+	"golang.org/x/arch/arm64/arm64asm"
+	"golang.org/x/arch/x86/x86asm"
+)
+
+// branchReach is how far a branch back to the entry of a function can be redirected to its trampoline,
+// the range of JMP rel32 on amd64 and B on arm64.
+var branchReach = map[string]uintptr{
+	"amd64": 1<<31 - 1,
+	"arm64": 1 << 27,
+}
+
+// codePatch is code written into a function, with the bytes it replaced.
+type codePatch struct {
+	pc       uintptr
+	code     []byte
+	original []byte
+}
+
+// trampoline runs the original of a patched function: the instructions the jump displaced,
+// relocated, followed by a jump to the first instruction kept in place. Branches of the function
+// back to its entry, the restart after its stack grew, are redirected to the trampoline as the
+// entry now jumps to the replacement.
+type trampoline struct {
+	addr     uintptr
+	restarts []codePatch
+}
+
+// buildTrampoline relocates the entry of f displaced by a jump of jumpSize bytes into executable
+// memory near f. It does not touch f, the restarts are written by PatchFunc.
+func (da *dwarfAssembly) buildTrampoline(f *proc.Function, jumpSize int) (*trampoline, error) {
+	arch := da.binaryInfo.Arch.Name
+	instructions, err := proc.Disassemble(new(localMemory), nil, &proc.BreakpointMap{}, da.binaryInfo, f.Entry, f.End)
+	if err != nil {
+		return nil, fmt.Errorf("disassemble %s: %w", f.Name, err)
+	}
+
+	var displaced int
+	var size int
+	for size < jumpSize && displaced < len(instructions) {
+		size += instructions[displaced].Size
+		displaced++
+	}
+	if size < jumpSize {
+		return nil, fmt.Errorf("%w: %s is shorter than the jump", ErrNotSupport, f.Name)
+	}
+	resume := f.Entry + uint64(size)
+
+	var targets = make(map[uint64]bool)
+	var restarts []int
+	for i := range instructions {
+		target, _, ok := branchTarget(arch, &instructions[i])
+		if !ok {
+			continue
+		}
+		targets[target] = true
+		if target > f.Entry && target < resume {
+			return nil, fmt.Errorf("%w: %s branches into its entry at %#x", ErrNotSupport, f.Name, target)
+		}
+		if target == f.Entry {
+			if i < displaced {
+				return nil, fmt.Errorf("%w: %s loops at its entry", ErrNotSupport, f.Name)
+			}
+			restarts = append(restarts, i)
+		}
+	}
+
+	var body []byte
+	switch arch {
+	case "amd64":
+		body, err = relocateAMD64(instructions[:displaced], resume)
+	case "arm64":
+		body, err = relocateARM64(instructions[:displaced], resume)
+	default:
+		err = fmt.Errorf("%w: trampolines on %s", ErrNotSupport, arch)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Name, err)
+	}
+
+	// On amd64 a short restart jump cannot reach the trampoline, the instructions before it
+	// are moved to a stub to make room for a long jump.
+	var moved = make([][]byte, len(restarts))
+	var total = len(body)
+	for n, i := range restarts {
+		if arch == "amd64" && instructions[i].Size < 5 {
+			if moved[n], err = movableTail(instructions, i, targets); err != nil {
+				return nil, fmt.Errorf("%s: %w", f.Name, err)
+			}
+			total += len(moved[n]) + len(absJumpAMD64(0))
+		}
+	}
+
+	var reach uintptr
+	if len(restarts) > 0 {
+		reach = branchReach[arch]
+	}
+	addr, err := allocCode(uintptr(f.Entry), total, reach)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Name, err)
+	}
+
+	t := &trampoline{addr: addr}
+	code := body
+	for n, i := range restarts {
+		inst := &instructions[i]
+		if arch == "arm64" {
+			decoded, err := arm64asm.Decode(inst.Bytes)
+			if err != nil || decoded.Op != arm64asm.B || len(decoded.Args) != 1 {
+				return nil, fmt.Errorf("%w: %s restarts with a conditional branch at %#x", ErrNotSupport, f.Name, inst.Loc.PC)
+			}
+			branch := binary.LittleEndian.AppendUint32(nil, 0x14000000|uint32((int64(addr)-int64(inst.Loc.PC))/4)&0x3ffffff)
+			t.restarts = append(t.restarts, codePatch{pc: uintptr(inst.Loc.PC), code: branch})
+			continue
+		}
+
+		decoded, err := x86asm.Decode(inst.Bytes, 64)
+		if err != nil || decoded.Op != x86asm.JMP {
+			return nil, fmt.Errorf("%w: %s restarts with a conditional branch at %#x", ErrNotSupport, f.Name, inst.Loc.PC)
+		}
+		pc, dest := uintptr(inst.Loc.PC), addr
+		if moved[n] != nil {
+			pc, dest = uintptr(inst.Loc.PC)+uintptr(inst.Size)-uintptr(len(moved[n])), addr+uintptr(len(code))
+			code = append(code, moved[n][:len(moved[n])-inst.Size]...)
+			code = append(code, absJumpAMD64(uint64(addr))...)
+		}
+		size := int(uintptr(inst.Loc.PC) + uintptr(inst.Size) - pc)
+		jump := append([]byte{0xe9}, binary.LittleEndian.AppendUint32(nil, uint32(int32(int64(dest)-int64(pc+5))))...)
+		for len(jump) < size {
+			jump = append(jump, 0xcc) // INT3, never reached
+		}
+		t.restarts = append(t.restarts, codePatch{pc: pc, code: jump})
+	}
+
+	if err = writeCode(addr, code); err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Name, err)
+	}
+	return t, nil
+}
+
+// movableTail returns the bytes of the instructions ending with the jump at index i that can be
+// moved away: up to the preceding control transfer or branch target, free of pc relative operands.
+func movableTail(instructions []proc.AsmInstruction, i int, targets map[uint64]bool) ([]byte, error) {
+	start := i
+	for start > 0 && !targets[instructions[start].Loc.PC] {
+		prev := &instructions[start-1]
+		if prev.IsCall() || prev.IsRet() || prev.IsJmp() || !positionIndependentAMD64(prev.Bytes) {
+			break
+		}
+		if _, _, ok := branchTarget("amd64", prev); ok {
+			break
+		}
+		start--
+	}
+
+	var moved []byte
+	for j := start; j <= i; j++ {
+		moved = append(moved, instructions[j].Bytes...)
+	}
+	if len(moved) < 5 {
+		return nil, fmt.Errorf("%w: no room to redirect the restart at %#x", ErrNotSupport, instructions[i].Loc.PC)
+	}
+	return moved, nil
+}
+
+// positionIndependentAMD64 reports whether the instruction does not address memory relative to the pc.
+func positionIndependentAMD64(code []byte) bool {
+	decoded, err := x86asm.Decode(code, 64)
+	if err != nil {
+		return false
+	}
+	for _, arg := range decoded.Args {
+		if mem, ok := arg.(x86asm.Mem); ok && mem.Base == x86asm.RIP {
+			return false
+		}
+		if _, ok := arg.(x86asm.Rel); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// absJumpAMD64 encodes JMP *0(PC) followed by the destination, clobbering no register.
+func absJumpAMD64(dest uint64) []byte {
+	return binary.LittleEndian.AppendUint64([]byte{0xff, 0x25, 0, 0, 0, 0}, dest)
+}
+
+// relocateAMD64 copies the displaced instructions, replacing jumps by absolute jumps and
+// conditional jumps by long forms targeting absolute jumps appended after the body.
+func relocateAMD64(displaced []proc.AsmInstruction, resume uint64) ([]byte, error) {
+	type stub struct {
+		field int
+		dest  uint64
+	}
+	var code []byte
+	var stubs []stub
+	entry := displaced[0].Loc.PC
+	for i := range displaced {
+		inst := &displaced[i]
+		decoded, err := x86asm.Decode(inst.Bytes, 64)
+		if err != nil {
+			return nil, fmt.Errorf("decode at %#x: %w", inst.Loc.PC, err)
+		}
+		for _, arg := range decoded.Args {
+			if mem, ok := arg.(x86asm.Mem); ok && mem.Base == x86asm.RIP {
+				return nil, fmt.Errorf("%w: pc relative operand at %#x", ErrNotSupport, inst.Loc.PC)
+			}
+		}
+		rel, ok := decoded.Args[0].(x86asm.Rel)
+		if !ok {
+			code = append(code, inst.Bytes...)
+			continue
+		}
+
+		dest := uint64(int64(inst.Loc.PC) + int64(decoded.Len) + int64(rel))
+		if dest >= entry && dest < resume {
+			return nil, fmt.Errorf("%w: branch within the displaced entry at %#x", ErrNotSupport, inst.Loc.PC)
+		}
+		var cond byte
+		switch {
+		case decoded.Op == x86asm.JMP:
+			code = append(code, absJumpAMD64(dest)...)
+			continue
+		case inst.Bytes[0]&0xf0 == 0x70:
+			cond = inst.Bytes[0] & 0xf
+		case len(inst.Bytes) > 1 && inst.Bytes[0] == 0x0f && inst.Bytes[1]&0xf0 == 0x80:
+			cond = inst.Bytes[1] & 0xf
+		default:
+			return nil, fmt.Errorf("%w: relocating %s at %#x", ErrNotSupport, decoded.Op, inst.Loc.PC)
+		}
+		code = append(code, 0x0f, 0x80|cond, 0, 0, 0, 0)
+		stubs = append(stubs, stub{field: len(code) - 4, dest: dest})
+	}
+
+	code = append(code, absJumpAMD64(resume)...)
+	for _, s := range stubs {
+		binary.LittleEndian.PutUint32(code[s.field:], uint32(len(code)-(s.field+4)))
+		code = append(code, absJumpAMD64(s.dest)...)
+	}
+	return code, nil
+}
+
+// appendAbsJumpARM64 appends LDR 8(PC), R27; B (R27) followed by the 8 byte aligned destination.
+// R27 is the assembler temporary, it holds no value across instructions.
+func appendAbsJumpARM64(code []byte, dest uint64) []byte {
+	if len(code)%8 != 0 {
+		code = binary.LittleEndian.AppendUint32(code, 0xd503201f) // NOP
+	}
+	code = binary.LittleEndian.AppendUint32(code, 0x5800005b)
+	code = binary.LittleEndian.AppendUint32(code, 0xd61f0360)
+	return binary.LittleEndian.AppendUint64(code, dest)
+}
+
+// relocateARM64 copies the displaced instructions, replacing branches by absolute jumps and
+// retargeting conditional branches to absolute jumps appended after the body.
+func relocateARM64(displaced []proc.AsmInstruction, resume uint64) ([]byte, error) {
+	type stub struct {
+		at    int
+		field uint32 // mask of the offset field, in instructions, shifted by 5
+		dest  uint64
+	}
+	var code []byte
+	var stubs []stub
+	entry := displaced[0].Loc.PC
+	for i := range displaced {
+		inst := &displaced[i]
+		decoded, err := arm64asm.Decode(inst.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("decode at %#x: %w", inst.Loc.PC, err)
+		}
+		var dest uint64
+		var relative, conditional bool
+		for _, arg := range decoded.Args {
+			switch arg := arg.(type) {
+			case arm64asm.PCRel:
+				dest, relative = uint64(int64(inst.Loc.PC)+int64(arg)), true
+			case arm64asm.Cond:
+				conditional = true
+			}
+		}
+		if !relative {
+			code = append(code, inst.Bytes...)
+			continue
+		}
+		if dest >= entry && dest < resume {
+			return nil, fmt.Errorf("%w: branch within the displaced entry at %#x", ErrNotSupport, inst.Loc.PC)
+		}
+
+		var field uint32
+		switch {
+		case decoded.Op == arm64asm.B && !conditional:
+			code = appendAbsJumpARM64(code, dest)
+			continue
+		case decoded.Op == arm64asm.B, decoded.Op == arm64asm.CBZ, decoded.Op == arm64asm.CBNZ:
+			field = 0x7ffff << 5
+		case decoded.Op == arm64asm.TBZ, decoded.Op == arm64asm.TBNZ:
+			field = 0x3fff << 5
+		default:
+			return nil, fmt.Errorf("%w: relocating %s at %#x", ErrNotSupport, decoded.Op, inst.Loc.PC)
+		}
+		stubs = append(stubs, stub{at: len(code), field: field, dest: dest})
+		code = append(code, inst.Bytes...)
+	}
+
+	code = appendAbsJumpARM64(code, resume)
+	for _, s := range stubs {
+		if len(code)%8 != 0 {
+			code = binary.LittleEndian.AppendUint32(code, 0xd503201f) // NOP
+		}
+		word := binary.LittleEndian.Uint32(code[s.at:])
+		word = word&^s.field | uint32((len(code)-s.at)/4)<<5&s.field
+		binary.LittleEndian.PutUint32(code[s.at:], word)
+		code = appendAbsJumpARM64(code, s.dest)
+	}
+	return code, nil
+}
+
+// Original returns the original implementation of the patched function name, for its replacement
+// to delegate to. It runs on a trampoline holding the relocated entry of the function and stays
+// callable after the patch is reverted. Functions capturing variables have no original, the
+// value creating them no longer exists.
+func (da *dwarfAssembly) Original(name string) (reflect.Value, error) {
+	f, err := da.findFunc(name)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	patches.mu.Lock()
+	defer patches.mu.Unlock()
+	p, ok := patches.active[f.Entry]
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("%s is not patched: %w", name, ErrNotFound)
+	}
+	if p.trampolineErr != nil {
+		return reflect.Value{}, fmt.Errorf("no original: %w", p.trampolineErr)
+	}
+	if !p.original.IsValid() {
+		p.original = CreateFuncForCodePtr(p.replacement.Type(), uint64(p.trampoline.addr))
+	}
+	return p.original, nil
+}
@@ -0,0 +1,140 @@
+package assembly
+
+import (
+	"debug/dwarf"
+	"fmt"
+
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// TypeMismatch is a layout difference of a type between two images. Path is the type or the
+// field differing, e.g. "orders.Order.Total" or "orders.Order.Lines[]", A and B describe it in
+// either image, empty where an image lacks the field.
+type TypeMismatch struct {
+	Path string
+	Kind string // "size", "align", "kind", "type", "offset" or "field"
+	A, B string
+}
+
+func (m TypeMismatch) String() string {
+	return fmt.Sprintf("%s %s: %q != %q", m.Path, m.Kind, m.A, m.B)
+}
+
+// CheckTypeCompatibility compares the layout of the type name as the DWARF of imgA and imgB
+// describes it: sizes, alignments and the names, offsets, sizes and types of the fields, of
+// nested structs and array elements too. Values of a type with mismatches cannot be shared
+// between code of both images, e.g. by a hotfix plugin patching functions of the host.
+func (da *dwarfAssembly) CheckTypeCompatibility(name string, imgA, imgB *proc.Image) ([]TypeMismatch, error) {
+	a, err := da.imageDwarfType(imgA, name)
+	if err != nil {
+		return nil, err
+	}
+	b, err := da.imageDwarfType(imgB, name)
+	if err != nil {
+		return nil, err
+	}
+	var mismatches []TypeMismatch
+	compareLayouts(&mismatches, name, a, b, make(map[[2]dwarf.Offset]bool))
+	return mismatches, nil
+}
+
+// imageDwarfType reads the DWARF type name defined by img, delve only indexes the first image
+// defining a name.
+func (da *dwarfAssembly) imageDwarfType(img *proc.Image, name string) (godwarf.Type, error) {
+	if img == nil {
+		return nil, fmt.Errorf("type %s: no image: %w", name, ErrNotFound)
+	}
+	if err := da.imageStale(img); err != nil {
+		return nil, err
+	}
+
+	reader := da.acquireReader(img)
+	defer da.releaseReader(img, reader)
+	reader.Seek(0)
+	for {
+		entry, err := reader.Next()
+		if err != nil {
+			return nil, fmt.Errorf("DWARF read error: %s: %w", img.Path, err)
+		}
+		if entry == nil {
+			break
+		}
+		switch entry.Tag {
+		case dwarf.TagCompileUnit:
+			continue
+		case dwarf.TagTypedef, dwarf.TagStructType, dwarf.TagBaseType, dwarf.TagPointerType, dwarf.TagArrayType:
+			if entryName, _ := entry.Val(dwarf.AttrName).(string); entryName == name {
+				return img.Type(entry.Offset)
+			}
+		}
+		if entry.Children {
+			reader.SkipChildren()
+		}
+	}
+	return nil, fmt.Errorf("type %s in %s: %w", name, img.Path, ErrNotFound)
+}
+
+// compareLayouts appends the differences of the layouts of a and b at path to mismatches,
+// visited holds the struct pairs compared already.
+func compareLayouts(mismatches *[]TypeMismatch, path string, a, b godwarf.Type, visited map[[2]dwarf.Offset]bool) {
+	mismatch := func(kind string, a, b any) {
+		*mismatches = append(*mismatches, TypeMismatch{Path: path, Kind: kind, A: fmt.Sprint(a), B: fmt.Sprint(b)})
+	}
+	a, b = resolveTypedef(a), resolveTypedef(b)
+	if a.Common().ReflectKind != b.Common().ReflectKind {
+		mismatch("kind", a.Common().ReflectKind, b.Common().ReflectKind)
+		return
+	}
+	if a.Size() != b.Size() {
+		mismatch("size", a.Size(), b.Size())
+	}
+	if a.Align() != b.Align() {
+		mismatch("align", a.Align(), b.Align())
+	}
+
+	switch ta := a.(type) {
+	case *godwarf.StructType:
+		tb, ok := b.(*godwarf.StructType)
+		if !ok {
+			mismatch("type", godwarfTypeName(a), godwarfTypeName(b))
+			return
+		}
+		key := [2]dwarf.Offset{ta.Offset, tb.Offset}
+		if visited[key] {
+			return
+		}
+		visited[key] = true
+
+		fields := make(map[string]*godwarf.StructField, len(tb.Field))
+		for _, field := range tb.Field {
+			fields[field.Name] = field
+		}
+		for _, fa := range ta.Field {
+			fieldPath := path + "." + fa.Name
+			fb, ok := fields[fa.Name]
+			if !ok {
+				*mismatches = append(*mismatches, TypeMismatch{Path: fieldPath, Kind: "field", A: godwarfTypeName(fa.Type)})
+				continue
+			}
+			delete(fields, fa.Name)
+			if fa.ByteOffset != fb.ByteOffset {
+				*mismatches = append(*mismatches, TypeMismatch{Path: fieldPath, Kind: "offset", A: fmt.Sprint(fa.ByteOffset), B: fmt.Sprint(fb.ByteOffset)})
+			}
+			if nameA, nameB := godwarfTypeName(fa.Type), godwarfTypeName(fb.Type); nameA != nameB {
+				*mismatches = append(*mismatches, TypeMismatch{Path: fieldPath, Kind: "type", A: nameA, B: nameB})
+			} else {
+				compareLayouts(mismatches, fieldPath, fa.Type, fb.Type, visited)
+			}
+		}
+		for _, fb := range tb.Field {
+			if _, ok := fields[fb.Name]; ok {
+				*mismatches = append(*mismatches, TypeMismatch{Path: path + "." + fb.Name, Kind: "field", B: godwarfTypeName(fb.Type)})
+			}
+		}
+	case *godwarf.ArrayType:
+		if tb, ok := b.(*godwarf.ArrayType); ok {
+			compareLayouts(mismatches, path+"[]", ta.Type, tb.Type, visited)
+		}
+	}
+}
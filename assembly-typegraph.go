@@ -0,0 +1,119 @@
+package assembly
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
+)
+
+// TypeGraph is the DWARF description of the types declared by a package and of every type they
+// reach through elements, keys, fields and underlying types. Nodes refer to each other by name,
+// so the graph holds only plain values and can be encoded with encoding/json or encoding/gob.
+type TypeGraph struct {
+	Package string
+	Roots   []string   // names of the types declared by Package, sorted
+	Types   []TypeNode // every type of the graph, sorted by name
+}
+
+// TypeNode is a type of a TypeGraph.
+type TypeNode struct {
+	Name string
+	Kind reflect.Kind
+	Size int64
+	// Elem is the element of pointers, slices, arrays, channels and maps, and the underlying
+	// type of a type defined from another named type.
+	Elem   string
+	Key    string // key of maps
+	Len    int64  // length of arrays
+	Fields []TypeField
+}
+
+// TypeField is a field of a struct TypeNode.
+type TypeField struct {
+	Name     string
+	Type     string
+	Offset   int64
+	Embedded bool
+}
+
+// Node returns the node of the type name, nil if the graph does not hold it.
+func (g *TypeGraph) Node(name string) *TypeNode {
+	i := sort.Search(len(g.Types), func(i int) bool { return g.Types[i].Name >= name })
+	if i < len(g.Types) && g.Types[i].Name == name {
+		return &g.Types[i]
+	}
+	return nil
+}
+
+// TypeGraph extracts the type graph of the package pkg from DWARF. Types defined by several
+// images are described as the first image loaded defines them.
+func (da *dwarfAssembly) TypeGraph(pkg string) (*TypeGraph, error) {
+	names, err := da.binaryInfo.Types()
+	if err != nil {
+		return nil, err
+	}
+
+	graph := &TypeGraph{Package: pkg}
+	for _, name := range names {
+		if symbolPackage(name) == pkg {
+			graph.Roots = append(graph.Roots, name)
+		}
+	}
+	if len(graph.Roots) == 0 {
+		return nil, fmt.Errorf("types of package %s: %w", pkg, ErrNotFound)
+	}
+	sort.Strings(graph.Roots)
+
+	nodes := make(map[string]bool)
+	var visit func(typ godwarf.Type) string
+	visit = func(typ godwarf.Type) string {
+		name := godwarfTypeName(typ)
+		// delve wraps the definition of a named type in a typedef of the same name
+		for {
+			typedef, ok := typ.(*godwarf.TypedefType)
+			if !ok || godwarfTypeName(typedef.Type) != name {
+				break
+			}
+			typ = typedef.Type
+		}
+		if nodes[name] {
+			return name
+		}
+		nodes[name] = true
+		node := TypeNode{Name: name, Kind: typ.Common().ReflectKind, Size: typ.Size()}
+
+		switch typ := typ.(type) {
+		case *godwarf.TypedefType:
+			node.Elem = visit(typ.Type)
+		case *godwarf.PtrType:
+			node.Elem = visit(typ.Type)
+		case *godwarf.SliceType:
+			node.Elem = visit(typ.ElemType)
+		case *godwarf.ArrayType:
+			node.Elem, node.Len = visit(typ.Type), typ.Count
+		case *godwarf.ChanType:
+			node.Elem = visit(typ.ElemType)
+		case *godwarf.MapType:
+			node.Key, node.Elem = visit(typ.KeyType), visit(typ.ElemType)
+		case *godwarf.StructType:
+			for _, field := range typ.Field {
+				node.Fields = append(node.Fields, TypeField{Name: field.Name, Type: visit(field.Type), Offset: field.ByteOffset, Embedded: field.Embedded})
+			}
+		}
+		graph.Types = append(graph.Types, node)
+		return name
+	}
+
+	for _, name := range graph.Roots {
+		typ, err := findType(da.binaryInfo, name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		visit(typ)
+	}
+
+	sort.Slice(graph.Types, func(i, j int) bool { return graph.Types[i].Name < graph.Types[j].Name })
+	return graph, nil
+}
@@ -1,9 +1,13 @@
 package assembly
 
 import (
+	"context"
 	"debug/dwarf"
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"sync"
 	"unsafe"
 
 	"github.com/go-delve/delve/pkg/dwarf/godwarf"
@@ -11,120 +15,305 @@ import (
 )
 
 func (da *dwarfAssembly) ForeachType(f func(name string) bool) error {
-	types, err := da.binaryInfo.Types()
+	return da.ForeachTypeContext(context.Background(), f)
+}
+
+// FindType resolves the runtime type of name. A type synthesized by TypeFallbackSynthesize is
+// returned together with an error wrapping ErrSyntheticType: it has the layout of the DWARF type
+// but is not the type of the program, values of it do not convert to or compare with it.
+func (da *dwarfAssembly) FindType(name string) (reflect.Type, error) {
+	typ, _, err := da.ResolveType(name)
+	return typ, err
+}
+
+// layoutType is FindType accepting a synthetic type, for callers that only need the memory
+// layout of name, such as the arguments of a call or the value of a global.
+func (da *dwarfAssembly) layoutType(name string) (reflect.Type, error) {
+	typ, err := da.FindType(name)
+	if errors.Is(err, ErrSyntheticType) {
+		err = nil
+	}
+	return typ, err
+}
+
+func (da *dwarfAssembly) ResolveType(name string) (reflect.Type, *proc.Image, error) {
+	return da.resolveType(name, da.policy.Priority)
+}
+
+// resolveType is ResolveType preferring the images of priority over the resolve order.
+func (da *dwarfAssembly) resolveType(name string, priority []string) (reflect.Type, *proc.Image, error) {
+	var dwarfType godwarf.Type
+	var err error
+	name, _ = da.resolveName(name, func(name string) bool {
+		dwarfType, err = findType(da.binaryInfo, name)
+		return err == nil
+	})
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	for _, name := range types {
-		if !f(name) {
-			break
+	if da.tracing() && dwarfType.Common().Index < len(da.binaryInfo.Images) {
+		da.trace(ResolveStep{Step: "dwarf type", Name: name, Image: da.binaryInfo.Images[dwarfType.Common().Index], Offset: dwarfType.Common().Offset})
+	}
+
+	typeAddr, img, err := da.dwarfToRuntimeType(dwarfType, name)
+	if err != nil && da.policy.TypeFallback == TypeFallbackSynthesize {
+		if typ, synthErr := newTypeSynthesizer(da).synthesize(resolveTypedef(dwarfType)); synthErr == nil {
+			da.trace(ResolveStep{Step: "synthesized", Name: name, Offset: dwarfType.Common().Offset, Detail: typ.String()})
+			return typ, nil, fmt.Errorf("type:%s: %w", name, ErrSyntheticType)
 		}
 	}
-	return nil
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = da.imageStale(img); err != nil {
+		return nil, nil, err
+	}
+
+	if da.policy.Order != ResolveDefault || len(priority) > 0 {
+		typeAddr, img = da.preferredType(name, typeAddr, img, priority)
+	}
+
+	return runtimeType(typeAddr), img, nil
 }
 
-func (da *dwarfAssembly) FindType(name string) (reflect.Type, error) {
-	dwarfType, err := findType(da.binaryInfo, name)
-	if err != nil {
-		return nil, err
+func (da *dwarfAssembly) ForeachImageType(image *proc.Image, f func(name string, typ reflect.Type) bool) {
+	if da.imageStale(image) != nil {
+		return
 	}
+	types := da.imageTypeCache(image)
 
-	typeAddr, err := da.dwarfToRuntimeType(dwarfType, name)
-	if err != nil {
-		return nil, err
+	visible := da.visibleFilter()
+	names := make([]string, 0, len(types))
+	for name := range types {
+		if visible(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !f(da.displayName(name), runtimeType(types[name])) {
+			break
+		}
 	}
+}
 
-	typ := reflect.TypeOf(*(*interface{})(unsafe.Pointer(&typeAddr)))
-	return typ, nil
+// runtimeType converts the address of a runtime type descriptor into a reflect.Type.
+func runtimeType(typeAddr uint64) reflect.Type {
+	return reflect.TypeOf(*(*interface{})(unsafe.Pointer(&typeAddr)))
+}
+
+// preferredType collects the runtime types other images registered under name and picks one by
+// policy, with priority in place of the priority of the policy.
+func (da *dwarfAssembly) preferredType(name string, typeAddr uint64, img *proc.Image, priority []string) (uint64, *proc.Image) {
+	images := []*proc.Image{img}
+	addrs := []uint64{typeAddr}
+	for _, image := range da.binaryInfo.Images {
+		if image == img || da.imageStale(image) != nil {
+			continue
+		}
+		if addr := da.findImageType(image, name); addr != 0 {
+			images = append(images, image)
+			addrs = append(addrs, addr)
+		}
+	}
+
+	chosen := da.preferredImageOf(images, 0, priority)
+	da.traceChosen(name, images, chosen)
+	return addrs[chosen], images[chosen]
+}
+
+// imageTypeCache holds the runtime types registered by one image, built exactly once.
+type imageTypeCache struct {
+	once  sync.Once
+	types map[string]uint64
 }
 
 func (da *dwarfAssembly) findImageType(img *proc.Image, name string) uint64 {
+	return da.imageTypeCache(img)[name]
+}
+
+// imageTypeCache returns the runtime types of img by name, building them on first use.
+// Concurrent callers for the same image wait for a single build.
+func (da *dwarfAssembly) imageTypeCache(img *proc.Image) map[string]uint64 {
+	return da.imageTypeCacheOf(img, da.modules)
+}
+
+// imageTypeCacheOf is imageTypeCache locating the types of img in modules, a background build is
+// given a snapshot of the module data, LoadImage replaces da.modules.
+func (da *dwarfAssembly) imageTypeCacheOf(img *proc.Image, modules []ModuleData) map[string]uint64 {
+	da.typesMu.Lock()
 	if da.imageTypes == nil {
-		da.imageTypes = make(map[*proc.Image]map[string]uint64)
+		da.imageTypes = make(map[*proc.Image]*imageTypeCache)
 	}
 	cache, ok := da.imageTypes[img]
 	if !ok {
-		cache = make(map[string]uint64)
+		cache = &imageTypeCache{}
 		da.imageTypes[img] = cache
+	}
+	da.typesMu.Unlock()
+
+	cache.once.Do(func() {
+		cache.types = da.indexedImageTypes(img, modules)
+	})
+	return cache.types
+}
+
+// warmImageTypes builds the runtime type cache of img in the background. Images are loaded and
+// unloaded once the background builds finished, delve is not safe for concurrent use.
+func (da *dwarfAssembly) warmImageTypes(img *proc.Image) {
+	modules := da.modules
+	da.background.Add(1)
+	go func() {
+		defer da.background.Done()
+		da.imageTypeCacheOf(img, modules)
+	}()
+}
 
-		reader := img.DwarfReader()
-		md := imageToModuleData(da.binaryInfo, img, da.modules)
-		if md == nil {
-			return 0
+// indexedImageTypes returns the runtime types of img from the index cache, loading and
+// storing them if the cache has none.
+func (da *dwarfAssembly) indexedImageTypes(img *proc.Image, modules []ModuleData) map[string]uint64 {
+	if index := da.persistedIndex(img); index != nil && index.Types != nil {
+		types := make(map[string]uint64, len(index.Types))
+		for name, addr := range index.Types {
+			types[da.intern(name)] = img.StaticBase + addr
 		}
+		return types
+	}
 
-		rRuntimeTypes := reflect.ValueOf(img).Elem().FieldByName("runtimeTypeToDIE")
-		iter := rRuntimeTypes.MapRange()
-		for iter.Next() {
-			k := iter.Key()
-			v := iter.Value()
-
-			offset := v.FieldByName("offset").Uint()
-			reader.Seek(dwarf.Offset(offset))
-			entry, err := reader.Next()
-			if err != nil || entry == nil {
-				continue
-			}
-			entryName, ok := entry.Val(dwarf.AttrName).(string)
-			if !ok {
-				continue
-			}
-			if k.Uint() == 0 {
-				continue
+	types := da.loadImageTypes(img, modules)
+	if len(types) > 0 {
+		da.storeIndex(img, func(index *persistedIndex) {
+			index.Types = make(map[string]uint64, len(types))
+			for name, addr := range types {
+				index.Types[name] = addr - img.StaticBase
 			}
+		})
+	}
+	return types
+}
 
-			typeAddr := md.types + k.Uint()
-			if typeAddr < md.types || typeAddr >= md.etypes {
-				cache[entryName] = img.StaticBase + k.Uint()
-			} else {
-				cache[entryName] = typeAddr
-			}
+func (da *dwarfAssembly) loadImageTypes(img *proc.Image, modules []ModuleData) map[string]uint64 {
+	cache := make(map[string]uint64)
+	if !imageLoaded(img) {
+		return cache
+	}
+
+	md := imageToModuleData(da.binaryInfo, img, modules)
+	if md == nil {
+		return cache
+	}
+
+	// The entries are read in the order of their offsets, each seek then moves the reader
+	// forward through the compile units instead of jumping back and forth.
+	type typeDIE struct {
+		off    uint64
+		offset dwarf.Offset
+	}
+	rRuntimeTypes := reflect.ValueOf(img).Elem().FieldByName("runtimeTypeToDIE")
+	dies := make([]typeDIE, 0, rRuntimeTypes.Len())
+	iter := rRuntimeTypes.MapRange()
+	for iter.Next() {
+		if off := iter.Key().Uint(); off != 0 {
+			dies = append(dies, typeDIE{off: off, offset: dwarf.Offset(iter.Value().FieldByName("offset").Uint())})
+		}
+	}
+	sort.Slice(dies, func(i, j int) bool { return dies[i].offset < dies[j].offset })
+
+	reader := da.acquireReader(img)
+	defer da.releaseReader(img, reader)
+	for _, die := range dies {
+		reader.Seek(die.offset)
+		entry, err := reader.Next()
+		if err != nil || entry == nil {
+			continue
+		}
+		entryName, ok := entry.Val(dwarf.AttrName).(string)
+		if !ok {
+			continue
+		}
+
+		entryName = da.intern(entryName)
+		typeAddr := md.types + die.off
+		if typeAddr < md.types || typeAddr >= md.etypes {
+			cache[entryName] = img.StaticBase + die.off
+		} else {
+			cache[entryName] = typeAddr
 		}
 	}
 
-	return cache[name]
+	return cache
 }
 
-func (da *dwarfAssembly) dwarfToRuntimeType(typ godwarf.Type, name string) (typeAddr uint64, err error) {
+// fallbackRuntimeType finds the runtime type of name in the libraries, according to the type
+// fallback of the resolve policy, when img defines the DWARF type without a runtime type.
+func (da *dwarfAssembly) fallbackRuntimeType(img *proc.Image, name string) (uint64, *proc.Image, error) {
+	if da.policy.TypeFallback == TypeFallbackStrict {
+		return 0, nil, fmt.Errorf("%w: %s records no runtime type for type:%s", ErrNotFound, img.Path, name)
+	}
+
+	var images []*proc.Image
+	var addrs []uint64
+	for i, img := range da.binaryInfo.Images {
+		if i == 0 {
+			continue
+		}
+		addr := da.findImageType(img, name)
+		if addr == 0 {
+			da.trace(ResolveStep{Step: "fallback", Name: name, Image: img, Detail: "no runtime type"})
+			continue
+		}
+		da.trace(ResolveStep{Step: "fallback", Name: name, Image: img, Detail: "runtime type found"})
+		if da.policy.TypeFallback == TypeFallbackScan || da.policy.TypeFallback == TypeFallbackSynthesize {
+			return addr, img, nil
+		}
+		images = append(images, img)
+		addrs = append(addrs, addr)
+	}
+	if len(images) == 0 {
+		return 0, nil, fmt.Errorf("could not find runtime type for type:%s", name)
+	}
+
+	chosen := da.preferredImage(images, 0)
+	da.traceChosen(name, images, chosen)
+	return addrs[chosen], images[chosen], nil
+}
+
+func (da *dwarfAssembly) dwarfToRuntimeType(typ godwarf.Type, name string) (typeAddr uint64, typeImage *proc.Image, err error) {
 	bi := da.binaryInfo
 	mds := da.modules
 
 	if typ.Common().Index >= len(bi.Images) {
-		return 0, fmt.Errorf("could not find image for type %s", name)
+		return 0, nil, fmt.Errorf("could not find image for type %s", name)
 	}
 	img := bi.Images[typ.Common().Index]
-	rdr := img.DwarfReader()
+	rdr := da.acquireReader(img)
 	rdr.Seek(typ.Common().Offset)
 	e, err := rdr.Next()
+	da.releaseReader(img, rdr)
 	if err != nil {
-		return 0, fmt.Errorf("could not find dwarf entry for type:%s err:%s", name, err)
+		return 0, nil, fmt.Errorf("could not find dwarf entry for type:%s err:%s", name, err)
 	}
 	entryName, ok := e.Val(dwarf.AttrName).(string)
 	if !ok || entryName != name {
-		return 0, fmt.Errorf("could not find name for type:%s entry:%s", name, entryName)
+		return 0, nil, fmt.Errorf("could not find name for type:%s entry:%s", name, entryName)
 	}
 	off, ok := e.Val(godwarf.AttrGoRuntimeType).(uint64)
 	if !ok || off == 0 {
-		for i, img := range bi.Images {
-			if i == 0 {
-				continue
-			}
-			addr := da.findImageType(img, name)
-			if addr != 0 {
-				return addr, nil
-			}
-		}
-		return 0, fmt.Errorf("could not find runtime type for type:%s", name)
+		return da.fallbackRuntimeType(img, name)
 	}
 
 	md := imageToModuleData(bi, img, mds)
 	if md == nil {
-		return 0, fmt.Errorf("could not find module data for type %s", name)
+		return 0, nil, fmt.Errorf("could not find module data for type %s", name)
 	}
 
 	typeAddr = md.types + off
 	if typeAddr < md.types || typeAddr >= md.etypes {
-		return img.StaticBase + off, nil
+		typeAddr = img.StaticBase + off
+	}
+	if da.tracing() {
+		da.trace(ResolveStep{Step: "runtime type", Name: name, Image: img, Offset: typ.Common().Offset, Detail: fmt.Sprintf("%#x", typeAddr)})
 	}
-	return typeAddr, nil
+	return typeAddr, img, nil
 }
@@ -0,0 +1,133 @@
+package assembly
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"unsafe"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// UnloadImage removes the image loaded from path: its functions and package variables leave
+// the indexes, its caches are dropped and function values created for it report
+// ErrImageUnloaded. The main executable cannot be unloaded. An image is in use, and unloading
+// it fails with ErrImageInUse, while handles reference it or a patch targets or jumps into it.
+//
+// The image keeps its slot in BinaryInfo().Images, the DWARF of the other images refers to
+// images by index, but no symbol resolves to it anymore.
+func (da *dwarfAssembly) UnloadImage(path string) error {
+	img := da.findImage(path)
+	if img == nil {
+		return fmt.Errorf("%s: %w", path, ErrNotFound)
+	}
+	if da.imageIndex(img) == 0 {
+		return fmt.Errorf("%s: main executable: %w", path, ErrNotSupport)
+	}
+	if n := da.References(img); n > 0 {
+		return fmt.Errorf("%s: %d handles: %w", path, n, ErrImageInUse)
+	}
+	if active := da.patchesIn(img); len(active) > 0 {
+		return fmt.Errorf("%s: patch of %s: %w", path, active[0].Site.Target, ErrImageInUse)
+	}
+	return da.unloadImage(img)
+}
+
+// UnloadImageForce unloads the image loaded from path like UnloadImage, reverting the patches
+// targeting or jumping into it first. It fails with ErrImageInUse while handles reference the
+// image, or if a patch cannot be reverted, the patches reverted before stay reverted.
+func (da *dwarfAssembly) UnloadImageForce(path string) error {
+	img := da.findImage(path)
+	if img == nil {
+		return fmt.Errorf("%s: %w", path, ErrNotFound)
+	}
+	if da.imageIndex(img) == 0 {
+		return fmt.Errorf("%s: main executable: %w", path, ErrNotSupport)
+	}
+	if n := da.References(img); n > 0 {
+		return fmt.Errorf("%s: %d handles: %w", path, n, ErrImageInUse)
+	}
+	for _, p := range da.patchesIn(img) {
+		if err := p.Revert(); err != nil {
+			return fmt.Errorf("%s: revert patch: %w: %v", path, ErrImageInUse, err)
+		}
+	}
+	return da.unloadImage(img)
+}
+
+func (da *dwarfAssembly) unloadImage(img *proc.Image) error {
+	da.background.Wait()
+	da.retireImage(img)
+	da.removeIndexes(img)
+	da.resetReaders(img)
+	return img.Close()
+}
+
+// findImage returns the loaded image of path, nil if none is.
+func (da *dwarfAssembly) findImage(path string) *proc.Image {
+	for _, img := range da.binaryInfo.Images {
+		if da.imageStale(img) == nil && (img.Path == path || sameFile(img.Path, path)) {
+			return img
+		}
+	}
+	return nil
+}
+
+// patchesIn returns the active patches whose target or replacement lives in img, by target address.
+func (da *dwarfAssembly) patchesIn(img *proc.Image) []*Patch {
+	patches.mu.Lock()
+	defer patches.mu.Unlock()
+	var active []*Patch
+	for _, p := range patches.active {
+		if da.binaryInfo.PCToImage(p.Site.TargetPC) == img || da.binaryInfo.PCToImage(p.Site.ReplacementPC) == img {
+			active = append(active, p)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].Site.TargetPC < active[j].Site.TargetPC })
+	return active
+}
+
+// removeIndexes replaces the function and package variable indexes of delve with copies
+// lacking the entries of img, snapshots keep the previous ones.
+func (da *dwarfAssembly) removeIndexes(img *proc.Image) {
+	da.indexMu.Lock()
+	defer da.indexMu.Unlock()
+
+	functions := make([]proc.Function, 0, len(da.binaryInfo.Functions))
+	for i := range da.binaryInfo.Functions {
+		if funcToImage(da.binaryInfo, &da.binaryInfo.Functions[i]) != img {
+			functions = append(functions, da.binaryInfo.Functions[i])
+		}
+	}
+	da.binaryInfo.Functions = functions
+
+	// The name lookups of delve point into the previous slice, they are rebuilt on demand.
+	for _, name := range []string{"lookupFunc", "lookupGenericFunc"} {
+		if field := unexportedField(da.binaryInfo, name); field.IsValid() {
+			field.Set(reflect.Zero(field.Type()))
+		}
+	}
+
+	if field := packageVarsField(da.binaryInfo); field.IsValid() {
+		vars := reflect.MakeSlice(field.Type(), 0, field.Len())
+		for i := 0; i < field.Len(); i++ {
+			if varImage(field.Index(i)) != img {
+				vars = reflect.Append(vars, field.Index(i))
+			}
+		}
+		field.Set(vars)
+	}
+}
+
+// varImage returns the image of a delve packageVar.
+func varImage(v reflect.Value) *proc.Image {
+	cu := v.FieldByName("cu")
+	if !cu.IsValid() || cu.IsNil() {
+		return nil
+	}
+	image := cu.Elem().FieldByName("image")
+	if !image.IsValid() {
+		return nil
+	}
+	return (*proc.Image)(unsafe.Pointer(image.Pointer()))
+}
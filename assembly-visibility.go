@@ -0,0 +1,82 @@
+package assembly
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// VisibilityPolicy selects the symbols ForeachFunc, ForeachType and ForeachImageType yield
+// besides application code. The zero value yields application code only, lookups by name are
+// not affected.
+type VisibilityPolicy struct {
+	// Runtime yields the symbols of the runtime and its internal packages.
+	Runtime bool
+	// Stdlib yields the symbols of the other standard library packages and predeclared types.
+	Stdlib bool
+	// Generated yields symbols the compiler generates: equality functions, go/defer wrappers,
+	// method value wrappers and shape instantiations of generic code.
+	Generated bool
+}
+
+// generatedSymbol matches the names of compiler generated functions and types.
+var generatedSymbol = regexp.MustCompile(`^(type[:.]\.|go[:.])|go\.shape\.|\.(deferwrap|gowrap)\d+(\.|$)|-fm$`)
+
+func (da *dwarfAssembly) SetVisibilityPolicy(policy VisibilityPolicy) {
+	da.visibility = policy
+}
+
+// visibleFilter returns the predicate iteration applies to DWARF names under the visibility policy.
+func (da *dwarfAssembly) visibleFilter() func(name string) bool {
+	policy := da.visibility
+	if policy.Runtime && policy.Stdlib && policy.Generated {
+		return func(string) bool { return true }
+	}
+
+	// packages of the modules the images were built from are application code even when their
+	// path has no dot, as are the packages of the modules they depend on.
+	modules := make(map[string]bool)
+	for _, img := range da.binaryInfo.Images {
+		info, err := da.BuildInfo(img)
+		if err != nil {
+			continue
+		}
+		modules[info.Main.Path] = true
+		for _, dep := range info.Deps {
+			modules[dep.Path] = true
+		}
+	}
+
+	return func(name string) bool {
+		if generatedSymbol.MatchString(name) {
+			return policy.Generated
+		}
+		pkg := symbolPackage(strings.TrimLeft(name, "*[]0123456789"))
+		switch {
+		case pkg == "runtime" || strings.HasPrefix(pkg, "runtime/") || strings.HasPrefix(pkg, "internal/runtime/"):
+			return policy.Runtime
+		case stdlibPackage(pkg, modules):
+			return policy.Stdlib
+		}
+		return true
+	}
+}
+
+// stdlibPackage reports whether pkg is a standard library package, those are the paths whose
+// first element has no dot, unless they belong to one of modules. The empty path stands for
+// predeclared and unnamed composite types.
+func stdlibPackage(pkg string, modules map[string]bool) bool {
+	if pkg == "main" {
+		return false
+	}
+	for prefix := pkg; prefix != ""; prefix = path.Dir(prefix) {
+		if modules[prefix] {
+			return false
+		}
+		if !strings.Contains(prefix, "/") {
+			break
+		}
+	}
+	first, _, _ := strings.Cut(pkg, "/")
+	return !strings.Contains(first, ".")
+}
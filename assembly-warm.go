@@ -0,0 +1,48 @@
+package assembly
+
+import (
+	"time"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// WarmOptions selects the caches WarmCaches builds, the zero value builds all of them back to back.
+type WarmOptions struct {
+	// Pause is slept before each cache built in the background, to bound the CPU taken from the program.
+	Pause time.Duration
+	// SkipGlobals leaves the globals cache to the first global lookup.
+	SkipGlobals bool
+	// SkipTypes leaves the runtime type caches of the images to the first type lookup.
+	SkipTypes bool
+}
+
+// WarmCaches builds the function index before returning, then the runtime type cache of every
+// image and the globals cache one after another in the background, so the first lookups do not
+// pay for them. The returned channel is closed once warming finished; Close, LoadImage and
+// UnloadImage wait for it.
+func (da *dwarfAssembly) WarmCaches(opts WarmOptions) <-chan struct{} {
+	// delve builds its function index lazily and without locking, it is not built concurrently with lookups.
+	da.binaryInfo.LookupFunc()
+
+	var images []*proc.Image
+	var modules = da.modules
+	if !opts.SkipTypes {
+		images = append(images, da.binaryInfo.Images...)
+	}
+
+	done := make(chan struct{})
+	da.background.Add(1)
+	go func() {
+		defer da.background.Done()
+		defer close(done)
+		for _, img := range images {
+			time.Sleep(opts.Pause)
+			da.imageTypeCacheOf(img, modules)
+		}
+		if !opts.SkipGlobals {
+			time.Sleep(opts.Pause)
+			da.globalsCache()
+		}
+	}()
+	return done
+}
@@ -1,47 +1,224 @@
 package assembly
 
 import (
+	"context"
+	"debug/buildinfo"
 	"errors"
 	"os"
 	"reflect"
 	"runtime"
+	"sync"
+	"time"
 
 	"github.com/go-delve/delve/pkg/proc"
 )
 
 var (
-	ErrNotFound         = errors.New("not found")
-	ErrNotSupport       = errors.New("not support")
-	ErrTooManyLibraries = errors.New("number of loaded libraries exceeds maximum")
+	ErrNotFound            = errors.New("not found")
+	ErrNotSupport          = errors.New("not support")
+	ErrTooManyLibraries    = errors.New("number of loaded libraries exceeds maximum")
+	ErrReleased            = errors.New("handle released")
+	ErrImageUnloaded       = errors.New("image unloaded")
+	ErrABIMismatch         = errors.New("abi mismatch")
+	ErrProtectedSymbol     = errors.New("protected symbol")
+	ErrUnsafeArgument      = errors.New("unsafe argument not allowed")
+	ErrUnmappedAddress     = errors.New("address not mapped")
+	ErrClosureNotSupported = errors.New("closure not supported")
+	ErrAlreadyPatched      = errors.New("already patched")
+	ErrOutOfScope          = errors.New("out of scope")
+	ErrPatchBusy           = errors.New("patch target busy")
+	ErrImageInUse          = errors.New("image in use")
+	ErrBuildIDMismatch     = errors.New("build id mismatch")
+	ErrUnhealthy           = errors.New("assembly unhealthy")
+	ErrRateLimited         = errors.New("rate limited")
+	ErrSyntheticType       = errors.New("synthetic type")
 )
 
-type DwarfAssembly interface {
+// ImageLoader loads the debug information of the executable and its libraries.
+type ImageLoader interface {
 	BinaryInfo() *proc.BinaryInfo
 	LoadImage(path string, entryPoint uint64) error
+	LoadImageAuto(path string) error
+	ListLoadedLibraries() ([]LoadedLibrary, error)
+	UnloadImage(path string) error
+	UnloadImageForce(path string) error
+	ReloadMainImage(symbolFile string) error
+	ImageErrors() []*ImageError
 	Close() error
+}
 
-	FindGlobal(name string) (reflect.Value, error)
-	ForeachGlobal(fn func(name string, value reflect.Value) bool)
-
+// TypeFinder resolves runtime types by name.
+type TypeFinder interface {
+	ResolveType(name string) (reflect.Type, *proc.Image, error)
 	ForeachType(f func(name string) bool) error
+	ForeachTypeContext(ctx context.Context, f func(name string) bool) error
+	ForeachTypeInPackage(prefix string, f func(name string) bool) error
 	FindType(name string) (reflect.Type, error)
+	DescribeType(name string) (*TypeDescription, error)
+	SynthesizeType(name string) (reflect.Type, error)
+	CheckTypeCompatibility(name string, imgA, imgB *proc.Image) ([]TypeMismatch, error)
+	TypeGraph(pkg string) (*TypeGraph, error)
+	ForeachImageType(image *proc.Image, f func(name string, typ reflect.Type) bool)
+	ForeachConst(f func(c Constant) bool)
+	FindConst(name string) (Constant, error)
+}
 
+// FuncResolver locates functions and describes their signatures.
+type FuncResolver interface {
+	ResolveFunc(name string) (*proc.Function, *proc.Image, error)
 	FindFuncEntry(name string) (*proc.Function, error)
+	FindMethod(typeName string, method string) (*proc.Function, error)
+	FindGenericMethod(typeName string, typeArgs []string, method string) (*proc.Function, error)
 	FindFuncPc(name string) (uint64, error)
 	FindFuncType(name string, variadic bool) (reflect.Type, error)
+	FindMethodType(name string, variadic bool, mode ReceiverMode) (reflect.Type, reflect.Type, error)
 	FindFunc(name string, variadic bool) (reflect.Value, error)
+	FindFuncAs(name string, ftyp reflect.Type) (reflect.Value, error)
 	ForeachFunc(f func(name string, pc uint64) bool)
+	ForeachFuncInPackage(prefix string, f func(name string, pc uint64) bool)
+	DescribeFunc(name string) (*FuncDescription, error)
+	ParamLocations(name string) ([]ParamLocation, error)
+	ValidateCallABI(name string) error
+	FuncABI(name string) (CallABI, error)
+	FuzzTargets(filter func(name string) bool) []FuzzTarget
+	Closures(filter func(parent string) bool) []Closure
+	FuncOrigin(name string) (*proc.Function, error)
+	AnalyzePrologue(name string) (*Prologue, error)
+	PatchSite(target string, replacement reflect.Value) (*PatchSite, error)
+	PatchSiteNamed(target string, replacement string) (*PatchSite, error)
+	PatchFunc(target string, replacement reflect.Value) (*Patch, error)
+	PatchFuncSafe(target string, replacement reflect.Value) (*Patch, error)
+	Original(name string) (reflect.Value, error)
+	InlineSites(name string) ([]InlineSite, error)
+}
+
+// FuncCaller invokes functions by name.
+type FuncCaller interface {
+	SetCallPolicy(policy CallPolicy)
 	CallFunc(name string, variadic bool, args []reflect.Value) ([]reflect.Value, error)
+	CallFuncResult(name string, variadic bool, args []reflect.Value) (*CallResult, error)
+	CallFuncNamed(name string, args map[string]any) (*CallResult, error)
+	CallFuncErr(name string, args ...reflect.Value) ([]reflect.Value, error)
+	CallMethod(typeName string, method string, receiver reflect.Value, args []reflect.Value) ([]reflect.Value, error)
+	BindMethod(recv reflect.Value, method string) (reflect.Value, error)
+	CallInterfaceMethod(iface reflect.Value, method string, args []reflect.Value) ([]reflect.Value, error)
+	NewExecutor(name string, variadic bool, workers int) (*Executor, error)
+}
+
+// GlobalAccessor reads and writes global variables.
+type GlobalAccessor interface {
+	ResolveGlobal(name string) (reflect.Value, *proc.Image, error)
+	FindGlobal(name string) (reflect.Value, error)
+	FindGlobalAddr(name string) (uintptr, error)
+	FindGlobals(pattern string) ([]GlobalMatch, error)
+	ForeachGlobal(fn func(name string, value reflect.Value) bool)
+	ForeachGlobalInPackage(prefix string, fn func(name string, value reflect.Value) bool)
+	LoadGlobalsContext(ctx context.Context) error
+	StreamGlobals(fn func(name string, value reflect.Value) bool)
+	SetMutationPolicy(policy MutationPolicy)
+	SetGlobal(name string, value reflect.Value) error
+	FindMethodValue(name string) (*MethodValue, error)
+	RebindMethodValue(name string, receiver reflect.Value, method string) error
+}
 
+// PluginSearcher finds the Go plugins loaded into the process.
+type PluginSearcher interface {
 	SearchPluginByName(name string) (lib string, addr uint64, err error)
-	SearchPlugins() (libs []string, addrs []uint64, err error)
+	SearchPlugins() ([]PluginInfo, error)
+	SearchPluginsContext(ctx context.Context) ([]PluginInfo, error)
+	ListPluginSymbols(nameOrPath string) ([]PluginSymbol, error)
+	PreflightPlugin(path string) error
+}
+
+type DwarfAssembly interface {
+	ImageLoader
+	TypeFinder
+	FuncResolver
+	FuncCaller
+	GlobalAccessor
+	PluginSearcher
+
+	SetResolvePolicy(policy ResolvePolicy)
+	SetTracer(tracer func(step ResolveStep))
+	SetLayoutHandler(handler func(change LayoutChange))
+	SetIndexCache(dir string) error
+	CheckLayout() ([]LayoutChange, error)
+	WatchLayout(ctx context.Context, interval time.Duration) error
+	SetNamePolicy(policy NamePolicy)
+	SetVisibilityPolicy(policy VisibilityPolicy)
+	RegisterAlias(alias string, target string) error
+	UnregisterAlias(alias string)
+	Aliases() map[string]string
+	ShadowedSymbols() []ShadowedSymbol
+	Provenance(kind SymbolKind, name string) (ImageInfo, error)
+	ListImages() []ImageInfo
+	ResolveAddress(addr uint64) (AddressInfo, error)
+	FileLine(pc uint64) (file string, line int)
+	LineToPC(file string, line int) ([]uint64, error)
+	SymbolizeStack(pcs []uint64) []SymbolizedPC
+	Sections(image *proc.Image) ([]Section, error)
+	BuildID(image *proc.Image) (string, error)
+	BuildInfo(image *proc.Image) (*buildinfo.BuildInfo, error)
+	CompareDependencies(path string) ([]DependencyMismatch, error)
+	SetDependencyPolicy(policy DependencyPolicy)
+	SetRateLimitPolicy(policy RateLimitPolicy)
+	CheckDependencies(path string) (warnings []DependencyMismatch, err error)
+
+	AcquireGlobal(name string) (*Handle, error)
+	AcquireFunc(name string, variadic bool) (*Handle, error)
+	References(image *proc.Image) int
+	Pin(value reflect.Value) (*Pinned, error)
+	PinCount() int
+	Symbol(name string, imageHint string) *SymbolHandle
+	MemoryUsage() MemoryUsage
+	WarmCaches(opts WarmOptions) <-chan struct{}
+	PatchMetrics() PatchMetrics
+	Healthy() error
+	SelfTest() (*SelfTestReport, error)
+	Scoped(scope Scope) *ScopedAssembly
+
+	GOMAXPROCS() (int, error)
+	SchedStats() (SchedStats, error)
+	RuntimeStat(name string) (int64, error)
+	RuntimeModules() ([]RuntimeModule, error)
+	CurrentGoroutine() (Goroutine, error)
 }
 
 type dwarfAssembly struct {
-	binaryInfo *proc.BinaryInfo
-	modules    []ModuleData
-	globals    map[string]reflect.Value
-	imageTypes map[*proc.Image]map[string]uint64
+	binaryInfo  *proc.BinaryInfo
+	modules     []ModuleData
+	globals     map[string][]imageGlobal
+	globalsMu   sync.Mutex
+	lazyGlobals lazyGlobals
+	imageTypes  map[*proc.Image]*imageTypeCache
+	typesMu     sync.Mutex
+	background  sync.WaitGroup
+	cacheMu     sync.Mutex
+	sections    map[*proc.Image][]Section
+	abi0Funcs   map[*proc.Image]map[uint64]bool
+	buildIDs    map[*proc.Image]string
+	buildInfos  map[*proc.Image]*buildinfo.BuildInfo
+	policy      ResolvePolicy
+	depPolicy   DependencyPolicy
+	mutPolicy   MutationPolicy
+	callPolicy  CallPolicy
+	aliases     map[string]string
+	namePolicy  NamePolicy
+	visibility  VisibilityPolicy
+	vendorRoots []string
+	funcs       map[funcKey]*createdFunc
+	funcTypes   map[funcTypeKey]reflect.Type
+	handles     map[*proc.Image]map[*Handle]struct{}
+	pins        pinSet
+	indexMu     sync.RWMutex
+	readers     readerPools
+	packages    packageIndex
+	layout      layoutState
+	indexCache  indexCache
+	patchStats  patchStats
+	limits      rateLimiter
+	names       nameTable
+	tracer      func(step ResolveStep)
 }
 
 func NewDwarfAssembly() (DwarfAssembly, error) {
@@ -69,19 +246,41 @@ func (da *dwarfAssembly) BinaryInfo() *proc.BinaryInfo {
 	return da.binaryInfo
 }
 
+// LoadImage loads the debug information of the executable or a library mapped at entryPoint.
+// A library whose debug information is invalid is reported by a *LoadError, the images loaded
+// before remain usable.
 func (da *dwarfAssembly) LoadImage(path string, entryPoint uint64) (err error) {
+	var loadErr *LoadError
+
+	// The background builds of the caches read the binary info delve is about to extend.
+	da.background.Wait()
 
 	if 0 == len(da.binaryInfo.Images) {
-		if err = da.binaryInfo.LoadBinaryInfo(path, entryPoint, nil); nil != err {
+		if err = da.extendIndexes(func() error { return da.binaryInfo.LoadBinaryInfo(path, entryPoint, nil) }); nil != err {
 			return
 		}
 	} else {
-		if err = da.binaryInfo.AddImage(path, entryPoint); nil != err {
+		var depErr *DependencyError
+		if _, err = da.CheckDependencies(path); errors.As(err, &depErr) {
 			return
 		}
+		if err = da.extendIndexes(func() error { return da.binaryInfo.AddImage(path, entryPoint) }); nil != err {
+			// delve keeps the image registered without symbols, the other images stay usable.
+			loadErr = &LoadError{Images: []*ImageError{{Path: path, Err: err}}}
+		}
+	}
+
+	if err = da.refreshModules(); nil != err {
+		return
 	}
 
-	return da.refreshModules()
+	if nil != loadErr {
+		return loadErr
+	}
+	if images := da.binaryInfo.Images; len(images) > 1 {
+		da.warmImageTypes(images[len(images)-1])
+	}
+	return nil
 }
 
 func (da *dwarfAssembly) refreshModules() error {
@@ -90,14 +289,38 @@ func (da *dwarfAssembly) refreshModules() error {
 		return err
 	}
 	da.modules = modules
-	da.globals = nil
+	da.resetGlobals()
+	da.resetPackageIndex()
+	da.cacheMu.Lock()
+	da.funcTypes = nil
+	da.cacheMu.Unlock()
+	da.vendorRoots = nil
 	return nil
 }
 
 func (da *dwarfAssembly) Close() error {
+	da.background.Wait()
 	da.modules = nil
-	da.globals = nil
+	da.resetGlobals()
+	da.resetPackageIndex()
+	da.typesMu.Lock()
 	da.imageTypes = nil
+	da.typesMu.Unlock()
+	da.cacheMu.Lock()
+	da.sections = nil
+	da.abi0Funcs = nil
+	da.buildIDs = nil
+	da.buildInfos = nil
+	da.funcs = nil
+	da.funcTypes = nil
+	da.cacheMu.Unlock()
+	da.resetNames()
+	da.resetReaders(nil)
+	da.dropIndex(nil)
+	da.layout.mu.Lock()
+	da.layout.stale = nil
+	da.layout.mu.Unlock()
+	da.unpinAll()
 	runtime.SetFinalizer(da, nil)
 	return da.binaryInfo.Close()
 }
@@ -1,10 +1,27 @@
 package assembly
 
 import (
+	"bytes"
 	"cmp"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/go-delve/delve/pkg/proc"
 )
 
 func testAdd(a, b int) int {
@@ -25,6 +42,54 @@ func testMax(a int, nums ...int) int {
 	return _max
 }
 
+func testScale(a, b int) int {
+	return a * 10
+}
+
+// testSum has a frame larger than the stack of a new goroutine, calls grow the stack from its prologue.
+func testSum(n int) int {
+	var buf [2048]int
+	for i := range buf {
+		buf[i] = i % n
+	}
+	return buf[n] + n
+}
+
+var testSumOriginal func(n int) int
+
+// testInc is short enough for the jump restarting it after stack growth to be a short one.
+func testInc(n int) int {
+	var buf [4]int
+	buf[n&3] = n
+	return testAdd(buf[n&3], 1)
+}
+
+func testSumTwice(n int) int {
+	return 2 * testSumOriginal(n)
+}
+
+var errTestDivideByZero = errors.New("divide by zero")
+
+func testDivide(a, b int) (quotient int, err error) {
+	if b == 0 {
+		return 0, errTestDivideByZero
+	}
+	return a / b, nil
+}
+
+func testLoadInt(p unsafe.Pointer) int {
+	return *(*int)(p)
+}
+
+type testPoint struct {
+	X, Y float64
+	N    int
+}
+
+func testABI(a int, p testPoint, s string, big [4]int, f float32) float64 {
+	return float64(a) + p.X + p.Y + float64(p.N) + float64(len(s)) + float64(big[3]) + float64(f)
+}
+
 func genericMin[T cmp.Ordered](a T, nums ...T) T {
 	if len(nums) == 0 {
 		return a
@@ -39,8 +104,64 @@ func genericMin[T cmp.Ordered](a T, nums ...T) T {
 	return _min
 }
 
+func (p testPoint) Sum() float64 {
+	return p.X + p.Y
+}
+
+var testPointSum = testPoint{X: 1, Y: 2}.Sum
+
+type testShape interface {
+	area() float64
+	scaled(k float64) testShape
+}
+
+func (p testPoint) area() float64 {
+	return p.X * p.Y
+}
+
+func (p testPoint) scaled(k float64) testShape {
+	return testPoint{X: p.X * k, Y: p.Y * k}
+}
+
+func (p *testPoint) scale(k float64) {
+	p.X *= k
+	p.Y *= k
+}
+
+func testDeferAdd() {
+	defer testAdd(1, 2)
+}
+
+type testLocalOnly struct {
+	n int
+}
+
+func testLocalOnlyValue() int {
+	var v testLocalOnly
+	v.n = 1
+	return v.n
+}
+
+type genericBox[T any] struct {
+	value T
+}
+
+func (b *genericBox[T]) Get() T {
+	return b.value
+}
+
+var testGenericBox = &genericBox[int]{value: 7}
+
+type testColor int
+
+const (
+	testRed testColor = iota + 1
+	testGreen
+)
+
 var testGlobalInt = 11001
 var testGlobalString = "hello world"
+var testGlobalRatio = 0.5
 
 func TestDwarfAssembly(t *testing.T) {
 
@@ -60,8 +181,58 @@ func TestDwarfAssembly(t *testing.T) {
 		AssemblyTestFindFunc,
 		AssemblyTestFindVariadicFunc,
 		AssemblyTestFindGenericVariadicFunc,
+		AssemblyTestFindGenericMethod,
+		AssemblyTestCallABI,
 		AssemblyTestGlobalVar,
 		AssemblyTestPlugin,
+		AssemblyTestResolvePolicy,
+		AssemblyTestResolveAddress,
+		AssemblyTestImages,
+		AssemblyTestHandles,
+		AssemblyTestRuntime,
+		AssemblyTestExecutor,
+		AssemblyTestExecutorUnsafePointer,
+		AssemblyTestFuzzTargets,
+		AssemblyTestClosures,
+		AssemblyTestPin,
+		AssemblyTestPatchMetrics,
+		AssemblyTestPatchFunc,
+		AssemblyTestScoped,
+		AssemblyTestOriginal,
+		AssemblyTestSnapshot,
+		AssemblyTestCallFuncErr,
+		AssemblyTestPatchManager,
+		AssemblyTestPatchFuncSafe,
+		AssemblyTestCheckLayout,
+		AssemblyTestReloadMainImage,
+		AssemblyTestListImages,
+		AssemblyTestInlineSites,
+		AssemblyTestIndexCache,
+		AssemblyTestFindMethodType,
+		AssemblyTestVisibilityPolicy,
+		AssemblyTestLazyGlobals,
+		AssemblyTestTypeGraph,
+		AssemblyTestFindGlobals,
+		AssemblyTestHealthy,
+		AssemblyTestForeachInPackage,
+		AssemblyTestConstants,
+		AssemblyTestRateLimit,
+		AssemblyTestLines,
+		AssemblyTestMethodValues,
+		AssemblyTestSymbolizeStack,
+		AssemblyTestSelfTest,
+		AssemblyTestTypeCompatibility,
+		AssemblyTestAdminHandler,
+		AssemblyTestRemote,
+		AssemblyTestGenerics,
+		AssemblyTestPatchSite,
+		AssemblyTestPrologue,
+		AssemblyTestContext,
+		AssemblyTestClosureCall,
+		AssemblyTestPartialLoad,
+		AssemblyTestFuncOrigin,
+		AssemblyTestMethod,
+		AssemblyTestInterfaceMethod,
 	}
 
 	for _, testCase := range testCases {
@@ -89,16 +260,60 @@ func AssemblyTestFindType(t *testing.T, asm DwarfAssembly) {
 		t.Fatalf("ForeachType() not found")
 	}
 
+	var steps []ResolveStep
+	asm.SetTracer(func(step ResolveStep) { steps = append(steps, step) })
 	asmType, err := asm.FindType("github.com/go-hotfix/assembly.dwarfAssembly")
+	asm.SetTracer(nil)
+	if 0 == len(steps) || "candidate" != steps[0].Step || "runtime type" != steps[len(steps)-1].Step {
+		t.Fatalf("SetTracer() steps got = %v", steps)
+	}
 	if nil != err {
 		t.Fatalf("FindType() error: %v", err)
 	}
 
+	_ = testLocalOnlyValue()
+	asm.SetResolvePolicy(ResolvePolicy{TypeFallback: TypeFallbackStrict})
+	_, err = asm.FindType("github.com/go-hotfix/assembly.testLocalOnly")
+	asm.SetResolvePolicy(ResolvePolicy{})
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("FindType(testLocalOnly) with TypeFallbackStrict got = %v, want ErrNotFound", err)
+	}
+	asm.SetResolvePolicy(ResolvePolicy{TypeFallback: TypeFallbackSynthesize})
+	synthetic, img, err := asm.ResolveType("github.com/go-hotfix/assembly.testLocalOnly")
+	asm.SetResolvePolicy(ResolvePolicy{})
+	if !errors.Is(err, ErrSyntheticType) || nil != img || nil == synthetic || reflect.Struct != synthetic.Kind() || "" != synthetic.Name() {
+		t.Fatalf("ResolveType(testLocalOnly) with TypeFallbackSynthesize got = %v, %v, %v", synthetic, img, err)
+	}
+	if field, ok := synthetic.FieldByName("N"); !ok || reflect.TypeOf(0) != field.Type || unsafe.Sizeof(testLocalOnly{}) != synthetic.Size() {
+		t.Fatalf("ResolveType(testLocalOnly) with TypeFallbackSynthesize got = %v", synthetic)
+	}
+	if synthetic, err = asm.SynthesizeType("github.com/go-hotfix/assembly.testPoint"); nil != err {
+		t.Fatalf("SynthesizeType(testPoint) error: %v", err)
+	}
+	if want := reflect.TypeOf(struct {
+		X, Y float64
+		N    int
+	}{}); want != synthetic {
+		t.Fatalf("SynthesizeType(testPoint) got = %v, want %v", synthetic, want)
+	}
+
 	wantType := reflect.TypeOf(dwarfAssembly{})
 
 	if wantType != asmType {
 		t.Fatalf("FindType() got = %v, want %v", asmType, wantType)
 	}
+
+	var imageType reflect.Type
+	asm.ForeachImageType(asm.BinaryInfo().Images[0], func(name string, typ reflect.Type) bool {
+		if "github.com/go-hotfix/assembly.dwarfAssembly" == name {
+			imageType = typ
+		}
+		return nil == imageType
+	})
+
+	if wantType != imageType {
+		t.Fatalf("ForeachImageType() got = %v, want %v", imageType, wantType)
+	}
 }
 
 func AssemblyTestFindFunc(t *testing.T, asm DwarfAssembly) {
@@ -114,6 +329,39 @@ func AssemblyTestFindFunc(t *testing.T, asm DwarfAssembly) {
 		t.Fatalf("FindFuncType() got = %v, want %v", asmType, wantType)
 	}
 
+	if err = asm.RegisterAlias("add", "github.com/go-hotfix/assembly.testAdd"); nil != err {
+		t.Fatalf("RegisterAlias() error: %v", err)
+	}
+	defer asm.UnregisterAlias("add")
+
+	asm.SetNamePolicy(NamePolicy{Rewrites: []NameRewrite{{From: "github.com/go-hotfix/", To: "hotfix/"}}})
+	defer asm.SetNamePolicy(NamePolicy{})
+
+	if _, err = asm.FindFuncPc("hotfix/assembly.testAdd"); nil != err {
+		t.Fatalf("FindFuncPc(hotfix/assembly.testAdd) error: %v", err)
+	}
+
+	if _, err = asm.FindType("*hotfix/assembly.dwarfAssembly"); nil != err {
+		t.Fatalf("FindType(*hotfix/assembly.dwarfAssembly) error: %v", err)
+	}
+
+	if _, err = asm.FindFuncPc("github.com/go-hotfix/assembly\u00b7testAdd"); nil != err {
+		t.Fatalf("FindFuncPc(github.com/go-hotfix/assembly\u00b7testAdd) error: %v", err)
+	}
+
+	if got := CanonicalName("type..eq.gopkg.in/yaml%2ev2.Node"); got != "type:.eq.gopkg.in/yaml.v2.Node" {
+		t.Fatalf("CanonicalName() got = %v", got)
+	}
+
+	var displayed bool
+	asm.ForeachFunc(func(name string, pc uint64) bool {
+		displayed = name == "hotfix/assembly.testAdd"
+		return !displayed
+	})
+	if !displayed {
+		t.Fatalf("ForeachFunc() hotfix/assembly.testAdd not found")
+	}
+
 	callResults, err := asm.CallFunc("github.com/go-hotfix/assembly.testAdd", false, []reflect.Value{reflect.ValueOf(100), reflect.ValueOf(1)})
 	if nil != err {
 		t.Fatalf("CallFunc(%s) error: %v", wantType.String(), err)
@@ -126,6 +374,10 @@ func AssemblyTestFindFunc(t *testing.T, asm DwarfAssembly) {
 		t.Fatalf("CallFunc(%s) got = %v, want %v", wantType.String(), gotValue, wantValue)
 	}
 
+	if callResults, err = asm.CallFunc("add", false, []reflect.Value{reflect.ValueOf(100), reflect.ValueOf(1)}); nil != err || int64(wantValue) != callResults[0].Int() {
+		t.Fatalf("CallFunc(add) got = %v, %v, want %v", callResults, err, wantValue)
+	}
+
 }
 
 func AssemblyTestFindVariadicFunc(t *testing.T, asm DwarfAssembly) {
@@ -178,8 +430,153 @@ func AssemblyTestFindGenericVariadicFunc(t *testing.T, asm DwarfAssembly) {
 	}
 }
 
+func AssemblyTestFindGenericMethod(t *testing.T, asm DwarfAssembly) {
+	f, err := asm.FindGenericMethod("github.com/go-hotfix/assembly.genericBox", []string{"int"}, "Get")
+	if nil != err {
+		t.Fatalf("FindGenericMethod() error: %v", err)
+	}
+
+	wantName := "github.com/go-hotfix/assembly.(*genericBox[int]).Get"
+	if f.Name != wantName {
+		t.Fatalf("FindGenericMethod() got = %v, want %v", f.Name, wantName)
+	}
+
+	if _, err = asm.FindGenericMethod("github.com/go-hotfix/assembly.genericBox", []string{"string"}, "Get"); err != ErrNotFound {
+		t.Fatalf("FindGenericMethod() got = %v, want %v", err, ErrNotFound)
+	}
+	desc, err := asm.DescribeFunc("github.com/go-hotfix/assembly.genericMin[go.shape.int]")
+	if nil != err {
+		t.Fatalf("DescribeFunc() error: %v", err)
+	}
+
+	if len(desc.TypeArgs) != 1 || desc.TypeArgs[0] != "go.shape.int" || len(desc.TypeParams) == 0 {
+		t.Fatalf("DescribeFunc() got = %+v, want go.shape.int instantiation", desc)
+	}
+
+	typeDesc, err := asm.DescribeType("github.com/go-hotfix/assembly.genericBox[int]")
+	if nil != err {
+		t.Fatalf("DescribeType() error: %v", err)
+	}
+
+	if typeDesc.Kind != reflect.Struct || len(typeDesc.TypeArgs) != 1 || typeDesc.TypeArgs[0] != "int" {
+		t.Fatalf("DescribeType() got = %+v, want genericBox[int]", typeDesc)
+	}
+	_ = testGenericBox.Get()
+}
+
+func AssemblyTestCallABI(t *testing.T, asm DwarfAssembly) {
+	locations, err := asm.ParamLocations("github.com/go-hotfix/assembly.testABI")
+	if nil != err {
+		t.Fatalf("ParamLocations() error: %v", err)
+	}
+
+	if len(locations) != 6 || locations[1].Name != "p" || locations[1].Type != "github.com/go-hotfix/assembly.testPoint" {
+		t.Fatalf("ParamLocations() got = %+v", locations)
+	}
+
+	if err = asm.ValidateCallABI("github.com/go-hotfix/assembly.testABI"); nil != err {
+		t.Fatalf("ValidateCallABI() error: %v", err)
+	}
+
+	if abi, err := asm.FuncABI("github.com/go-hotfix/assembly.testABI"); nil != err || abi == ABIUnknown {
+		t.Fatalf("FuncABI() got = %v, %v", abi, err)
+	}
+
+	point := testPoint{X: 1.5, Y: 2.5, N: 3}
+	big := [4]int{0, 0, 0, 4}
+	callResults, err := asm.CallFunc("github.com/go-hotfix/assembly.testABI", false, []reflect.Value{
+		reflect.ValueOf(1), reflect.ValueOf(point), reflect.ValueOf("ab"), reflect.ValueOf(big), reflect.ValueOf(float32(0.5)),
+	})
+	if nil != err {
+		t.Fatalf("CallFunc(testABI) error: %v", err)
+	}
+
+	if want := testABI(1, point, "ab", big, 0.5); callResults[0].Float() != want {
+		t.Fatalf("CallFunc(testABI) got = %v, want %v", callResults[0].Float(), want)
+	}
+
+	res, err := asm.CallFuncResult("github.com/go-hotfix/assembly.testDivide", false, []reflect.Value{reflect.ValueOf(7), reflect.ValueOf(2)})
+	if nil != err {
+		t.Fatalf("CallFuncResult(testDivide) error: %v", err)
+	}
+
+	want, _ := testDivide(7, 2)
+	if quotient, ok := res.Get("quotient"); !ok || quotient.Int() != int64(want) || nil != res.Err() {
+		t.Fatalf("CallFuncResult(testDivide) got = %v, %v", res.Names, res.Err())
+	}
+
+	res, err = asm.CallFuncResult("github.com/go-hotfix/assembly.testDivide", false, []reflect.Value{reflect.ValueOf(7), reflect.ValueOf(0)})
+	if nil != err {
+		t.Fatalf("CallFuncResult(testDivide) error: %v", err)
+	}
+
+	if !errors.Is(res.Err(), errTestDivideByZero) {
+		t.Fatalf("CallFuncResult(testDivide).Err() got = %v, want %v", res.Err(), errTestDivideByZero)
+	}
+
+	res, err = asm.CallFuncNamed("github.com/go-hotfix/assembly.testDivide", map[string]any{"a": 9.0, "b": int32(3)})
+	if nil != err {
+		t.Fatalf("CallFuncNamed(testDivide) error: %v", err)
+	}
+
+	if res.Values[0].Int() != 3 {
+		t.Fatalf("CallFuncNamed(testDivide) got = %v, want %v", res.Values[0].Int(), 3)
+	}
+
+	var argErr *ArgumentError
+	_, err = asm.CallFuncNamed("github.com/go-hotfix/assembly.testDivide", map[string]any{"a": 9, "c": 3})
+	if !errors.As(err, &argErr) || len(argErr.Missing) != 1 || argErr.Missing[0] != "b" || len(argErr.Extra) != 1 || argErr.Extra[0] != "c" {
+		t.Fatalf("CallFuncNamed(testDivide) error = %v", err)
+	}
+
+	if _, err = asm.CallFuncNamed("github.com/go-hotfix/assembly.testDivide", map[string]any{"a": 9.5, "b": 3}); nil == err {
+		t.Fatalf("CallFuncNamed(testDivide) truncating argument accepted")
+	}
+
+	addr, err := asm.FindGlobalAddr("github.com/go-hotfix/assembly.testGlobalInt")
+	if nil != err {
+		t.Fatalf("FindGlobalAddr() error: %v", err)
+	}
+
+	if addr != uintptr(unsafe.Pointer(&testGlobalInt)) {
+		t.Fatalf("FindGlobalAddr() got = %#x, want %p", addr, &testGlobalInt)
+	}
+
+	loadArgs := []reflect.Value{reflect.ValueOf(unsafe.Pointer(addr))}
+	if _, err = asm.CallFunc("github.com/go-hotfix/assembly.testLoadInt", false, loadArgs); !errors.Is(err, ErrUnsafeArgument) {
+		t.Fatalf("CallFunc(testLoadInt) error = %v, want %v", err, ErrUnsafeArgument)
+	}
+
+	asm.SetCallPolicy(CallPolicy{AllowUnsafePointer: true})
+	defer asm.SetCallPolicy(CallPolicy{})
+
+	callResults, err = asm.CallFunc("github.com/go-hotfix/assembly.testLoadInt", false, loadArgs)
+	if nil != err {
+		t.Fatalf("CallFunc(testLoadInt) error: %v", err)
+	}
+
+	if want := testLoadInt(unsafe.Pointer(&testGlobalInt)); callResults[0].Int() != int64(want) {
+		t.Fatalf("CallFunc(testLoadInt) got = %v, want %v", callResults[0].Int(), want)
+	}
+
+	unmapped := []reflect.Value{reflect.ValueOf(unsafe.Pointer(uintptr(0x10)))}
+	if _, err = asm.CallFunc("github.com/go-hotfix/assembly.testLoadInt", false, unmapped); !errors.Is(err, ErrUnmappedAddress) {
+		t.Fatalf("CallFunc(testLoadInt) error = %v, want %v", err, ErrUnmappedAddress)
+	}
+}
+
 func AssemblyTestGlobalVar(t *testing.T, asm DwarfAssembly) {
 
+	var streamed = false
+	asm.StreamGlobals(func(name string, value reflect.Value) bool {
+		streamed = name == "github.com/go-hotfix/assembly.testGlobalString" && value.String() == testGlobalString
+		return !streamed
+	})
+
+	if !streamed {
+		t.Fatalf("StreamGlobals() testGlobalString not found")
+	}
+
 	var wantIntValue = int64(testGlobalInt + 1)
 	var wantGlobalString = testGlobalString + "!"
 
@@ -211,23 +608,49 @@ func AssemblyTestGlobalVar(t *testing.T, asm DwarfAssembly) {
 		t.Fatalf("testGlobalString got = %v, want %v", globalStringValue.String(), wantGlobalString)
 	}
 
+	if err = asm.SetGlobal("github.com/go-hotfix/assembly.testGlobalInt", reflect.ValueOf(11001)); nil != err {
+		t.Fatalf("SetGlobal() error: %v", err)
+	}
+
+	if testGlobalInt != 11001 {
+		t.Fatalf("testGlobalInt got = %v, want %v", testGlobalInt, 11001)
+	}
+
+	if err = asm.SetGlobal("github.com/go-hotfix/assembly.testGlobalInt", reflect.Value{}); nil == err || 11001 != testGlobalInt {
+		t.Fatalf("SetGlobal() of the zero Value got = %v, %v, want an error", testGlobalInt, err)
+	}
+
+	if err = asm.SetGlobal("runtime.gomaxprocs", reflect.ValueOf(int32(1))); !errors.Is(err, ErrProtectedSymbol) {
+		t.Fatalf("SetGlobal(runtime.gomaxprocs) error = %v, want %v", err, ErrProtectedSymbol)
+	}
+
+	if err = asm.RegisterAlias("procs", "runtime.gomaxprocs"); nil != err {
+		t.Fatalf("RegisterAlias() error: %v", err)
+	}
+	defer asm.UnregisterAlias("procs")
+
+	if err = asm.SetGlobal("procs", reflect.ValueOf(int32(1))); !errors.Is(err, ErrProtectedSymbol) {
+		t.Fatalf("SetGlobal(procs) error = %v, want %v", err, ErrProtectedSymbol)
+	}
+
+	if err = asm.RegisterAlias("procs", "runtime.ncpu"); nil == err {
+		t.Fatalf("RegisterAlias() of a registered alias accepted")
+	}
+
 }
 
 func AssemblyTestPlugin(t *testing.T, asm DwarfAssembly) {
 
-	libs, addrs, err := asm.SearchPlugins()
+	plugins, err := asm.SearchPlugins()
 	if nil != err {
 		t.Fatalf("SearchPlugins() error: %v", err)
 	}
 
-	if len(libs) != len(addrs) {
-		t.Fatalf("len(libs) != len(addrs)")
-	}
-
-	for i, lib := range libs {
-		if len(lib) > 0 {
-			fmt.Println(lib, addrs[i])
+	for _, plugin := range plugins {
+		if 0 == len(plugin.Path) {
+			t.Fatalf("SearchPlugins() got an unnamed library")
 		}
+		fmt.Println(plugin.Path, plugin.Addr)
 	}
 
 	_, _, err = asm.SearchPluginByName("not-found-image")
@@ -235,3 +658,1703 @@ func AssemblyTestPlugin(t *testing.T, asm DwarfAssembly) {
 		t.Fatalf("SearchPluginByName failed")
 	}
 }
+
+func AssemblyTestResolvePolicy(t *testing.T, asm DwarfAssembly) {
+
+	mainImage := asm.BinaryInfo().Images[0]
+
+	for _, policy := range []ResolvePolicy{{Order: ResolveDefault}, {Order: ResolveNewestFirst}, {Order: ResolveMainFirst}, {Priority: []string{mainImage.Path}}} {
+		asm.SetResolvePolicy(policy)
+
+		_, img, err := asm.ResolveFunc("github.com/go-hotfix/assembly.testAdd")
+		if nil != err {
+			t.Fatalf("ResolveFunc() error: %v", err)
+		}
+		if img != mainImage {
+			t.Fatalf("ResolveFunc() image got = %v, want %v", img.Path, mainImage.Path)
+		}
+
+		_, img, err = asm.ResolveType("github.com/go-hotfix/assembly.dwarfAssembly")
+		if nil != err {
+			t.Fatalf("ResolveType() error: %v", err)
+		}
+		if img != mainImage {
+			t.Fatalf("ResolveType() image got = %v, want %v", img.Path, mainImage.Path)
+		}
+
+		_, img, err = asm.ResolveGlobal("github.com/go-hotfix/assembly.testGlobalInt")
+		if nil != err {
+			t.Fatalf("ResolveGlobal() error: %v", err)
+		}
+		if img != mainImage {
+			t.Fatalf("ResolveGlobal() image got = %v, want %v", img.Path, mainImage.Path)
+		}
+	}
+
+	asm.SetResolvePolicy(ResolvePolicy{})
+
+	for kind, name := range map[SymbolKind]string{
+		SymbolFunc:   "github.com/go-hotfix/assembly.testAdd",
+		SymbolType:   "github.com/go-hotfix/assembly.dwarfAssembly",
+		SymbolGlobal: "github.com/go-hotfix/assembly.testGlobalInt",
+	} {
+		info, err := asm.Provenance(kind, name)
+		if nil != err {
+			t.Fatalf("Provenance(%s) error: %v", kind, err)
+		}
+		if !info.Main || info.Path != mainImage.Path {
+			t.Fatalf("Provenance(%s) got = %+v, want main image %s", kind, info, mainImage.Path)
+		}
+	}
+
+	if shadowed := asm.ShadowedSymbols(); len(shadowed) != 0 {
+		t.Fatalf("ShadowedSymbols() got = %v, want none", shadowed)
+	}
+}
+
+func AssemblyTestResolveAddress(t *testing.T, asm DwarfAssembly) {
+
+	pc, err := asm.FindFuncPc("github.com/go-hotfix/assembly.testAdd")
+	if nil != err {
+		t.Fatalf("FindFuncPc() error: %v", err)
+	}
+
+	info, err := asm.ResolveAddress(pc)
+	if nil != err {
+		t.Fatalf("ResolveAddress() error: %v", err)
+	}
+
+	if !info.Image.Main {
+		t.Fatalf("ResolveAddress() image got = %v, want main image", info.Image.Path)
+	}
+
+	if info.Section != ".text" {
+		t.Fatalf("ResolveAddress() section got = %v, want .text", info.Section)
+	}
+
+	sections, err := asm.Sections(asm.BinaryInfo().Images[0])
+	if nil != err {
+		t.Fatalf("Sections() error: %v", err)
+	}
+
+	var executable = false
+	for _, sec := range sections {
+		if sec.Contains(pc) {
+			executable = sec.Executable() && !sec.Writable()
+		}
+	}
+	if !executable {
+		t.Fatalf("Sections() no read-only executable section contains %#x", pc)
+	}
+
+	if _, err = asm.ResolveAddress(0); err != ErrNotFound {
+		t.Fatalf("ResolveAddress(0) got = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func AssemblyTestImages(t *testing.T, asm DwarfAssembly) {
+
+	mainImage := asm.BinaryInfo().Images[0]
+
+	buildID, err := asm.BuildID(mainImage)
+	if nil != err {
+		t.Fatalf("BuildID() error: %v", err)
+	}
+
+	if !strings.Contains(buildID, "/") {
+		t.Fatalf("BuildID() got = %q, want a Go build id", buildID)
+	}
+
+	info, err := asm.BuildInfo(mainImage)
+	if nil != err {
+		t.Fatalf("BuildInfo() error: %v", err)
+	}
+
+	if info.GoVersion != runtime.Version() {
+		t.Fatalf("BuildInfo() go version got = %v, want %v", info.GoVersion, runtime.Version())
+	}
+
+	mismatches, err := asm.CompareDependencies(mainImage.Path)
+	if nil != err {
+		t.Fatalf("CompareDependencies() error: %v", err)
+	}
+
+	if len(mismatches) != 0 {
+		t.Fatalf("CompareDependencies() got = %v, want none", mismatches)
+	}
+}
+
+func AssemblyTestHandles(t *testing.T, asm DwarfAssembly) {
+
+	mainImage := asm.BinaryInfo().Images[0]
+
+	global, err := asm.AcquireGlobal("github.com/go-hotfix/assembly.testGlobalString")
+	if nil != err {
+		t.Fatalf("AcquireGlobal() error: %v", err)
+	}
+
+	fn, err := asm.AcquireFunc("github.com/go-hotfix/assembly.testAdd", false)
+	if nil != err {
+		t.Fatalf("AcquireFunc() error: %v", err)
+	}
+
+	if fn.Image() != mainImage || global.Image() != mainImage {
+		t.Fatalf("Handle.Image() want main image")
+	}
+
+	if n := asm.References(mainImage); n != 2 {
+		t.Fatalf("References() got = %v, want 2", n)
+	}
+
+	value, err := fn.Value()
+	if nil != err {
+		t.Fatalf("Handle.Value() error: %v", err)
+	}
+
+	if got := value.Interface().(func(int, int) int)(1, 2); got != 3 {
+		t.Fatalf("Handle.Value() call got = %v, want 3", got)
+	}
+
+	global.Release()
+	fn.Release()
+	fn.Release()
+
+	if n := asm.References(mainImage); n != 0 {
+		t.Fatalf("References() got = %v, want 0", n)
+	}
+
+	if _, err = global.Value(); err != ErrReleased {
+		t.Fatalf("Handle.Value() got = %v, want %v", err, ErrReleased)
+	}
+
+	symbol := asm.Symbol("github.com/go-hotfix/assembly.testAdd", mainImage.Path)
+	callResults, err := symbol.Call(false, []reflect.Value{reflect.ValueOf(2), reflect.ValueOf(3)})
+	if nil != err {
+		t.Fatalf("SymbolHandle.Call() error: %v", err)
+	}
+
+	if got := callResults[0].Int(); got != 5 {
+		t.Fatalf("SymbolHandle.Call() got = %v, want 5", got)
+	}
+	if typ, err := asm.Symbol("github.com/go-hotfix/assembly.testPoint", mainImage.Path).Type(); nil != err || typ != reflect.TypeOf(testPoint{}) {
+		t.Fatalf("SymbolHandle.Type() got = %v, %v", typ, err)
+	}
+	if value, err := asm.Symbol("github.com/go-hotfix/assembly.testGlobalInt", mainImage.Path).Global(); nil != err || value.UnsafeAddr() != uintptr(unsafe.Pointer(&testGlobalInt)) {
+		t.Fatalf("SymbolHandle.Global() got = %v, %v", value, err)
+	}
+	if priority := asm.(*dwarfAssembly).policy.Priority; 0 != len(priority) {
+		t.Fatalf("SymbolHandle changed the resolve policy priority to %v", priority)
+	}
+}
+
+func AssemblyTestRuntime(t *testing.T, asm DwarfAssembly) {
+	procs, err := asm.GOMAXPROCS()
+	if nil != err {
+		t.Fatalf("GOMAXPROCS() error: %v", err)
+	}
+	if want := runtime.GOMAXPROCS(0); want != procs {
+		t.Fatalf("GOMAXPROCS() got = %v, want %v", procs, want)
+	}
+
+	stats, err := asm.SchedStats()
+	if nil != err {
+		t.Fatalf("SchedStats() error: %v", err)
+	}
+	if stats.MaxThreads <= 0 || stats.Threads <= 0 || stats.GoroutineIDGen == 0 {
+		t.Fatalf("SchedStats() got = %+v", stats)
+	}
+
+	for _, name := range RuntimeStatNames() {
+		if _, err = asm.RuntimeStat(name); nil != err {
+			t.Fatalf("RuntimeStat(%q) error: %v", name, err)
+		}
+	}
+	runtime.GC()
+	if numgc, _ := asm.RuntimeStat("memstats.numgc"); numgc <= 0 {
+		t.Fatalf("RuntimeStat(\"memstats.numgc\") got = %v after runtime.GC()", numgc)
+	}
+
+	modules, err := asm.RuntimeModules()
+	if nil != err {
+		t.Fatalf("RuntimeModules() error: %v", err)
+	}
+	pc, err := asm.FindFuncPc("github.com/go-hotfix/assembly.testAdd")
+	if nil != err {
+		t.Fatalf("FindFuncPc() error: %v", err)
+	}
+	if 0 == len(modules) || pc < modules[0].Text || pc >= modules[0].EText {
+		t.Fatalf("RuntimeModules() got = %+v, want text containing %#x", modules, pc)
+	}
+
+	select {
+	case <-asm.WarmCaches(WarmOptions{Pause: time.Millisecond}):
+	case <-time.After(time.Minute):
+		t.Fatalf("WarmCaches() did not finish")
+	}
+
+	usage := asm.MemoryUsage()
+	if 0 == usage.DWARF || 0 == usage.Indexes || 0 == usage.Globals || 0 == len(usage.TypeCaches) || usage.Total() < usage.DWARF+usage.Indexes {
+		t.Fatalf("MemoryUsage() got = %+v", usage)
+	}
+
+	g, err := asm.CurrentGoroutine()
+	if nil != err {
+		t.Fatalf("CurrentGoroutine() error: %v", err)
+	}
+	if 0 == g.ID || 0 == g.Addr || GoroutineRunning != g.Status {
+		t.Fatalf("CurrentGoroutine() got = %+v", g)
+	}
+}
+
+func AssemblyTestFuzzTargets(t *testing.T, asm DwarfAssembly) {
+	targets := asm.FuzzTargets(func(name string) bool {
+		return strings.HasPrefix(name, "github.com/go-hotfix/assembly.test")
+	})
+
+	var found = make(map[string]reflect.Type)
+	for _, target := range targets {
+		found[target.Name] = target.Type
+	}
+
+	if found["github.com/go-hotfix/assembly.testAdd"] != reflect.TypeOf(testAdd) {
+		t.Fatalf("FuzzTargets() testAdd got = %v, want %v", found["github.com/go-hotfix/assembly.testAdd"], reflect.TypeOf(testAdd))
+	}
+
+	for _, name := range []string{"github.com/go-hotfix/assembly.testABI", "github.com/go-hotfix/assembly.testLoadInt"} {
+		if _, ok := found[name]; ok {
+			t.Fatalf("FuzzTargets() got %s", name)
+		}
+	}
+}
+
+func testMakeCounter(start int) func() int {
+	return func() int {
+		start++
+		return start
+	}
+}
+
+func testMakeDouble() func(int) int {
+	return func(x int) int {
+		return x * 2
+	}
+}
+
+func AssemblyTestClosureCall(t *testing.T, asm DwarfAssembly) {
+	_, _ = testMakeCounter(0)(), testMakeDouble()(1)
+
+	if _, err := asm.FindFunc("github.com/go-hotfix/assembly.testMakeCounter.func1", false); !errors.Is(err, ErrClosureNotSupported) {
+		t.Fatalf("FindFunc(capturing closure) error = %v, want ErrClosureNotSupported", err)
+	}
+	out, err := asm.CallFunc("github.com/go-hotfix/assembly.testMakeDouble.func1", false, []reflect.Value{reflect.ValueOf(21)})
+	if nil != err || 42 != out[0].Int() {
+		t.Fatalf("CallFunc(static closure) got = %v, %v", out, err)
+	}
+}
+
+func AssemblyTestContext(t *testing.T, _ DwarfAssembly) {
+	// a fresh assembly, the globals cache of the shared one is already built.
+	asm, err := NewDwarfAssembly()
+	if nil != err {
+		t.Fatalf("NewDwarfAssembly() error: %v", err)
+	}
+	defer asm.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err = asm.ForeachTypeContext(ctx, func(string) bool { return true }); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ForeachTypeContext() error = %v, want context.Canceled", err)
+	}
+	if err = asm.LoadGlobalsContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("LoadGlobalsContext() error = %v, want context.Canceled", err)
+	}
+	if _, err = asm.SearchPluginsContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("SearchPluginsContext() error = %v, want context.Canceled", err)
+	}
+	if err = asm.LoadGlobalsContext(context.Background()); nil != err {
+		t.Fatalf("LoadGlobalsContext() error: %v", err)
+	}
+}
+
+func AssemblyTestPrologue(t *testing.T, asm DwarfAssembly) {
+	if "amd64" != runtime.GOARCH && "arm64" != runtime.GOARCH {
+		t.Skipf("AnalyzePrologue() not supported on %s", runtime.GOARCH)
+	}
+
+	for name, stackCheck := range map[string]bool{
+		"github.com/go-hotfix/assembly.testMakeCounter": true,
+		"github.com/go-hotfix/assembly.testAdd":         false,
+	} {
+		prologue, err := asm.AnalyzePrologue(name)
+		if nil != err {
+			t.Fatalf("AnalyzePrologue(%s) error: %v", name, err)
+		}
+		var size int
+		for _, inst := range prologue.Instructions {
+			if uint64(size) != inst.Offset {
+				t.Fatalf("AnalyzePrologue(%s) instruction at %#x, want %#x", name, inst.Offset, size)
+			}
+			size += len(inst.Bytes)
+		}
+		if stackCheck != prologue.StackCheck || 0 == size || size != prologue.Size || uint64(size) >= prologue.FuncSize {
+			t.Fatalf("AnalyzePrologue(%s) got = %+v", name, prologue)
+		}
+	}
+}
+
+func AssemblyTestPatchSite(t *testing.T, asm DwarfAssembly) {
+	const target = "github.com/go-hotfix/assembly.testAdd"
+	mul := func(a, b int) int { return a * b }
+
+	site, err := asm.PatchSite(target, reflect.ValueOf(mul))
+	if nil != err {
+		t.Fatalf("PatchSite() error: %v", err)
+	}
+	pc, _ := asm.FindFuncPc(target)
+	if target != site.Target || pc != site.TargetPC || 0 == site.TargetSize || uint64(reflect.ValueOf(mul).Pointer()) != site.ReplacementPC {
+		t.Fatalf("PatchSite() got = %+v", site)
+	}
+
+	if _, err = asm.PatchSite(target, reflect.ValueOf(testDivide)); nil == err {
+		t.Fatalf("PatchSite() accepted a replacement of another signature")
+	}
+	if _, err = asm.PatchSite("runtime.nanotime", reflect.ValueOf(func() int64 { return 0 })); !errors.Is(err, ErrProtectedSymbol) {
+		t.Fatalf("PatchSite(runtime.nanotime) error = %v, want ErrProtectedSymbol", err)
+	}
+	if _, err = asm.PatchSiteNamed(target, "github.com/go-hotfix/assembly.testMakeDouble.func1"); nil == err {
+		t.Fatalf("PatchSiteNamed() accepted a replacement of another signature")
+	}
+}
+
+func AssemblyTestGenerics(t *testing.T, asm DwarfAssembly) {
+	const ratio = "github.com/go-hotfix/assembly.testGlobalRatio"
+	_ = testGlobalRatio
+
+	if got, err := GetGlobal[float64](asm, ratio); nil != err || 0.5 != got {
+		t.Fatalf("GetGlobal() got = %v, %v", got, err)
+	}
+	if _, err := GetGlobal[string](asm, ratio); nil == err {
+		t.Fatalf("GetGlobal[string]() accepted a float64 global")
+	}
+	if err := SetGlobal(asm, ratio, 0.25); nil != err || 0.25 != testGlobalRatio {
+		t.Fatalf("SetGlobal() got = %v, %v", testGlobalRatio, err)
+	}
+
+	add, err := TypedFunc[func(a, b int) int](asm, "github.com/go-hotfix/assembly.testAdd")
+	if nil != err || 5 != add(2, 3) {
+		t.Fatalf("TypedFunc(testAdd) error: %v", err)
+	}
+	maxOf, err := TypedFunc[func(a int, nums ...int) int](asm, "github.com/go-hotfix/assembly.testMax")
+	if nil != err || 9 != maxOf(1, 9, 4) {
+		t.Fatalf("TypedFunc(testMax) error: %v", err)
+	}
+	if _, err = TypedFunc[func(a int) int](asm, "github.com/go-hotfix/assembly.testAdd"); nil == err {
+		t.Fatalf("TypedFunc() accepted a mismatched signature")
+	}
+}
+
+func AssemblyTestAdminHandler(t *testing.T, asm DwarfAssembly) {
+	const target = "github.com/go-hotfix/assembly.testScale"
+	const token = "secret"
+	extra := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusAccepted) })
+	handler := AdminHandler(asm, AdminOptions{Patches: NewPatchManager(asm), Token: token, Handlers: map[string]http.Handler{"/extra": extra}})
+	serveAs := func(auth, method, url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, url, nil)
+		if "" != auth {
+			req.Header.Set("Authorization", auth)
+		}
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	serve := func(method, url string) *httptest.ResponseRecorder {
+		return serveAs("Bearer "+token, method, url)
+	}
+
+	if rec := serveAs("", http.MethodGet, "/healthz"); http.StatusOK != rec.Code {
+		t.Fatalf("GET /healthz got %d: %s", rec.Code, rec.Body)
+	}
+	for _, auth := range []string{"", "Bearer wrong"} {
+		if rec := serveAs(auth, http.MethodPost, "/patches?target="+target+"&replacement=github.com/go-hotfix/assembly.testAdd"); http.StatusUnauthorized != rec.Code {
+			t.Fatalf("POST /patches with authorization %q got %d: %s", auth, rec.Code, rec.Body)
+		}
+		if rec := serveAs(auth, http.MethodPost, "/patches/rollback-all"); http.StatusUnauthorized != rec.Code {
+			t.Fatalf("POST /patches/rollback-all with authorization %q got %d: %s", auth, rec.Code, rec.Body)
+		}
+	}
+	if rec := serveAs("", http.MethodGet, "/extra"); http.StatusUnauthorized != rec.Code {
+		t.Fatalf("GET /extra without authorization got %d", rec.Code)
+	}
+	if rec := serve(http.MethodGet, "/extra"); http.StatusAccepted != rec.Code {
+		t.Fatalf("GET /extra got %d: %s", rec.Code, rec.Body)
+	}
+	rec := httptest.NewRecorder()
+	AdminHandler(asm, AdminOptions{}).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/patches/rollback-all", nil))
+	if http.StatusUnauthorized != rec.Code {
+		t.Fatalf("POST /patches/rollback-all without a configured token got %d", rec.Code)
+	}
+	if err := ServeAdmin(context.Background(), asm, AdminOptions{Addr: ":0"}); nil == err {
+		t.Fatalf("ServeAdmin() on every interface without a token succeeded")
+	}
+	for addr, want := range map[string]bool{"127.0.0.1:1": true, "[::1]:1": true, "localhost:1": true, ":1": false, "0.0.0.0:1": false, "10.0.0.1:1": false} {
+		if got := LoopbackAddr(addr); want != got {
+			t.Fatalf("LoopbackAddr(%q) got = %v, want %v", addr, got, want)
+		}
+	}
+	if rec := serve(http.MethodPost, "/patches?target="+target+"&replacement=github.com/go-hotfix/assembly.testAdd"); http.StatusCreated != rec.Code {
+		t.Fatalf("POST /patches got %d: %s", rec.Code, rec.Body)
+	}
+	if got := testScale(2, 3); 5 != got {
+		t.Fatalf("testScale() after POST /patches got = %d, want 5", got)
+	}
+	if rec := serve(http.MethodPost, "/patches?target="+target+"&replacement=github.com/go-hotfix/assembly.testAdd"); http.StatusConflict != rec.Code {
+		t.Fatalf("POST /patches twice got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = serve(http.MethodGet, "/patches")
+	var infos []PatchInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &infos); nil != err || 1 != len(infos) || target != infos[0].Target {
+		t.Fatalf("GET /patches got %d: %s, %v", rec.Code, rec.Body, err)
+	}
+
+	if rec := serve(http.MethodGet, "/patches/rollback?target="+target); http.StatusMethodNotAllowed != rec.Code {
+		t.Fatalf("GET /patches/rollback got %d", rec.Code)
+	}
+	if rec := serve(http.MethodPost, "/patches/rollback?target="+target); http.StatusNoContent != rec.Code {
+		t.Fatalf("POST /patches/rollback got %d: %s", rec.Code, rec.Body)
+	}
+	if got := testScale(2, 3); 20 != got {
+		t.Fatalf("testScale() after POST /patches/rollback got = %d, want 20", got)
+	}
+	if rec := serve(http.MethodPost, "/patches/rollback?target="+target); http.StatusNotFound != rec.Code {
+		t.Fatalf("POST /patches/rollback of an unpatched target got %d", rec.Code)
+	}
+}
+
+func AssemblyTestRemote(t *testing.T, asm DwarfAssembly) {
+	if "linux" != runtime.GOOS {
+		t.Skipf("AttachProcess() test reads /proc/self/mem")
+	}
+	// the test process inspects itself through the out-of-process path.
+	remote, err := AttachProcess(os.Getpid())
+	if nil != err {
+		t.Fatalf("AttachProcess() error: %v", err)
+	}
+	defer remote.Close()
+
+	var value int
+	if err = remote.ReadGlobal("github.com/go-hotfix/assembly.testGlobalInt", &value); nil != err || testGlobalInt != value {
+		t.Fatalf("ReadGlobal() got = %v, %v, want %v", value, err, testGlobalInt)
+	}
+	var text string
+	if err = remote.ReadGlobal("github.com/go-hotfix/assembly.testGlobalString", &text); !errors.Is(err, ErrUnsafeArgument) {
+		t.Fatalf("ReadGlobal(string) error = %v, want ErrUnsafeArgument", err)
+	}
+	if _, err = remote.mem.WriteMemory(uint64(uintptr(unsafe.Pointer(&testGlobalInt))), []byte{0}); !errors.Is(err, ErrNotSupport) || value != testGlobalInt {
+		t.Fatalf("WriteMemory() error = %v, global %d, want ErrNotSupport", err, testGlobalInt)
+	}
+
+	modules, err := remote.RuntimeModules()
+	if nil != err {
+		t.Fatalf("RuntimeModules() error: %v", err)
+	}
+	local, _ := asm.RuntimeModules()
+	if 0 == len(modules) || local[0] != modules[0] {
+		t.Fatalf("RuntimeModules() got = %+v, want %+v", modules, local)
+	}
+}
+
+func AssemblyTestPin(t *testing.T, asm DwarfAssembly) {
+	buf := reflect.New(reflect.TypeOf([64]byte{}))
+	pinned, err := asm.Pin(buf)
+	if nil != err {
+		t.Fatalf("Pin() error: %v", err)
+	}
+	if buf.Pointer() != pinned.Addr() || 1 != asm.PinCount() {
+		t.Fatalf("Pin() got addr %#x, count %d, want %#x, 1", pinned.Addr(), asm.PinCount(), buf.Pointer())
+	}
+
+	global, err := asm.FindGlobal("github.com/go-hotfix/assembly.testGlobalInt")
+	if nil != err {
+		t.Fatalf("FindGlobal() error: %v", err)
+	}
+	pinnedGlobal, err := asm.Pin(global)
+	if nil != err || uintptr(unsafe.Pointer(&testGlobalInt)) != pinnedGlobal.Addr() {
+		t.Fatalf("Pin(global) got = %v, %v", pinnedGlobal, err)
+	}
+
+	pinned.Unpin()
+	pinned.Unpin()
+	pinnedGlobal.Unpin()
+	if 0 != asm.PinCount() || 0 != pinned.Addr() {
+		t.Fatalf("Unpin() left %d pins", asm.PinCount())
+	}
+	if _, err = asm.Pin(reflect.ValueOf(42)); nil == err {
+		t.Fatalf("Pin() accepted an unaddressable value")
+	}
+}
+
+func AssemblyTestPatchMetrics(t *testing.T, asm DwarfAssembly) {
+	before := asm.PatchMetrics()
+	if _, err := asm.PatchSite("github.com/go-hotfix/assembly.testAdd", reflect.ValueOf(42)); nil == err {
+		t.Fatalf("PatchSite() accepted a non function replacement")
+	}
+	after := asm.PatchMetrics()
+	if before.ValidationFailures+1 != after.ValidationFailures {
+		t.Fatalf("PatchMetrics() got %d validation failures, want %d", after.ValidationFailures, before.ValidationFailures+1)
+	}
+
+	var buf strings.Builder
+	if err := WritePrometheus(&buf, PatchMetrics{Active: 2, Calls: map[string]uint64{"main.f": 7}}); nil != err {
+		t.Fatalf("WritePrometheus() error: %v", err)
+	}
+	for _, want := range []string{"# TYPE assembly_patches_active gauge\n", "assembly_patches_active 2\n", `assembly_patch_calls_total{target="main.f"} 7`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("WritePrometheus() got = %s, want %s", buf.String(), want)
+		}
+	}
+}
+
+func AssemblyTestPatchFunc(t *testing.T, asm DwarfAssembly) {
+	const target = "github.com/go-hotfix/assembly.testScale"
+	before := asm.PatchMetrics()
+
+	patch, err := asm.PatchFunc(target, reflect.ValueOf(testAdd))
+	if nil != err {
+		t.Fatalf("PatchFunc() error: %v", err)
+	}
+	if got := testScale(2, 3); 5 != got {
+		t.Fatalf("testScale() after PatchFunc() got = %d, want 5", got)
+	}
+	if _, err = asm.PatchFunc(target, reflect.ValueOf(testAdd)); !errors.Is(err, ErrAlreadyPatched) {
+		t.Fatalf("PatchFunc() twice error = %v, want ErrAlreadyPatched", err)
+	}
+	if got := asm.PatchMetrics(); before.Active+1 != got.Active {
+		t.Fatalf("PatchMetrics() got %d active patches, want %d", got.Active, before.Active+1)
+	}
+
+	if err = patch.Revert(); nil != err {
+		t.Fatalf("Revert() error: %v", err)
+	}
+	if err = patch.Revert(); nil != err {
+		t.Fatalf("Revert() twice error: %v", err)
+	}
+	if got := testScale(2, 3); 20 != got {
+		t.Fatalf("testScale() after Revert() got = %d, want 20", got)
+	}
+	if got := asm.PatchMetrics(); before.Active != got.Active || before.Rollbacks+1 != got.Rollbacks {
+		t.Fatalf("PatchMetrics() after Revert() got = %+v", got)
+	}
+
+	offset := 100
+	madeFunc := reflect.MakeFunc(reflect.TypeOf(testScale), func(args []reflect.Value) []reflect.Value {
+		return []reflect.Value{reflect.ValueOf(int(args[0].Int()*args[1].Int()) + offset)}
+	})
+	if _, err = asm.PatchSite(target, madeFunc); !errors.Is(err, ErrClosureNotSupported) {
+		t.Fatalf("PatchSite(reflect.MakeFunc) error = %v, want ErrClosureNotSupported", err)
+	}
+	if patch, err = asm.PatchFunc(target, madeFunc); nil != err {
+		t.Fatalf("PatchFunc(reflect.MakeFunc) error: %v", err)
+	}
+	if got := testScale(2, 3); 106 != got {
+		t.Fatalf("testScale() after PatchFunc(reflect.MakeFunc) got = %d, want 106", got)
+	}
+	if err = patch.Revert(); nil != err {
+		t.Fatalf("Revert() error: %v", err)
+	}
+	if got := testScale(2, 3); 20 != got {
+		t.Fatalf("testScale() after Revert() got = %d, want 20", got)
+	}
+
+	asm.SetMutationPolicy(MutationPolicy{CountCalls: true})
+	patch, err = asm.PatchFunc(target, reflect.ValueOf(testAdd))
+	asm.SetMutationPolicy(MutationPolicy{})
+	if nil != err {
+		t.Fatalf("PatchFunc() counting calls error: %v", err)
+	}
+	before = asm.PatchMetrics()
+	if got := testScale(2, 3) + testScale(4, 5); 14 != got {
+		t.Fatalf("testScale() after PatchFunc() counting calls got = %d, want 14", got)
+	}
+	if err = patch.Revert(); nil != err {
+		t.Fatalf("Revert() error: %v", err)
+	}
+	if got := asm.PatchMetrics(); before.Calls[patch.Site.Target]+2 != got.Calls[patch.Site.Target] {
+		t.Fatalf("PatchMetrics() got %d calls of %s, want %d", got.Calls[patch.Site.Target], patch.Site.Target, before.Calls[patch.Site.Target]+2)
+	}
+}
+
+func AssemblyTestScoped(t *testing.T, asm DwarfAssembly) {
+	scoped := asm.Scoped(Scope{Packages: []string{"github.com/go-hotfix/assembly/..."}})
+
+	if out, err := scoped.CallFunc("github.com/go-hotfix/assembly.testAdd", false, []reflect.Value{reflect.ValueOf(1), reflect.ValueOf(2)}); nil != err || 3 != out[0].Int() {
+		t.Fatalf("CallFunc() got = %v, %v", out, err)
+	}
+	if _, err := scoped.FindFunc("fmt.Sprint", true); !errors.Is(err, ErrOutOfScope) {
+		t.Fatalf("FindFunc(fmt.Sprint) error = %v, want ErrOutOfScope", err)
+	}
+	if _, err := scoped.FindType("github.com/go-hotfix/assembly.testPoint"); nil != err {
+		t.Fatalf("FindType() error: %v", err)
+	}
+	if _, err := scoped.FindType("*runtime.g"); !errors.Is(err, ErrOutOfScope) {
+		t.Fatalf("FindType(*runtime.g) error = %v, want ErrOutOfScope", err)
+	}
+	if _, err := scoped.FindGlobal("github.com/go-hotfix/assembly.testGlobalInt"); nil != err {
+		t.Fatalf("FindGlobal() error: %v", err)
+	}
+	if err := scoped.SetGlobal("runtime.buildVersion", reflect.ValueOf("go0")); !errors.Is(err, ErrOutOfScope) {
+		t.Fatalf("SetGlobal(runtime.buildVersion) error = %v, want ErrOutOfScope", err)
+	}
+
+	scoped.ForeachFunc(func(name string, pc uint64) bool {
+		if !strings.HasPrefix(name, "github.com/go-hotfix/assembly") {
+			t.Fatalf("ForeachFunc() yielded %s", name)
+		}
+		return true
+	})
+	scoped.ForeachGlobal(func(name string, value reflect.Value) bool {
+		if !strings.HasPrefix(name, "github.com/go-hotfix/assembly") {
+			t.Fatalf("ForeachGlobal() yielded %s", name)
+		}
+		return true
+	})
+
+	elsewhere := asm.Scoped(Scope{Packages: []string{"github.com/go-hotfix/assembly"}, Images: []string{"libother.so"}})
+	if _, err := elsewhere.FindFunc("github.com/go-hotfix/assembly.testAdd", false); !errors.Is(err, ErrOutOfScope) {
+		t.Fatalf("FindFunc() of another image error = %v, want ErrOutOfScope", err)
+	}
+}
+
+func AssemblyTestOriginal(t *testing.T, asm DwarfAssembly) {
+	const target = "github.com/go-hotfix/assembly.testSum"
+	if _, err := asm.Original(target); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Original() of an unpatched function error = %v, want ErrNotFound", err)
+	}
+
+	patch, err := asm.PatchFunc(target, reflect.ValueOf(testSumTwice))
+	if nil != err {
+		t.Fatalf("PatchFunc() error: %v", err)
+	}
+	defer patch.Revert()
+	original, err := asm.Original(target)
+	if nil != err {
+		t.Fatalf("Original() error: %v", err)
+	}
+	testSumOriginal = original.Interface().(func(int) int)
+
+	if got := testSum(5); 10 != got {
+		t.Fatalf("testSum() after PatchFunc() got = %d, want 10", got)
+	}
+	var got = make(chan int)
+	go func() { got <- testSum(7) }()
+	if n := <-got; 14 != n {
+		t.Fatalf("testSum() growing the stack got = %d, want 14", n)
+	}
+
+	if err = patch.Revert(); nil != err {
+		t.Fatalf("Revert() error: %v", err)
+	}
+	if got := testSum(5); 5 != got {
+		t.Fatalf("testSum() after Revert() got = %d, want 5", got)
+	}
+	if got := testSumOriginal(5); 5 != got {
+		t.Fatalf("Original() after Revert() got = %d, want 5", got)
+	}
+
+	if patch, err = asm.PatchFunc("github.com/go-hotfix/assembly.testInc", reflect.ValueOf(testSumTwice)); nil != err {
+		t.Fatalf("PatchFunc(testInc) error: %v", err)
+	}
+	if original, err = asm.Original("github.com/go-hotfix/assembly.testInc"); nil != err {
+		t.Fatalf("Original(testInc) error: %v", err)
+	}
+	testSumOriginal = original.Interface().(func(int) int)
+	if got := testInc(4); 10 != got {
+		t.Fatalf("testInc() after PatchFunc() got = %d, want 10", got)
+	}
+	if err = patch.Revert(); nil != err || 5 != testInc(4) {
+		t.Fatalf("Revert(testInc) got = %d, %v", testInc(4), err)
+	}
+}
+
+func AssemblyTestSnapshot(t *testing.T, _ DwarfAssembly) {
+	asm, err := NewDwarfAssembly()
+	if nil != err {
+		t.Fatalf("NewDwarfAssembly() error: %v", err)
+	}
+	defer asm.Close()
+	da := asm.(*dwarfAssembly)
+
+	before := da.snapshot()
+	first := before.functions[0]
+	vars := before.packageVars.Len()
+	err = da.extendIndexes(func() error {
+		// delve appends the functions of the new image and sorts the index in place.
+		da.binaryInfo.Functions[0], da.binaryInfo.Functions[1] = da.binaryInfo.Functions[1], da.binaryInfo.Functions[0]
+		da.binaryInfo.Functions = append(da.binaryInfo.Functions, proc.Function{Name: "main.loaded"})
+		return nil
+	})
+	if nil != err {
+		t.Fatalf("extendIndexes() error: %v", err)
+	}
+	if first.Name != before.functions[0].Name || vars != before.packageVars.Len() {
+		t.Fatalf("snapshot() changed by a load: %s, want %s", before.functions[0].Name, first.Name)
+	}
+	if after := da.snapshot(); len(before.functions)+1 != len(after.functions) {
+		t.Fatalf("snapshot() after a load got %d functions, want %d", len(after.functions), len(before.functions)+1)
+	}
+}
+
+func AssemblyTestCallFuncErr(t *testing.T, asm DwarfAssembly) {
+	const name = "github.com/go-hotfix/assembly.testDivide"
+	out, err := asm.CallFuncErr(name, reflect.ValueOf(7), reflect.ValueOf(2))
+	if nil != err || 1 != len(out) || 3 != out[0].Int() {
+		t.Fatalf("CallFuncErr() got = %v, %v", out, err)
+	}
+	if out, err = asm.CallFuncErr(name, reflect.ValueOf(7), reflect.ValueOf(0)); !errors.Is(err, errTestDivideByZero) || 1 != len(out) {
+		t.Fatalf("CallFuncErr() by zero got = %v, %v", out, err)
+	}
+	if out, err = asm.CallFuncErr("github.com/go-hotfix/assembly.testAdd", reflect.ValueOf(1), reflect.ValueOf(2)); nil != err || 3 != out[0].Int() {
+		t.Fatalf("CallFuncErr() without error result got = %v, %v", out, err)
+	}
+	if _, err = asm.CallFuncErr("github.com/go-hotfix/assembly.missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("CallFuncErr() of a missing function error = %v, want ErrNotFound", err)
+	}
+}
+
+func AssemblyTestPatchManager(t *testing.T, asm DwarfAssembly) {
+	const scale, inc = "github.com/go-hotfix/assembly.testScale", "github.com/go-hotfix/assembly.testInc"
+	manager := NewPatchManager(asm)
+	if _, err := manager.Apply(scale, reflect.ValueOf(testAdd)); nil != err {
+		t.Fatalf("Apply(testScale) error: %v", err)
+	}
+	if _, err := manager.Apply(inc, reflect.ValueOf(testSumTwice)); nil != err {
+		t.Fatalf("Apply(testInc) error: %v", err)
+	}
+
+	infos := manager.ListPatches()
+	if 2 != len(infos) || scale != infos[0].Target || inc != infos[1].Target {
+		t.Fatalf("ListPatches() got = %+v", infos)
+	}
+	if "github.com/go-hotfix/assembly.testAdd" != infos[0].Replacement || 0 == len(infos[0].Original) || infos[0].Applied.IsZero() {
+		t.Fatalf("ListPatches() got = %+v", infos[0])
+	}
+
+	if err := manager.Rollback(scale); nil != err || 20 != testScale(2, 3) {
+		t.Fatalf("Rollback() got = %d, %v", testScale(2, 3), err)
+	}
+	if err := manager.Rollback(scale); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Rollback() twice error = %v, want ErrNotFound", err)
+	}
+	if err := manager.RollbackAll(); nil != err || 0 != len(manager.ListPatches()) || 5 != testInc(4) {
+		t.Fatalf("RollbackAll() got = %+v, %v", manager.ListPatches(), err)
+	}
+}
+
+func AssemblyTestPatchFuncSafe(t *testing.T, asm DwarfAssembly) {
+	const target = "github.com/go-hotfix/assembly.testScale"
+
+	var stop atomic.Bool
+	var done = make(chan struct{})
+	go func() {
+		defer close(done)
+		for !stop.Load() {
+			testScale(2, 3)
+		}
+	}()
+	defer func() {
+		stop.Store(true)
+		<-done
+	}()
+
+	patch, err := asm.PatchFuncSafe(target, reflect.ValueOf(testAdd))
+	if nil != err {
+		t.Fatalf("PatchFuncSafe() error: %v", err)
+	}
+	if got := testScale(2, 3); 5 != got {
+		t.Fatalf("testScale() after PatchFuncSafe() got = %d, want 5", got)
+	}
+	if err = patch.Revert(); nil != err {
+		t.Fatalf("Revert() error: %v", err)
+	}
+	if got := testScale(2, 3); 20 != got {
+		t.Fatalf("testScale() after Revert() got = %d, want 20", got)
+	}
+}
+
+func AssemblyTestCheckLayout(t *testing.T, asm DwarfAssembly) {
+	var notified []LayoutChange
+	asm.SetLayoutHandler(func(change LayoutChange) { notified = append(notified, change) })
+	defer asm.SetLayoutHandler(nil)
+
+	changes, err := asm.CheckLayout()
+	if errors.Is(err, ErrNotSupport) {
+		t.Logf("CheckLayout() on %s: %v", runtime.GOOS, err)
+		return
+	}
+	if nil != err {
+		t.Fatalf("CheckLayout() error: %v", err)
+	}
+	if 0 != len(changes) || 0 != len(notified) {
+		t.Fatalf("CheckLayout() got = %+v, notified %+v, want no changes", changes, notified)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err = asm.WatchLayout(ctx, 10*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WatchLayout() error = %v, want context.DeadlineExceeded", err)
+	}
+	if _, err = asm.FindFunc("github.com/go-hotfix/assembly.testAdd", false); nil != err {
+		t.Fatalf("FindFunc() after WatchLayout() error: %v", err)
+	}
+}
+
+func AssemblyTestReloadMainImage(t *testing.T, _ DwarfAssembly) {
+	asm, err := NewDwarfAssembly()
+	if nil != err {
+		t.Fatalf("NewDwarfAssembly() error: %v", err)
+	}
+	defer asm.Close()
+
+	handle, err := asm.AcquireFunc("github.com/go-hotfix/assembly.testAdd", false)
+	if nil != err {
+		t.Fatalf("AcquireFunc() error: %v", err)
+	}
+	defer handle.Release()
+
+	other := filepath.Join(t.TempDir(), "other")
+	if err = os.WriteFile(other, []byte("\xff Go build ID: \"other\"\n \xff"), 0o644); nil != err {
+		t.Fatal(err)
+	}
+	if err = asm.ReloadMainImage(other); !errors.Is(err, ErrBuildIDMismatch) {
+		t.Fatalf("ReloadMainImage(other) error = %v, want ErrBuildIDMismatch", err)
+	}
+
+	exe, err := os.Executable()
+	if nil != err {
+		t.Fatal(err)
+	}
+	if err = asm.ReloadMainImage(exe); nil != err {
+		t.Fatalf("ReloadMainImage() error: %v", err)
+	}
+	if n := asm.References(asm.BinaryInfo().Images[0]); 1 != n {
+		t.Fatalf("References() after ReloadMainImage() got = %d, want 1", n)
+	}
+	fn, err := handle.Value()
+	if nil != err {
+		t.Fatalf("Handle.Value() after ReloadMainImage() error: %v", err)
+	}
+	if out := fn.Call([]reflect.Value{reflect.ValueOf(1), reflect.ValueOf(2)}); 3 != out[0].Int() {
+		t.Fatalf("testAdd() after ReloadMainImage() got = %v, want 3", out[0].Int())
+	}
+	if _, err = asm.FindGlobal("github.com/go-hotfix/assembly.testGlobalString"); nil != err {
+		t.Fatalf("FindGlobal() after ReloadMainImage() error: %v", err)
+	}
+}
+
+func AssemblyTestListImages(t *testing.T, asm DwarfAssembly) {
+	images := asm.ListImages()
+	if 0 == len(images) || !images[0].Main || "" != images[0].PluginPath {
+		t.Fatalf("ListImages() got = %+v, want the main executable first", images)
+	}
+	exe, err := os.Executable()
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !sameFile(exe, images[0].Path) || "" == images[0].GoBuildID {
+		t.Fatalf("ListImages() main image got = %+v", images[0])
+	}
+	if runtime.Version() != images[0].GoVersion || "github.com/go-hotfix/assembly" != images[0].Module {
+		t.Fatalf("ListImages() main image build info got = %+v", images[0])
+	}
+	if err = asm.LoadImageAuto(filepath.Join(t.TempDir(), "missing.so")); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("LoadImageAuto(missing.so) error = %v, want ErrNotFound", err)
+	}
+
+	libraries, err := asm.ListLoadedLibraries()
+	if nil != err || 0 == len(libraries) || !sameFile(exe, libraries[0].Path) || !libraries[0].Loaded {
+		t.Fatalf("ListLoadedLibraries() got = %+v, error: %v", libraries, err)
+	}
+}
+
+func AssemblyTestInlineSites(t *testing.T, asm DwarfAssembly) {
+	// The tests are built without inlining, a function called directly has no inline sites.
+	sites, err := asm.InlineSites("github.com/go-hotfix/assembly.testAdd")
+	if nil != err || 0 != len(sites) {
+		t.Fatalf("InlineSites(testAdd) got = %+v, %v", sites, err)
+	}
+	if _, err = asm.InlineSites("github.com/go-hotfix/assembly.testMissing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("InlineSites(testMissing) error = %v, want ErrNotFound", err)
+	}
+}
+
+func AssemblyTestIndexCache(t *testing.T, _ DwarfAssembly) {
+	const global = "github.com/go-hotfix/assembly.testGlobalString"
+	const typeName = "github.com/go-hotfix/assembly.testPoint"
+	dir := t.TempDir()
+
+	// the last run reads an index whose addresses lie outside the image, it must be discarded.
+	for run := 0; run < 3; run++ {
+		if 2 == run {
+			corruptIndexes(t, dir)
+		}
+		asm, err := NewDwarfAssembly()
+		if nil != err {
+			t.Fatalf("NewDwarfAssembly() error: %v", err)
+		}
+		if err = asm.SetIndexCache(dir); nil != err {
+			t.Fatalf("SetIndexCache() error: %v", err)
+		}
+		value, err := asm.FindGlobal(global)
+		if nil != err || &testGlobalString != value.Addr().Interface().(*string) {
+			t.Fatalf("FindGlobal() run %d got = %v, %v", run, value, err)
+		}
+		typ, err := asm.FindType(typeName)
+		if nil != err || reflect.TypeOf(testPoint{}) != typ {
+			t.Fatalf("FindType() run %d got = %v, %v", run, typ, err)
+		}
+		var points int
+		asm.ForeachImageType(asm.BinaryInfo().Images[0], func(name string, typ reflect.Type) bool {
+			if typeName == name && reflect.TypeOf(testPoint{}) == typ {
+				points++
+			}
+			return true
+		})
+		if 1 != points {
+			t.Fatalf("ForeachImageType() run %d found testPoint %d times", run, points)
+		}
+		asm.Close()
+
+		if files, _ := filepath.Glob(filepath.Join(dir, "*.idx")); 1 != len(files) {
+			t.Fatalf("SetIndexCache() run %d wrote %v, want one index", run, files)
+		}
+	}
+}
+
+// corruptIndexes moves every address of the indexes stored in dir past the end of the image.
+func corruptIndexes(t *testing.T, dir string) {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.idx"))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if nil != err {
+			t.Fatalf("ReadFile() error: %v", err)
+		}
+		var index persistedIndex
+		if err = gob.NewDecoder(bytes.NewReader(data)).Decode(&index); nil != err {
+			t.Fatalf("Decode() error: %v", err)
+		}
+		for name := range index.Types {
+			index.Types[name] = 1 << 46
+		}
+		for i := range index.Globals {
+			index.Globals[i].Addr = 1 << 46
+		}
+		var buf bytes.Buffer
+		if err = gob.NewEncoder(&buf).Encode(&index); nil != err {
+			t.Fatalf("Encode() error: %v", err)
+		}
+		if err = os.WriteFile(file, buf.Bytes(), 0o644); nil != err {
+			t.Fatalf("WriteFile() error: %v", err)
+		}
+	}
+}
+
+func AssemblyTestFindMethodType(t *testing.T, asm DwarfAssembly) {
+	const pkg = "github.com/go-hotfix/assembly."
+	(&testPoint{}).scale(1)
+
+	for _, tc := range []struct {
+		name     string
+		mode     ReceiverMode
+		want     reflect.Type
+		receiver reflect.Type
+	}{
+		{pkg + "(*testPoint).scale", ReceiverFirst, reflect.TypeOf((*testPoint).scale), reflect.TypeOf(&testPoint{})},
+		{pkg + "(*testPoint).scale", ReceiverStrip, reflect.TypeOf(func(float64) {}), reflect.TypeOf(&testPoint{})},
+		{pkg + "testPoint.Sum", ReceiverStrip, reflect.TypeOf(testPoint{}.Sum), reflect.TypeOf(testPoint{})},
+		{pkg + "testAdd", ReceiverStrip, reflect.TypeOf(testAdd), nil},
+	} {
+		ftyp, recv, err := asm.FindMethodType(tc.name, false, tc.mode)
+		if nil != err || tc.want != ftyp || tc.receiver != recv {
+			t.Fatalf("FindMethodType(%s, %d) got = %v, %v, %v, want %v, %v", tc.name, tc.mode, ftyp, recv, err, tc.want, tc.receiver)
+		}
+	}
+}
+
+func AssemblyTestVisibilityPolicy(t *testing.T, asm DwarfAssembly) {
+	defer asm.SetVisibilityPolicy(VisibilityPolicy{})
+
+	funcs := func() map[string]bool {
+		var names = make(map[string]bool)
+		asm.ForeachFunc(func(name string, pc uint64) bool {
+			names[name] = true
+			return true
+		})
+		return names
+	}
+
+	var generated bool
+	for name := range funcs() {
+		if strings.HasPrefix(name, "runtime.") || strings.HasPrefix(name, "fmt.") {
+			t.Fatalf("ForeachFunc() yielded %s with the default visibility", name)
+		}
+		generated = generated || generatedSymbol.MatchString(name)
+	}
+	if generated {
+		t.Fatalf("ForeachFunc() yielded generated functions with the default visibility")
+	}
+	if !funcs()["github.com/go-hotfix/assembly.testAdd"] {
+		t.Fatalf("ForeachFunc() github.com/go-hotfix/assembly.testAdd not found")
+	}
+
+	asm.SetVisibilityPolicy(VisibilityPolicy{Runtime: true})
+	if names := funcs(); !names["runtime.main"] || names["fmt.Sprintf"] {
+		t.Fatalf("ForeachFunc() with Runtime got runtime.main %v, fmt.Sprintf %v", names["runtime.main"], names["fmt.Sprintf"])
+	}
+
+	asm.SetVisibilityPolicy(VisibilityPolicy{Runtime: true, Stdlib: true, Generated: true})
+	generated = false
+	for name := range funcs() {
+		generated = generated || generatedSymbol.MatchString(name)
+	}
+	if !generated {
+		t.Fatalf("ForeachFunc() with Generated yielded no generated functions")
+	}
+
+	var predeclared bool
+	if err := asm.ForeachType(func(name string) bool {
+		predeclared = name == "int"
+		return !predeclared
+	}); nil != err || !predeclared {
+		t.Fatalf("ForeachType() with Stdlib found int = %v, error: %v", predeclared, err)
+	}
+	asm.SetVisibilityPolicy(VisibilityPolicy{})
+	predeclared = false
+	_ = asm.ForeachType(func(name string) bool {
+		predeclared = name == "int"
+		return !predeclared
+	})
+	if predeclared {
+		t.Fatalf("ForeachType() yielded int with the default visibility")
+	}
+}
+
+func AssemblyTestLazyGlobals(t *testing.T, _ DwarfAssembly) {
+	// a fresh assembly, the globals cache of the shared one is already built.
+	asm, err := NewDwarfAssembly()
+	if nil != err {
+		t.Fatalf("NewDwarfAssembly() error: %v", err)
+	}
+	defer asm.Close()
+	da := asm.(*dwarfAssembly)
+
+	global, err := asm.FindGlobal("github.com/go-hotfix/assembly.testGlobalInt")
+	if nil != err || global.Addr().Interface() != &testGlobalInt {
+		t.Fatalf("FindGlobal(testGlobalInt) got = %v, error: %v", global, err)
+	}
+	if _, err = asm.FindGlobal("github.com/go-hotfix/assembly.testGlobalMissing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("FindGlobal(testGlobalMissing) error = %v, want ErrNotFound", err)
+	}
+	if nil != da.globals || 2 != len(da.lazyGlobals.resolved) {
+		t.Fatalf("FindGlobal() built the globals cache or resolved %d names, want 2", len(da.lazyGlobals.resolved))
+	}
+
+	var found bool
+	asm.ForeachGlobal(func(name string, value reflect.Value) bool {
+		found = name == "github.com/go-hotfix/assembly.testGlobalInt"
+		return !found
+	})
+	if !found || nil == da.globals || nil != da.lazyGlobals.resolved {
+		t.Fatalf("ForeachGlobal() found testGlobalInt = %v, cache built = %v", found, nil != da.globals)
+	}
+	if global, err = asm.FindGlobal("github.com/go-hotfix/assembly.testGlobalInt"); nil != err || global.Addr().Interface() != &testGlobalInt {
+		t.Fatalf("FindGlobal(testGlobalInt) after ForeachGlobal got = %v, error: %v", global, err)
+	}
+}
+
+func AssemblyTestTypeGraph(t *testing.T, asm DwarfAssembly) {
+	const pkg = "github.com/go-hotfix/assembly"
+	graph, err := asm.TypeGraph(pkg)
+	if nil != err {
+		t.Fatalf("TypeGraph() error: %v", err)
+	}
+	if !slices.Contains(graph.Roots, pkg+".testPoint") || slices.Contains(graph.Roots, "int") {
+		t.Fatalf("TypeGraph() roots = %v", graph.Roots)
+	}
+
+	point := graph.Node(pkg + ".testPoint")
+	want := []TypeField{{Name: "X", Type: "float64"}, {Name: "Y", Type: "float64", Offset: 8}, {Name: "N", Type: "int", Offset: 16}}
+	if nil == point || reflect.Struct != point.Kind || 24 != point.Size || !reflect.DeepEqual(want, point.Fields) {
+		t.Fatalf("TypeGraph() testPoint = %+v", point)
+	}
+	if node := graph.Node("float64"); nil == node || reflect.Float64 != node.Kind {
+		t.Fatalf("TypeGraph() float64 = %+v", node)
+	}
+
+	// types of other packages reached through fields are part of the graph
+	var binaryInfo string
+	for _, field := range graph.Node(pkg + ".dwarfAssembly").Fields {
+		if field.Name == "binaryInfo" {
+			binaryInfo = field.Type
+		}
+	}
+	if node := graph.Node(binaryInfo); nil == node || reflect.Pointer != node.Kind || nil == graph.Node(node.Elem) {
+		t.Fatalf("TypeGraph() binaryInfo field type %q = %+v", binaryInfo, node)
+	}
+
+	data, err := json.Marshal(graph)
+	if nil != err {
+		t.Fatalf("json.Marshal(TypeGraph) error: %v", err)
+	}
+	var decoded TypeGraph
+	if err = json.Unmarshal(data, &decoded); nil != err || !reflect.DeepEqual(graph.Node(pkg+".testPoint"), decoded.Node(pkg+".testPoint")) {
+		t.Fatalf("json round trip of TypeGraph got = %+v, error: %v", decoded.Node(pkg+".testPoint"), err)
+	}
+
+	if _, err = asm.TypeGraph("github.com/go-hotfix/missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("TypeGraph(missing) error = %v, want ErrNotFound", err)
+	}
+}
+
+func AssemblyTestFindGlobals(t *testing.T, asm DwarfAssembly) {
+	names := func(matches []GlobalMatch) []string {
+		var names []string
+		for _, m := range matches {
+			names = append(names, m.Name)
+		}
+		return names
+	}
+
+	matches, err := asm.FindGlobals("go-hotfix/assembly.testGlobal*")
+	if nil != err {
+		t.Fatalf("FindGlobals() error: %v", err)
+	}
+	if got := names(matches); !slices.Contains(got, "github.com/go-hotfix/assembly.testGlobalInt") || !slices.Contains(got, "github.com/go-hotfix/assembly.testGlobalString") {
+		t.Fatalf("FindGlobals(glob) got = %v", got)
+	}
+	if matches, err = asm.FindGlobals("hotfix/assembly.testGlobalInt"); nil != err || 0 != len(matches) {
+		t.Fatalf("FindGlobals() matched inside a path element: %v, error: %v", names(matches), err)
+	}
+
+	matches, err = asm.FindGlobals(`re:assembly\.testGlobalInt$`)
+	if nil != err || 1 != len(matches) || matches[0].Value.Addr().Interface() != &testGlobalInt {
+		t.Fatalf("FindGlobals(regexp) got = %v, error: %v", names(matches), err)
+	}
+	if _, err = asm.FindGlobals("re:("); nil == err {
+		t.Fatalf("FindGlobals(invalid regexp) error = nil")
+	}
+}
+
+func AssemblyTestHealthy(t *testing.T, asm DwarfAssembly) {
+	if err := asm.Healthy(); nil != err {
+		t.Fatalf("Healthy() error: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	HealthHandler(asm).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if http.StatusOK != rec.Code {
+		t.Fatalf("HealthHandler() status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	patch, err := asm.PatchFunc("github.com/go-hotfix/assembly.testScale", reflect.ValueOf(testAdd))
+	if nil != err {
+		t.Fatalf("PatchFunc() error: %v", err)
+	}
+	defer patch.Revert()
+	if err = asm.Healthy(); nil != err {
+		t.Fatalf("Healthy() with a patch error: %v", err)
+	}
+
+	// overwrite the jump behind the back of the patch
+	if err = writeCode(uintptr(patch.Site.TargetPC), patch.displaced); nil != err {
+		t.Fatalf("writeCode() error: %v", err)
+	}
+	if err = asm.Healthy(); !errors.Is(err, ErrUnhealthy) || !strings.Contains(err.Error(), "overwritten") {
+		t.Fatalf("Healthy() with an overwritten patch error = %v, want ErrUnhealthy", err)
+	}
+	rec = httptest.NewRecorder()
+	HealthHandler(asm).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if http.StatusServiceUnavailable != rec.Code {
+		t.Fatalf("HealthHandler() with an overwritten patch status = %d", rec.Code)
+	}
+
+	if err = patch.Revert(); nil != err {
+		t.Fatalf("Revert() error: %v", err)
+	}
+	if err = asm.Healthy(); nil != err {
+		t.Fatalf("Healthy() after Revert() error: %v", err)
+	}
+}
+
+func AssemblyTestForeachInPackage(t *testing.T, asm DwarfAssembly) {
+	const pkg = "github.com/go-hotfix/assembly"
+
+	var funcs []string
+	asm.ForeachFuncInPackage(pkg, func(name string, pc uint64) bool {
+		funcs = append(funcs, name)
+		return true
+	})
+	if !slices.Contains(funcs, pkg+".testAdd") || !slices.IsSorted(funcs) {
+		t.Fatalf("ForeachFuncInPackage() got %d functions, testAdd found = %v", len(funcs), slices.Contains(funcs, pkg+".testAdd"))
+	}
+	for _, name := range funcs {
+		if !strings.HasPrefix(name, pkg+".") && !strings.HasPrefix(name, pkg+"/") {
+			t.Fatalf("ForeachFuncInPackage() yielded %s", name)
+		}
+	}
+
+	var partial bool
+	asm.ForeachFuncInPackage("github.com/go-hotfix/assembl", func(name string, pc uint64) bool {
+		partial = true
+		return false
+	})
+	if partial {
+		t.Fatalf("ForeachFuncInPackage() matched a partial path element")
+	}
+
+	var types []string
+	if err := asm.ForeachTypeInPackage(pkg, func(name string) bool {
+		types = append(types, name)
+		return true
+	}); nil != err || !slices.Contains(types, pkg+".testPoint") || slices.Contains(types, "int") {
+		t.Fatalf("ForeachTypeInPackage() got %d types, error: %v", len(types), err)
+	}
+
+	var global reflect.Value
+	asm.ForeachGlobalInPackage(pkg, func(name string, value reflect.Value) bool {
+		if name == pkg+".testGlobalInt" {
+			global = value
+		}
+		return !global.IsValid()
+	})
+	if !global.IsValid() || global.Addr().Interface() != &testGlobalInt {
+		t.Fatalf("ForeachGlobalInPackage() testGlobalInt got = %v", global)
+	}
+}
+
+func AssemblyTestConstants(t *testing.T, asm DwarfAssembly) {
+	const pkg = "github.com/go-hotfix/assembly."
+	c, err := asm.FindConst(pkg + "testGreen")
+	if nil != err || pkg+"testColor" != c.Type || int64(testGreen) != c.Value || nil == c.Image {
+		t.Fatalf("FindConst(testGreen) got = %+v, error: %v", c, err)
+	}
+	if _, err = asm.FindConst(pkg + "testMissing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("FindConst(testMissing) error = %v, want ErrNotFound", err)
+	}
+
+	var values = make(map[string]int64)
+	var names []string
+	asm.ForeachConst(func(c Constant) bool {
+		names = append(names, c.Name)
+		if c.Type == pkg+"testColor" {
+			values[c.Name] = c.Value
+		}
+		return true
+	})
+	if want := map[string]int64{pkg + "testRed": 1, pkg + "testGreen": 2}; !reflect.DeepEqual(want, values) || !slices.IsSorted(names) {
+		t.Fatalf("ForeachConst() testColor values = %v, want %v", values, want)
+	}
+}
+
+func AssemblyTestRateLimit(t *testing.T, asm DwarfAssembly) {
+	const pkg = "github.com/go-hotfix/assembly."
+	asm.SetRateLimitPolicy(RateLimitPolicy{Calls: RateLimit{Quota: 2}, Globals: RateLimit{Rate: 0.001, Burst: 1}, Patches: RateLimit{Quota: 1}})
+	defer asm.SetRateLimitPolicy(RateLimitPolicy{})
+
+	args := []reflect.Value{reflect.ValueOf(1), reflect.ValueOf(2)}
+	for i := 0; i < 2; i++ {
+		if _, err := asm.CallFunc(pkg+"testAdd", false, args); nil != err {
+			t.Fatalf("CallFunc() %d within the quota error: %v", i, err)
+		}
+	}
+	if _, err := asm.CallFunc(pkg+"testAdd", false, args); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("CallFunc() past the quota error = %v, want ErrRateLimited", err)
+	}
+	var shape testShape = testPoint{X: 2, Y: 3}
+	if _, err := asm.CallInterfaceMethod(reflect.ValueOf(&shape).Elem(), "area", nil); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("CallInterfaceMethod() past the quota error = %v, want ErrRateLimited", err)
+	}
+	sum, err := asm.BindMethod(reflect.ValueOf(testPoint{X: 1, Y: 2}), "Sum")
+	if nil != err {
+		t.Fatalf("BindMethod() error: %v", err)
+	}
+	func() {
+		defer func() {
+			if err, _ := recover().(error); !errors.Is(err, ErrRateLimited) {
+				t.Fatalf("BindMethod() value called past the quota panicked with %v, want ErrRateLimited", err)
+			}
+		}()
+		sum.Call(nil)
+	}()
+
+	if err := asm.SetGlobal(pkg+"testGlobalInt", reflect.ValueOf(testGlobalInt)); nil != err {
+		t.Fatalf("SetGlobal() within the burst error: %v", err)
+	}
+	if err := asm.SetGlobal(pkg+"testGlobalInt", reflect.ValueOf(testGlobalInt)); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("SetGlobal() past the burst error = %v, want ErrRateLimited", err)
+	}
+
+	patch, err := asm.PatchFunc(pkg+"testScale", reflect.ValueOf(testAdd))
+	if nil != err {
+		t.Fatalf("PatchFunc() within the quota error: %v", err)
+	}
+	if err = patch.Revert(); nil != err {
+		t.Fatalf("Revert() error: %v", err)
+	}
+	if _, err = asm.PatchFunc(pkg+"testScale", reflect.ValueOf(testAdd)); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("PatchFunc() past the quota error = %v, want ErrRateLimited", err)
+	}
+
+	asm.SetRateLimitPolicy(RateLimitPolicy{})
+	if _, err = asm.CallFunc(pkg+"testAdd", false, args); nil != err {
+		t.Fatalf("CallFunc() after lifting the limits error: %v", err)
+	}
+}
+
+func AssemblyTestLines(t *testing.T, asm DwarfAssembly) {
+	pc, err := asm.FindFuncPc("github.com/go-hotfix/assembly.testAdd")
+	if nil != err {
+		t.Fatalf("FindFuncPc() error: %v", err)
+	}
+	file, line := asm.FileLine(pc)
+	if !strings.HasSuffix(file, "/assembly_test.go") || 0 == line {
+		t.Fatalf("FileLine(testAdd) got = %s:%d", file, line)
+	}
+	if none, noLine := asm.FileLine(0); "" != none || 0 != noLine {
+		t.Fatalf("FileLine(0) got = %s:%d", none, noLine)
+	}
+
+	// the line following the declaration is the first statement of the body
+	pcs, err := asm.LineToPC(filepath.Base(filepath.Dir(file))+"/assembly_test.go", line+1)
+	if nil != err || 0 == len(pcs) || !slices.IsSorted(pcs) {
+		t.Fatalf("LineToPC(assembly_test.go:%d) got = %x, error: %v", line+1, pcs, err)
+	}
+	if got, _ := asm.FileLine(pcs[0]); got != file {
+		t.Fatalf("FileLine(LineToPC()) got = %s, want %s", got, file)
+	}
+	if _, err = asm.LineToPC("missing_test.go", 1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("LineToPC(missing_test.go) error = %v, want ErrNotFound", err)
+	}
+	if _, err = asm.LineToPC(file, 1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("LineToPC(%s:1) error = %v, want ErrNotFound", file, err)
+	}
+}
+
+func AssemblyTestMethodValues(t *testing.T, asm DwarfAssembly) {
+	const name = "github.com/go-hotfix/assembly.testPointSum"
+	saved := testPointSum
+	defer func() { testPointSum = saved }()
+
+	mv, err := asm.FindMethodValue(name)
+	if nil != err {
+		t.Fatalf("FindMethodValue() error: %v", err)
+	}
+	if "github.com/go-hotfix/assembly.testPoint.Sum" != mv.Method || mv.Method+"-fm" != mv.Wrapper {
+		t.Fatalf("FindMethodValue() got = %+v", mv)
+	}
+	if recv, ok := mv.Receiver.Interface().(testPoint); !ok || 1 != recv.X || 2 != recv.Y {
+		t.Fatalf("FindMethodValue() receiver = %v", mv.Receiver)
+	}
+
+	if err = asm.RebindMethodValue(name, reflect.ValueOf(testPoint{X: 5, Y: 6}), ""); nil != err {
+		t.Fatalf("RebindMethodValue(receiver) error: %v", err)
+	}
+	if got := testPointSum(); 11 != got {
+		t.Fatalf("RebindMethodValue(receiver) got = %v, want 11", got)
+	}
+	if got := saved(); 3 != got {
+		t.Fatalf("RebindMethodValue() changed the previous value, got = %v, want 3", got)
+	}
+	if err = asm.RebindMethodValue(name, reflect.Value{}, "area"); nil != err {
+		t.Fatalf("RebindMethodValue(area) error: %v", err)
+	}
+	if got := testPointSum(); 30 != got {
+		t.Fatalf("RebindMethodValue(area) got = %v, want 30", got)
+	}
+
+	if _, err = asm.FindMethodValue("github.com/go-hotfix/assembly.testGlobalInt"); !errors.Is(err, ErrNotSupport) {
+		t.Fatalf("FindMethodValue(testGlobalInt) error = %v, want ErrNotSupport", err)
+	}
+}
+
+func AssemblyTestSymbolizeStack(t *testing.T, asm DwarfAssembly) {
+	callers := make([]uintptr, 8)
+	callers = callers[:runtime.Callers(1, callers)]
+	pcs := make([]uint64, 0, len(callers)+1)
+	for _, pc := range callers {
+		pcs = append(pcs, uint64(pc))
+	}
+	pcs = append(pcs, 0)
+
+	stack := asm.SymbolizeStack(pcs)
+	if len(pcs) != len(stack) || 0 != len(stack[len(pcs)-1].Frames) {
+		t.Fatalf("SymbolizeStack() got = %+v", stack)
+	}
+	var frames []StackFrame
+	for _, pc := range stack[:len(callers)] {
+		if nil == pc.Image || 0 == len(pc.Frames) {
+			t.Fatalf("SymbolizeStack(%#x) got = %+v", pc.PC, pc)
+		}
+		frames = append(frames, pc.Frames...)
+	}
+
+	// the line tables of the assembly of the runtime record no file names
+	want := runtime.CallersFrames(callers)
+	for i := 0; ; i++ {
+		frame, more := want.Next()
+		if strings.HasPrefix(frame.Function, "runtime.") {
+			break
+		}
+		if i >= len(frames) {
+			t.Fatalf("SymbolizeStack() got %d frames, missing %s", len(frames), frame.Function)
+		}
+		if frame.Function != frames[i].Func || frame.File != frames[i].File || frame.Line != frames[i].Line {
+			t.Fatalf("SymbolizeStack() frame %d got = %+v, want %s %s:%d", i, frames[i], frame.Function, frame.File, frame.Line)
+		}
+		if !more {
+			break
+		}
+	}
+}
+
+func AssemblyTestSelfTest(t *testing.T, asm DwarfAssembly) {
+	report, err := asm.SelfTest()
+	if nil != err {
+		t.Fatalf("SelfTest() error: %v", err)
+	}
+	if runtime.Version() != report.GoVersion || runtime.GOARCH != report.GOARCH || 3 != len(report.Checks) {
+		t.Fatalf("SelfTest() got = %+v", report)
+	}
+	for _, check := range report.Checks {
+		if "" == check.Name || nil != check.Err {
+			t.Fatalf("SelfTest() check got = %+v", check)
+		}
+	}
+}
+
+func AssemblyTestTypeCompatibility(t *testing.T, asm DwarfAssembly) {
+	img := asm.BinaryInfo().Images[0]
+	mismatches, err := asm.CheckTypeCompatibility("github.com/go-hotfix/assembly.testPoint", img, img)
+	if nil != err || 0 != len(mismatches) {
+		t.Fatalf("CheckTypeCompatibility(testPoint) got = %v, error: %v", mismatches, err)
+	}
+	if _, err = asm.CheckTypeCompatibility("github.com/go-hotfix/assembly.testMissing", img, img); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("CheckTypeCompatibility(testMissing) error = %v, want ErrNotFound", err)
+	}
+}
+
+func AssemblyTestClosures(t *testing.T, asm DwarfAssembly) {
+	const parent = "github.com/go-hotfix/assembly.AssemblyTestExecutor"
+	closures := asm.Closures(func(name string) bool { return name == parent })
+	if 0 == len(closures) {
+		t.Fatalf("Closures() no closure of %s", parent)
+	}
+	for _, closure := range closures {
+		if !strings.HasPrefix(closure.Name, parent+".func") || !strings.HasSuffix(closure.File, "assembly_test.go") || 0 == closure.Line {
+			t.Fatalf("Closures() got = %+v", closure)
+		}
+	}
+}
+
+func AssemblyTestPartialLoad(t *testing.T, _ DwarfAssembly) {
+	asm, err := NewDwarfAssembly()
+	if nil != err {
+		t.Fatalf("NewDwarfAssembly() error: %v", err)
+	}
+	defer asm.Close()
+
+	corrupt := filepath.Join(t.TempDir(), "corrupt.so")
+	if err = os.WriteFile(corrupt, []byte("\x7fELF corrupt"), 0o644); nil != err {
+		t.Fatal(err)
+	}
+
+	var loadErr *LoadError
+	if err = asm.LoadImage(corrupt, 0x10000); !errors.As(err, &loadErr) {
+		t.Fatalf("LoadImage(corrupt) got = %v, want *LoadError", err)
+	}
+	if imageErrs := asm.ImageErrors(); 1 != len(imageErrs) || corrupt != imageErrs[0].Path {
+		t.Fatalf("ImageErrors() got = %v", imageErrs)
+	}
+	if _, err = asm.FindFunc("github.com/go-hotfix/assembly.testAdd", false); nil != err {
+		t.Fatalf("FindFunc() after partial load error: %v", err)
+	}
+}
+
+func AssemblyTestMethod(t *testing.T, asm DwarfAssembly) {
+	const typeName = "github.com/go-hotfix/assembly.testPoint"
+	(&testPoint{}).scale(1)
+
+	if f, err := asm.FindMethod("*"+typeName, "scale"); nil != err || "github.com/go-hotfix/assembly.(*testPoint).scale" != f.Name {
+		t.Fatalf("FindMethod(scale) got = %v, %v", f, err)
+	}
+
+	out, err := asm.CallMethod(typeName, "Sum", reflect.ValueOf(&testPoint{X: 1, Y: 2}), nil)
+	if nil != err {
+		t.Fatalf("CallMethod(Sum) error: %v", err)
+	}
+	if 3.0 != out[0].Float() {
+		t.Fatalf("CallMethod(Sum) got = %v, want 3", out[0].Float())
+	}
+
+	var p = testPoint{X: 1, Y: 2}
+	if _, err = asm.CallMethod(typeName, "scale", reflect.ValueOf(&p).Elem(), []reflect.Value{reflect.ValueOf(2.0)}); nil != err {
+		t.Fatalf("CallMethod(scale) error: %v", err)
+	}
+	if 2 != p.X || 4 != p.Y {
+		t.Fatalf("CallMethod(scale) got = %+v", p)
+	}
+
+	if _, err = asm.CallMethod(typeName, "scale", reflect.ValueOf(p), []reflect.Value{reflect.ValueOf(2.0)}); nil == err {
+		t.Fatalf("CallMethod(scale) on an unaddressable receiver succeeded")
+	}
+
+	sum, err := asm.BindMethod(reflect.ValueOf(p), "Sum")
+	if nil != err {
+		t.Fatalf("BindMethod(Sum) error: %v", err)
+	}
+	scale, err := asm.BindMethod(reflect.ValueOf(&p), "scale")
+	if nil != err {
+		t.Fatalf("BindMethod(scale) error: %v", err)
+	}
+	scale.Interface().(func(float64))(0.5)
+	if 1 != p.X || 2 != p.Y {
+		t.Fatalf("BindMethod(scale) got = %+v", p)
+	}
+	if got := sum.Interface().(func() float64)(); 6 != got {
+		t.Fatalf("BindMethod(Sum) got = %v, want the receiver copied at bind time", got)
+	}
+}
+
+func AssemblyTestInterfaceMethod(t *testing.T, asm DwarfAssembly) {
+	var shape testShape = testPoint{X: 2, Y: 3}
+	_ = shape.scaled(1).area()
+
+	out, err := asm.CallInterfaceMethod(reflect.ValueOf(&shape).Elem(), "scaled", []reflect.Value{reflect.ValueOf(2.0)})
+	if nil != err {
+		t.Fatalf("CallInterfaceMethod(scaled) error: %v", err)
+	}
+	if got := out[0].Interface().(testShape).area(); 24 != got {
+		t.Fatalf("CallInterfaceMethod(scaled) got area = %v, want 24", got)
+	}
+
+	var value any = testPoint{X: 2, Y: 3}
+	if out, err = asm.CallInterfaceMethod(reflect.ValueOf(&value).Elem(), "area", nil); nil != err || 6 != out[0].Float() {
+		t.Fatalf("CallInterfaceMethod(area) on any got = %v, %v", out, err)
+	}
+}
+
+func AssemblyTestFuncOrigin(t *testing.T, asm DwarfAssembly) {
+	testDeferAdd()
+	_ = testPointSum()
+
+	for wrapper, want := range map[string]string{
+		"github.com/go-hotfix/assembly.testPoint.Sum-fm":        "github.com/go-hotfix/assembly.testPoint.Sum",
+		"github.com/go-hotfix/assembly.testDeferAdd.deferwrap1": "github.com/go-hotfix/assembly.testAdd",
+		"github.com/go-hotfix/assembly.testAdd":                 "github.com/go-hotfix/assembly.testAdd",
+	} {
+		origin, err := asm.FuncOrigin(wrapper)
+		if nil != err {
+			t.Fatalf("FuncOrigin(%s) error: %v", wrapper, err)
+		}
+		if origin.Name != want {
+			t.Fatalf("FuncOrigin(%s) got = %v, want %v", wrapper, origin.Name, want)
+		}
+	}
+}
+
+func AssemblyTestExecutor(t *testing.T, asm DwarfAssembly) {
+	exec, err := asm.NewExecutor("github.com/go-hotfix/assembly.testAdd", false, 4)
+	if nil != err {
+		t.Fatalf("NewExecutor() error: %v", err)
+	}
+
+	const calls = 100
+	var results [calls]int64
+	err = exec.Run(calls, func(i int) []reflect.Value {
+		return []reflect.Value{reflect.ValueOf(i), reflect.ValueOf(testAdd(i, 0))}
+	}, func(i int, out []reflect.Value, err error) {
+		if nil != err {
+			t.Errorf("Executor.Run() call %d error: %v", i, err)
+			return
+		}
+		results[i] = out[0].Int()
+	})
+	if nil != err {
+		t.Fatalf("Executor.Run() error: %v", err)
+	}
+
+	for i, got := range results {
+		if got != int64(2*i) {
+			t.Fatalf("Executor.Run() call %d got = %v, want %v", i, got, 2*i)
+		}
+	}
+
+	err = exec.Run(2, func(i int) []reflect.Value {
+		return []reflect.Value{reflect.ValueOf("1"), reflect.ValueOf(i)}[i:]
+	}, func(i int, out []reflect.Value, err error) {
+		if nil == err || strings.Contains(err.Error(), "panicked") {
+			t.Errorf("Executor.Run() call %d with mismatched arguments error = %v", i, err)
+		}
+	})
+	if nil != err {
+		t.Fatalf("Executor.Run() error: %v", err)
+	}
+
+	asm.SetRateLimitPolicy(RateLimitPolicy{Calls: RateLimit{Quota: 1}})
+	var limited atomic.Int32
+	err = exec.Run(2, func(i int) []reflect.Value {
+		return []reflect.Value{reflect.ValueOf(i), reflect.ValueOf(i)}
+	}, func(i int, out []reflect.Value, err error) {
+		if errors.Is(err, ErrRateLimited) {
+			limited.Add(1)
+		}
+	})
+	asm.SetRateLimitPolicy(RateLimitPolicy{})
+	if nil != err || 1 != limited.Load() {
+		t.Fatalf("Executor.Run() over the call quota error = %v, %d calls limited, want 1", err, limited.Load())
+	}
+
+	exec.Close()
+	if err = exec.Run(1, nil, nil); !errors.Is(err, ErrReleased) {
+		t.Fatalf("Executor.Run() after Close error = %v, want %v", err, ErrReleased)
+	}
+}
+
+func AssemblyTestExecutorUnsafePointer(t *testing.T, _ DwarfAssembly) {
+	// a new assembly, the workers fill the section and build id caches checking the pointers.
+	asm, err := NewDwarfAssembly()
+	if nil != err {
+		t.Fatalf("NewDwarfAssembly() error: %v", err)
+	}
+	defer asm.Close()
+	asm.SetCallPolicy(CallPolicy{AllowUnsafePointer: true})
+
+	const workers = 8
+	exec, err := asm.NewExecutor("github.com/go-hotfix/assembly.testLoadInt", false, workers)
+	if nil != err {
+		t.Fatalf("NewExecutor() error: %v", err)
+	}
+	defer exec.Close()
+
+	// the calls are made in rounds each worker joins before any starts, so the caches are filled
+	// by calls no lock orders even when the workers share one thread.
+	const rounds = 8
+	var arrived [rounds]atomic.Int32
+	var start [rounds]chan struct{}
+	for r := range start {
+		start[r] = make(chan struct{})
+	}
+	err = exec.Run(rounds*workers, func(i int) []reflect.Value {
+		if workers == arrived[i/workers].Add(1) {
+			close(start[i/workers])
+		}
+		<-start[i/workers]
+		return []reflect.Value{reflect.ValueOf(unsafe.Pointer(&testGlobalInt))}
+	}, func(i int, out []reflect.Value, err error) {
+		if nil != err || int64(testGlobalInt) != out[0].Int() {
+			t.Errorf("Executor.Run() call %d got = %v, %v, want %v", i, out, err, testGlobalInt)
+		}
+	})
+	if nil != err {
+		t.Fatalf("Executor.Run() error: %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+// Package assemblytest builds Go plugin fixtures on the fly and loads them into a DwarfAssembly,
+// so hotfix flows can be integration tested against real images.
+package assemblytest
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"plugin"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"github.com/go-hotfix/assembly"
+)
+
+// BuildOptions customizes the build of a plugin fixture.
+type BuildOptions struct {
+	// Name is the module path and file name of the plugin, defaults to the test name.
+	Name string
+	// Flags are extra arguments passed to go build.
+	Flags []string
+	// Env is appended to the environment of go build.
+	Env []string
+}
+
+// BuildPlugin writes files, a map of file names to Go sources of package main, into a temporary
+// module and builds it with -buildmode=plugin, returning the path of the shared object.
+// The plugin inherits the -gcflags, -ldflags, -tags and -trimpath settings the running binary was
+// built with, since the runtime refuses plugins compiled with different flags.
+func BuildPlugin(t testing.TB, files map[string]string, opts BuildOptions) string {
+	t.Helper()
+	return build(t, files, opts, "plugin", ".so")
+}
+
+// build builds the fixture module of files with -buildmode=mode into a file named after the
+// fixture with the extension ext.
+func build(t testing.TB, files map[string]string, opts BuildOptions, mode, ext string) string {
+	t.Helper()
+
+	name := opts.Name
+	if name == "" {
+		name = strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	}
+
+	dir := t.TempDir()
+	// the files of the caller are not modified, they may be shared by several builds
+	files = maps.Clone(files)
+	if _, ok := files["go.mod"]; !ok {
+		files["go.mod"] = "module " + name + "\n\ngo 1.21\n"
+	}
+	for file, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(src), 0o644); err != nil {
+			t.Fatalf("assemblytest: write %s: %v", file, err)
+		}
+	}
+
+	out := filepath.Join(dir, name+ext)
+	args := append([]string{"build", "-buildmode=" + mode, "-o", out}, hostBuildFlags()...)
+	args = append(args, opts.Flags...)
+
+	cmd := exec.Command("go", append(args, ".")...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), opts.Env...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("assemblytest: go %s: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return out
+}
+
+// hostBuildFlags returns the go build flags recorded in the build info of the running binary
+// that must match between a host and its plugins.
+func hostBuildFlags() []string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+
+	var flags []string
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "-gcflags", "-ldflags", "-tags", "-asmflags":
+			flags = append(flags, s.Key+"="+s.Value)
+		case "-trimpath", "-race", "-msan", "-asan":
+			if s.Value == "true" {
+				flags = append(flags, s.Key)
+			}
+		}
+	}
+	return flags
+}
+
+// LoadPlugin opens the plugin at path and loads its debug information into asm.
+// The plugin is checked against the host first, for a precise diagnosis of incompatibilities.
+func LoadPlugin(t testing.TB, asm assembly.DwarfAssembly, path string) *plugin.Plugin {
+	t.Helper()
+
+	if err := asm.PreflightPlugin(path); err != nil {
+		t.Fatalf("assemblytest: preflight plugin %s: %v", path, err)
+	}
+
+	p, err := plugin.Open(path)
+	if err != nil {
+		t.Fatalf("assemblytest: open plugin %s: %v", path, err)
+	}
+
+	if err = asm.LoadImageAuto(path); err != nil {
+		t.Fatalf("assemblytest: load image %s: %v", path, err)
+	}
+	return p
+}
+
+// RequireFunc fails the test unless the function name can be resolved.
+func RequireFunc(t testing.TB, asm assembly.FuncResolver, name string) {
+	t.Helper()
+	if _, err := asm.FindFuncEntry(name); err != nil {
+		t.Fatalf("assemblytest: func %s: %v", name, err)
+	}
+}
+
+// RequireType fails the test unless the type name can be resolved.
+func RequireType(t testing.TB, asm assembly.TypeFinder, name string) {
+	t.Helper()
+	if _, err := asm.FindType(name); err != nil {
+		t.Fatalf("assemblytest: type %s: %v", name, err)
+	}
+}
+
+// RequireGlobal fails the test unless the global variable name can be resolved.
+func RequireGlobal(t testing.TB, asm assembly.GlobalAccessor, name string) {
+	t.Helper()
+	if _, err := asm.FindGlobalAddr(name); err != nil {
+		t.Fatalf("assemblytest: global %s: %v", name, err)
+	}
+}
+
+// RequireMissing fails the test if the function name can be resolved.
+func RequireMissing(t testing.TB, asm assembly.FuncResolver, name string) {
+	t.Helper()
+	if _, err := asm.FindFuncEntry(name); !errors.Is(err, assembly.ErrNotFound) {
+		t.Fatalf("assemblytest: func %s: %v", name, fmt.Errorf("want %w, got %v", assembly.ErrNotFound, err))
+	}
+}
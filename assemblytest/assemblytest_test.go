@@ -0,0 +1,161 @@
+package assemblytest
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-hotfix/assembly"
+)
+
+func TestBuildPlugin(t *testing.T) {
+	asm, err := assembly.NewDwarfAssembly()
+	if nil != err {
+		t.Fatalf("NewDwarfAssembly() error: %v", err)
+	}
+	defer asm.Close()
+
+	path := BuildPlugin(t, map[string]string{
+		"main.go": `package main
+
+var Counter = 1
+
+func Inc(n int) int {
+	Counter += n
+	return Counter
+}
+`,
+	}, BuildOptions{Name: "fixture"})
+
+	p := LoadPlugin(t, asm, path)
+	if _, err = p.Lookup("Inc"); nil != err {
+		t.Fatalf("Lookup(Inc) error: %v", err)
+	}
+
+	plugins, err := asm.SearchPlugins()
+	if nil != err {
+		t.Fatalf("SearchPlugins() error: %v", err)
+	}
+	var found bool
+	for _, plugin := range plugins {
+		if plugin.Path == path {
+			found = true
+			if "" == plugin.GoVersion || "" == plugin.BuildID || 0 == plugin.Packages || 0 == len(plugin.PkgHashes) {
+				t.Fatalf("SearchPlugins() got = %+v", plugin)
+			}
+			if nil == plugin.Module || "fixture" != plugin.Module.PluginPath {
+				t.Fatalf("SearchPlugins() got = %+v", plugin)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("SearchPlugins() %s not found", path)
+	}
+
+	symbols, err := asm.ListPluginSymbols("fixture")
+	if nil != err {
+		t.Fatalf("ListPluginSymbols() error: %v", err)
+	}
+	var kinds = make(map[string]assembly.SymbolKind)
+	for _, symbol := range symbols {
+		if 0 == symbol.Addr {
+			t.Fatalf("ListPluginSymbols() %s has no address", symbol.Name)
+		}
+		kinds[symbol.Name] = symbol.Kind
+	}
+	if assembly.SymbolFunc != kinds["fixture.Inc"] || assembly.SymbolGlobal != kinds["fixture.Counter"] || 2 != len(kinds) {
+		t.Fatalf("ListPluginSymbols() got = %+v", symbols)
+	}
+
+	RequireFunc(t, asm, "fixture.Inc")
+
+	files := map[string]string{"main.go": "package main\n\nfunc Dec(n int) int { return n - 1 }\n"}
+	trimmed := BuildPlugin(t, files, BuildOptions{Name: "trimmed", Flags: []string{"-trimpath"}})
+	if _, ok := files["go.mod"]; ok || 1 != len(files) {
+		t.Fatalf("BuildPlugin() modified the files of the caller: %v", files)
+	}
+	var compatErr *assembly.PluginCompatibilityError
+	if err = asm.PreflightPlugin(trimmed); !errors.As(err, &compatErr) {
+		t.Fatalf("PreflightPlugin(trimmed) got = %v, want *PluginCompatibilityError", err)
+	}
+	if m := compatErr.Mismatches[0]; "build setting" != m.Kind || "-trimpath" != m.Name {
+		t.Fatalf("PreflightPlugin(trimmed) got = %v", compatErr)
+	}
+	RequireGlobal(t, asm, "fixture.Counter")
+	RequireMissing(t, asm, "fixture.Dec")
+}
+
+func TestUnloadImage(t *testing.T) {
+	asm, err := assembly.NewDwarfAssembly()
+	if nil != err {
+		t.Fatalf("NewDwarfAssembly() error: %v", err)
+	}
+	defer asm.Close()
+
+	path := BuildPlugin(t, map[string]string{
+		"main.go": "package main\n\nvar Factor = 2\n\nfunc Twice(n int) int { return n * Factor }\n",
+	}, BuildOptions{Name: "unload"})
+	LoadPlugin(t, asm, path)
+	RequireFunc(t, asm, "unload.Twice")
+	// the module of the plugin has no dot in its path, its code is still not hidden as stdlib.
+	var yielded bool
+	asm.ForeachFunc(func(name string, pc uint64) bool {
+		yielded = "unload.Twice" == name
+		return !yielded
+	})
+	if !yielded {
+		t.Fatalf("ForeachFunc() did not yield unload.Twice with the default visibility policy")
+	}
+	libraries, err := asm.ListLoadedLibraries()
+	if nil != err {
+		t.Fatalf("ListLoadedLibraries() error: %v", err)
+	}
+	var listed bool
+	for _, lib := range libraries {
+		listed = listed || (lib.Loaded && 0 != lib.Base && strings.HasSuffix(lib.Path, "unload.so"))
+	}
+	if !listed {
+		t.Fatalf("ListLoadedLibraries() got = %+v, want unload.so loaded", libraries)
+	}
+	img := asm.BinaryInfo().Images[len(asm.BinaryInfo().Images)-1]
+	if images := asm.ListImages(); 2 != len(images) || images[1].Main || "" == images[1].PluginPath {
+		t.Fatalf("ListImages() got = %+v", images)
+	}
+
+	handle, err := asm.AcquireFunc("unload.Twice", false)
+	if nil != err {
+		t.Fatalf("AcquireFunc() error: %v", err)
+	}
+	if err = asm.UnloadImage(img.Path); !errors.Is(err, assembly.ErrImageInUse) {
+		t.Fatalf("UnloadImage() with a handle got = %v, want ErrImageInUse", err)
+	}
+	handle.Release()
+
+	patch, err := asm.PatchFunc("unload.Twice", reflect.ValueOf(func(n int) int { return n * 3 }))
+	if nil != err {
+		t.Fatalf("PatchFunc() error: %v", err)
+	}
+	if err = asm.UnloadImage(img.Path); !errors.Is(err, assembly.ErrImageInUse) {
+		t.Fatalf("UnloadImage() with a patch got = %v, want ErrImageInUse", err)
+	}
+	if err = asm.UnloadImageForce(img.Path); nil != err {
+		t.Fatalf("UnloadImageForce() error: %v", err)
+	}
+	if !patch.Reverted() {
+		t.Fatalf("UnloadImageForce() left the patch of %s applied", patch.Site.Target)
+	}
+	RequireMissing(t, asm, "unload.Twice")
+	if images := asm.ListImages(); 1 != len(images) {
+		t.Fatalf("ListImages() after UnloadImage() got = %+v", images)
+	}
+	if _, err = asm.FindGlobalAddr("unload.Factor"); !errors.Is(err, assembly.ErrNotFound) {
+		t.Fatalf("FindGlobalAddr() after UnloadImage() got = %v, want ErrNotFound", err)
+	}
+	if err = asm.UnloadImage(img.Path); !errors.Is(err, assembly.ErrNotFound) {
+		t.Fatalf("UnloadImage() twice got = %v, want ErrNotFound", err)
+	}
+	if err = asm.UnloadImage(asm.BinaryInfo().Images[0].Path); !errors.Is(err, assembly.ErrNotSupport) {
+		t.Fatalf("UnloadImage(main) got = %v, want ErrNotSupport", err)
+	}
+}
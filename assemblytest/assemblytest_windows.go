@@ -0,0 +1,23 @@
+package assemblytest
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// BuildDLL writes files, a map of file names to Go sources of package main, into a temporary
+// module and builds it with -buildmode=c-shared, returning the path of the DLL. Building a DLL
+// requires cgo, the test is skipped without a C compiler.
+func BuildDLL(t testing.TB, files map[string]string, opts BuildOptions) string {
+	t.Helper()
+
+	out, err := exec.Command("go", "env", "CC").Output()
+	if cc := strings.Fields(string(out)); err != nil || len(cc) == 0 {
+		t.Skip("assemblytest: building a DLL requires a C compiler")
+	} else if _, err = exec.LookPath(cc[0]); err != nil {
+		t.Skipf("assemblytest: building a DLL requires a C compiler: %v", err)
+	}
+	opts.Env = append([]string{"CGO_ENABLED=1"}, opts.Env...)
+	return build(t, files, opts, "c-shared", ".dll")
+}
@@ -0,0 +1,46 @@
+package assemblytest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-hotfix/assembly"
+	"golang.org/x/sys/windows"
+)
+
+func TestLoadDLL(t *testing.T) {
+	asm, err := assembly.NewDwarfAssembly()
+	if nil != err {
+		t.Fatalf("NewDwarfAssembly() error: %v", err)
+	}
+	defer asm.Close()
+
+	path := BuildDLL(t, map[string]string{
+		"main.go": "package main\n\nimport \"C\"\n\nvar Factor = 2\n\n//export Twice\nfunc Twice(n C.int) C.int { return n * C.int(Factor) }\n\nfunc main() {}\n",
+	}, BuildOptions{Name: "fixture"})
+	dll, err := windows.LoadDLL(path)
+	if nil != err {
+		t.Fatalf("LoadDLL() error: %v", err)
+	}
+	defer dll.Release()
+
+	if err = asm.LoadImageAuto(path); nil != err {
+		t.Fatalf("LoadImageAuto() error: %v", err)
+	}
+	RequireFunc(t, asm, "main.Twice")
+	RequireGlobal(t, asm, "main.Factor")
+
+	plugins, err := asm.SearchPlugins()
+	if nil != err {
+		t.Fatalf("SearchPlugins() error: %v", err)
+	}
+	var found bool
+	for _, plugin := range plugins {
+		if strings.EqualFold(plugin.Path, path) {
+			found = "" != plugin.GoVersion && 0 != plugin.Packages
+		}
+	}
+	if !found {
+		t.Fatalf("SearchPlugins() got = %+v, want %s with its build info", plugins, path)
+	}
+}
@@ -0,0 +1,316 @@
+// Command assembly-admin is a ready-to-run agent serving a Go process over HTTP, configured by
+// flags or the ASSEMBLY_ADMIN_* environment variable named after each flag.
+//
+// With -pid it inspects another process through the debug information of its executable,
+// without writing to it:
+//
+//	$ assembly-admin -pid 4242 -listen 127.0.0.1:6062
+//
+//	GET /healthz                       200 while the process is readable
+//	GET /modules                       the module data list of the process, as JSON
+//	GET /globals?name=n[&size=s]       the address of the global n, and its first s bytes in hex
+//	GET /memory?addr=a&size=s          s bytes at address a, in hex
+//
+// With -service it runs a service built with -buildmode=plugin and the build flags of the agent,
+// calling its exported func Main(context.Context) error, and serves the patch manager and the
+// layout watcher of assembly.ServeAdmin for it, along with the loading of patch plugins:
+//
+//	$ assembly-admin -service ./orders.so -watch 1m -token "$TOKEN"
+//
+//	POST /plugins?path=p               open the patch plugin p, its functions become replacements
+//
+// The reads of process memory and every change to the process require -token as a bearer token,
+// and without a token the agent only listens on loopback addresses. It exits when Main returns.
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"plugin"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/go-hotfix/assembly"
+)
+
+// maxReadSize bounds the bytes one request reads from the process.
+const maxReadSize = 1 << 20
+
+func main() {
+	pid := flag.Int("pid", envInt("ASSEMBLY_ADMIN_PID"), "process to inspect")
+	service := flag.String("service", envString("ASSEMBLY_ADMIN_SERVICE", ""), "service plugin to run and patch")
+	listen := flag.String("listen", envString("ASSEMBLY_ADMIN_LISTEN", "127.0.0.1:6062"), "address to serve on")
+	token := flag.String("token", envString("ASSEMBLY_ADMIN_TOKEN", ""), "bearer token authorizing memory reads and changes")
+	watch := flag.Duration("watch", envDuration("ASSEMBLY_ADMIN_WATCH"), "layout watcher interval of -service, 0 runs none")
+	flag.Parse()
+
+	if (*pid > 0) == (*service != "") {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *token == "" && !assembly.LoopbackAddr(*listen) {
+		log.Fatalf("assembly-admin: %s is not a loopback address, it requires -token", *listen)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var err error
+	if *pid > 0 {
+		err = inspect(ctx, *pid, *listen, *token)
+	} else {
+		err = host(ctx, *service, assembly.AdminOptions{Addr: *listen, WatchInterval: *watch, Token: *token})
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatalf("assembly-admin: %v", err)
+	}
+}
+
+// inspect serves the inspection endpoints of the process pid on listen until ctx is done.
+func inspect(ctx context.Context, pid int, listen, token string) error {
+	remote, err := assembly.AttachProcess(pid)
+	if err != nil {
+		return err
+	}
+	defer remote.Close()
+
+	server := &http.Server{Addr: listen, Handler: newHandler(remote, token), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("assembly-admin: serving process %d on %s", pid, listen)
+	if err = server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// host runs the service plugin at path in this process and serves its admin surface with opts
+// until ctx is done, the service returns or the admin surface fails, returning the first error.
+func host(ctx context.Context, path string, opts assembly.AdminOptions) error {
+	asm, err := assembly.NewDwarfAssembly()
+	if err != nil {
+		return err
+	}
+	defer asm.Close()
+	run, err := openService(asm, path)
+	if err != nil {
+		return err
+	}
+	opts.Patches = assembly.NewPatchManager(asm)
+	opts.Handlers = hostHandlers(asm)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, 2)
+	go func() { errs <- assembly.ServeAdmin(ctx, asm, opts) }()
+	go func() { errs <- run(ctx) }()
+
+	log.Printf("assembly-admin: running %s, serving on %s", path, opts.Addr)
+	err = <-errs
+	cancel()
+	<-errs
+	return err
+}
+
+// openService opens the service plugin at path and returns its Main function.
+func openService(asm assembly.DwarfAssembly, path string) (func(context.Context) error, error) {
+	p, err := openPlugin(asm, path)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := p.Lookup("Main")
+	if err != nil {
+		return nil, err
+	}
+	run, ok := sym.(func(context.Context) error)
+	if !ok {
+		return nil, fmt.Errorf("%s: Main is a %T, want a func(context.Context) error", path, sym)
+	}
+	return run, nil
+}
+
+// openPlugin opens the plugin at path and loads its debug information into asm, after checking
+// it against the agent for a precise diagnosis of incompatibilities.
+func openPlugin(asm assembly.DwarfAssembly, path string) (*plugin.Plugin, error) {
+	if err := asm.PreflightPlugin(path); err != nil {
+		return nil, err
+	}
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if err = asm.LoadImageAuto(path); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// hostHandlers are the endpoints -service adds to the admin surface of assembly.ServeAdmin.
+func hostHandlers(asm assembly.DwarfAssembly) map[string]http.Handler {
+	return map[string]http.Handler{
+		"/plugins": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				w.Header().Set("Allow", "POST")
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			path := r.URL.Query().Get("path")
+			if path == "" {
+				http.Error(w, "path is required", http.StatusBadRequest)
+				return
+			}
+			if _, err := openPlugin(asm, path); err != nil {
+				writeError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	}
+}
+
+// newHandler serves the inspection endpoints of remote, the reads of its memory are authorized
+// by token.
+func newHandler(remote *assembly.RemoteAssembly, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := remote.RuntimeModules(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/modules", func(w http.ResponseWriter, r *http.Request) {
+		modules, err := remote.RuntimeModules()
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, modules)
+	})
+	mux.HandleFunc("/globals", func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			http.Error(w, "name is required", http.StatusBadRequest)
+			return
+		}
+		addr, err := remote.GlobalAddr(name)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		global := struct {
+			Name string `json:"name"`
+			Addr uint64 `json:"addr"`
+			Data string `json:"data,omitempty"`
+		}{Name: name, Addr: addr}
+		if r.URL.Query().Has("size") {
+			if !assembly.AdminAuthorized(r, token) {
+				http.Error(w, "a valid admin token is required", http.StatusUnauthorized)
+				return
+			}
+			if global.Data, err = readHex(remote, addr, r.URL.Query().Get("size")); err != nil {
+				writeError(w, err)
+				return
+			}
+		}
+		writeJSON(w, global)
+	})
+	mux.HandleFunc("/memory", func(w http.ResponseWriter, r *http.Request) {
+		if !assembly.AdminAuthorized(r, token) {
+			http.Error(w, "a valid admin token is required", http.StatusUnauthorized)
+			return
+		}
+		addr, err := strconv.ParseUint(r.URL.Query().Get("addr"), 0, 64)
+		if err != nil {
+			http.Error(w, "addr: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, err := readHex(remote, addr, r.URL.Query().Get("size"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		fmt.Fprintln(w, data)
+	})
+	return readOnly(mux)
+}
+
+// readOnly refuses every method but GET and HEAD, the agent never changes the process.
+func readOnly(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// sizeError is a size parameter out of range.
+type sizeError struct{ size string }
+
+func (e *sizeError) Error() string {
+	return fmt.Sprintf("size %q is not between 1 and %d", e.size, maxReadSize)
+}
+
+// readHex reads size bytes at addr of the process and encodes them in hex.
+func readHex(remote *assembly.RemoteAssembly, addr uint64, size string) (string, error) {
+	n, err := strconv.Atoi(size)
+	if err != nil || n <= 0 || n > maxReadSize {
+		return "", &sizeError{size: size}
+	}
+	data, err := remote.ReadMemory(addr, n)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(data), nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError answers err with the status of its cause.
+func writeError(w http.ResponseWriter, err error) {
+	var sizeErr *sizeError
+	switch {
+	case errors.As(err, &sizeErr):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, assembly.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	}
+}
+
+func envString(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func envInt(name string) int {
+	n, _ := strconv.Atoi(os.Getenv(name))
+	return n
+}
+
+func envDuration(name string) time.Duration {
+	d, _ := time.ParseDuration(os.Getenv(name))
+	return d
+}
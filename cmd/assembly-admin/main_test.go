@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"plugin"
+	"runtime"
+	"testing"
+	"unsafe"
+
+	"github.com/go-hotfix/assembly"
+	"github.com/go-hotfix/assembly/assemblytest"
+)
+
+var testCounter uint64 = 0x1122334455667788
+
+const testToken = "secret"
+
+func serveAs(handler http.Handler, auth, method, url string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(method, url, nil)
+	if "" != auth {
+		req.Header.Set("Authorization", auth)
+	}
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandler(t *testing.T) {
+	if "linux" != runtime.GOOS {
+		t.Skipf("the test attaches to itself through /proc/self/mem")
+	}
+	remote, err := assembly.AttachProcess(os.Getpid())
+	if nil != err {
+		t.Fatalf("AttachProcess() error: %v", err)
+	}
+	defer remote.Close()
+	handler := newHandler(remote, testToken)
+	serve := func(method, url string) *httptest.ResponseRecorder {
+		return serveAs(handler, "Bearer "+testToken, method, url)
+	}
+
+	if rec := serve(http.MethodGet, "/healthz"); http.StatusOK != rec.Code {
+		t.Fatalf("GET /healthz got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec := serve(http.MethodGet, "/modules")
+	var modules []assembly.RuntimeModule
+	if err = json.Unmarshal(rec.Body.Bytes(), &modules); nil != err || 0 == len(modules) {
+		t.Fatalf("GET /modules got %d: %s, %v", rec.Code, rec.Body, err)
+	}
+
+	// the package under test is linked under its import path rather than as main.
+	rec = serve(http.MethodGet, "/globals?name=github.com/go-hotfix/assembly/cmd/assembly-admin.testCounter&size=8")
+	var global struct {
+		Addr uint64
+		Data string
+	}
+	want := hex.EncodeToString(unsafe.Slice((*byte)(unsafe.Pointer(&testCounter)), 8))
+	if err = json.Unmarshal(rec.Body.Bytes(), &global); nil != err || uint64(uintptr(unsafe.Pointer(&testCounter))) != global.Addr || want != global.Data {
+		t.Fatalf("GET /globals got %d: %s, %v, want data %s", rec.Code, rec.Body, err, want)
+	}
+
+	if rec = serve(http.MethodGet, fmt.Sprintf("/memory?addr=%#x&size=8", global.Addr)); http.StatusOK != rec.Code || want+"\n" != rec.Body.String() {
+		t.Fatalf("GET /memory got %d: %q, want %q", rec.Code, rec.Body, want)
+	}
+	if rec = serve(http.MethodGet, fmt.Sprintf("/memory?addr=%#x&size=0", global.Addr)); http.StatusBadRequest != rec.Code {
+		t.Fatalf("GET /memory of size 0 got %d", rec.Code)
+	}
+	for _, url := range []string{fmt.Sprintf("/memory?addr=%#x&size=8", global.Addr), "/globals?name=github.com/go-hotfix/assembly/cmd/assembly-admin.testCounter&size=8"} {
+		if rec = serveAs(handler, "", http.MethodGet, url); http.StatusUnauthorized != rec.Code {
+			t.Fatalf("GET %s without a token got %d", url, rec.Code)
+		}
+	}
+	if rec = serveAs(handler, "", http.MethodGet, "/globals?name=github.com/go-hotfix/assembly/cmd/assembly-admin.testCounter"); http.StatusOK != rec.Code {
+		t.Fatalf("GET /globals without size nor token got %d: %s", rec.Code, rec.Body)
+	}
+	if rec = serve(http.MethodGet, "/globals?name=github.com/go-hotfix/assembly/cmd/assembly-admin.missing"); http.StatusNotFound != rec.Code {
+		t.Fatalf("GET /globals of a missing global got %d: %s", rec.Code, rec.Body)
+	}
+	if rec = serve(http.MethodPost, "/modules"); http.StatusMethodNotAllowed != rec.Code {
+		t.Fatalf("POST /modules got %d", rec.Code)
+	}
+}
+
+const testService = `package main
+
+import "context"
+
+//go:noinline
+func Answer() int { return 1 }
+
+func Main(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+`
+
+const testPatch = `package main
+
+//go:noinline
+func Answer() int { return 2 }
+`
+
+func TestHost(t *testing.T) {
+	if "linux" != runtime.GOOS {
+		t.Skipf("the test builds plugins")
+	}
+	asm, err := assembly.NewDwarfAssembly()
+	if nil != err {
+		t.Fatalf("NewDwarfAssembly() error: %v", err)
+	}
+	defer asm.Close()
+
+	service := assemblytest.BuildPlugin(t, map[string]string{"main.go": testService}, assemblytest.BuildOptions{Name: "service"})
+	patch := assemblytest.BuildPlugin(t, map[string]string{"main.go": testPatch}, assemblytest.BuildOptions{Name: "patch"})
+	run, err := openService(asm, service)
+	if nil != err {
+		t.Fatalf("openService() error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- run(ctx) }()
+	defer func() {
+		cancel()
+		if err := <-done; nil != err {
+			t.Fatalf("Main() error: %v", err)
+		}
+	}()
+
+	handler := assembly.AdminHandler(asm, assembly.AdminOptions{Token: testToken, Handlers: hostHandlers(asm)})
+	if rec := serveAs(handler, "", http.MethodPost, "/plugins?path="+patch); http.StatusUnauthorized != rec.Code {
+		t.Fatalf("POST /plugins without a token got %d", rec.Code)
+	}
+	if rec := serveAs(handler, "Bearer "+testToken, http.MethodPost, "/plugins?path="+patch); http.StatusNoContent != rec.Code {
+		t.Fatalf("POST /plugins got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := serveAs(handler, "Bearer "+testToken, http.MethodPost, "/patches?target=service.Answer&replacement=patch.Answer"); http.StatusCreated != rec.Code {
+		t.Fatalf("POST /patches got %d: %s", rec.Code, rec.Body)
+	}
+
+	p, err := plugin.Open(service)
+	if nil != err {
+		t.Fatalf("Open() error: %v", err)
+	}
+	answer, err := p.Lookup("Answer")
+	if nil != err {
+		t.Fatalf("Lookup() error: %v", err)
+	}
+	if got := answer.(func() int)(); 2 != got {
+		t.Fatalf("Answer() after POST /patches got = %d, want 2", got)
+	}
+}
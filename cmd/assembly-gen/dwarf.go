@@ -0,0 +1,210 @@
+package main
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"errors"
+	"fmt"
+)
+
+// param is a formal parameter or result of a function as recorded in DWARF.
+type param struct {
+	name string
+	typ  string
+}
+
+// signature is the DWARF description of a function.
+type signature struct {
+	params  []param
+	results []param
+}
+
+// openDWARF reads the debug information of an ELF, Mach-O or PE executable.
+func openDWARF(path string) (*dwarf.Data, error) {
+	if f, err := elf.Open(path); err == nil {
+		defer f.Close()
+		return f.DWARF()
+	}
+	if f, err := macho.Open(path); err == nil {
+		defer f.Close()
+		return f.DWARF()
+	}
+	if f, err := pe.Open(path); err == nil {
+		defer f.Close()
+		return f.DWARF()
+	}
+	return nil, fmt.Errorf("%s: unrecognized executable format", path)
+}
+
+// lookupStructs finds the struct types named names in data.
+func lookupStructs(data *dwarf.Data, names []string) (map[string]*dwarf.StructType, error) {
+	var structs = make(map[string]*dwarf.StructType)
+	var want = make(map[string]bool)
+	for _, name := range names {
+		want[name] = true
+	}
+
+	reader := data.Reader()
+	for {
+		entry, err := reader.Next()
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			break
+		}
+		if entry.Tag != dwarf.TagCompileUnit && entry.Children {
+			reader.SkipChildren()
+		}
+
+		name, _ := entry.Val(dwarf.AttrName).(string)
+		if !want[name] || structs[name] != nil || (entry.Tag != dwarf.TagStructType && entry.Tag != dwarf.TagTypedef) {
+			continue
+		}
+		typ, err := data.Type(entry.Offset)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		for {
+			typedef, ok := typ.(*dwarf.TypedefType)
+			if !ok {
+				break
+			}
+			typ = typedef.Type
+		}
+		st, ok := typ.(*dwarf.StructType)
+		if !ok {
+			return nil, fmt.Errorf("%s: %s is not a struct", name, typ)
+		}
+		structs[name] = st
+	}
+
+	var missing []error
+	for _, name := range names {
+		if structs[name] == nil {
+			missing = append(missing, fmt.Errorf("type %s not found", name))
+		}
+	}
+	return structs, errors.Join(missing...)
+}
+
+// lookupSymbols finds the signatures of funcs and the types of globals in data.
+func lookupSymbols(data *dwarf.Data, funcs, globals []string) (map[string]*signature, map[string]string, error) {
+	var sigs = make(map[string]*signature)
+	var types = make(map[string]string)
+
+	var wantFunc = make(map[string]bool)
+	for _, name := range funcs {
+		wantFunc[name] = true
+	}
+	var wantGlobal = make(map[string]bool)
+	for _, name := range globals {
+		wantGlobal[name] = true
+	}
+
+	reader := data.Reader()
+	for {
+		entry, err := reader.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+		if entry == nil {
+			break
+		}
+
+		name, _ := entry.Val(dwarf.AttrName).(string)
+		switch {
+		case entry.Tag == dwarf.TagSubprogram && wantFunc[name] && sigs[name] == nil:
+			sig, err := readSignature(data, reader)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", name, err)
+			}
+			sigs[name] = sig
+			continue
+		case entry.Tag == dwarf.TagVariable && wantGlobal[name] && types[name] == "":
+			typ, err := entryTypeName(data, entry)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", name, err)
+			}
+			types[name] = typ
+		}
+		if entry.Tag != dwarf.TagCompileUnit && entry.Children {
+			reader.SkipChildren()
+		}
+	}
+
+	var missing []error
+	for _, name := range funcs {
+		if sigs[name] == nil {
+			missing = append(missing, fmt.Errorf("func %s not found", name))
+		}
+	}
+	for _, name := range globals {
+		if types[name] == "" {
+			missing = append(missing, fmt.Errorf("global %s not found", name))
+		}
+	}
+	return sigs, types, errors.Join(missing...)
+}
+
+// readSignature reads the formal parameters following a subprogram entry.
+func readSignature(data *dwarf.Data, reader *dwarf.Reader) (*signature, error) {
+	var sig signature
+	for {
+		entry, err := reader.Next()
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil || entry.Tag == 0 {
+			return &sig, nil
+		}
+		if entry.Children {
+			reader.SkipChildren()
+		}
+		if entry.Tag != dwarf.TagFormalParameter {
+			continue
+		}
+
+		name, _ := entry.Val(dwarf.AttrName).(string)
+		typ, err := entryTypeName(data, entry)
+		if err != nil {
+			return nil, fmt.Errorf("param %s: %w", name, err)
+		}
+		if isResult, _ := entry.Val(dwarf.AttrVarParam).(bool); isResult {
+			sig.results = append(sig.results, param{name: name, typ: typ})
+		} else {
+			sig.params = append(sig.params, param{name: name, typ: typ})
+		}
+	}
+}
+
+func entryTypeName(data *dwarf.Data, entry *dwarf.Entry) (string, error) {
+	off, ok := entry.Val(dwarf.AttrType).(dwarf.Offset)
+	if !ok {
+		return "", errors.New("no type")
+	}
+	typ, err := data.Type(off)
+	if err != nil {
+		return "", err
+	}
+	return typeName(typ), nil
+}
+
+func typeName(typ dwarf.Type) string {
+	switch typ := typ.(type) {
+	case *dwarf.StructType:
+		return typ.StructName
+	case *dwarf.PtrType:
+		if typ.Common().Name != "" {
+			return typ.Common().Name
+		}
+		return "*" + typeName(typ.Type)
+	default:
+		if name := typ.Common().Name; name != "" {
+			return name
+		}
+		return typ.String()
+	}
+}
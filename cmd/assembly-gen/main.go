@@ -0,0 +1,318 @@
+// Command assembly-gen emits strongly typed wrappers for functions and globals of a built binary,
+// resolved at init through the assembly, so hidden symbols can be used without reflect plumbing.
+//
+//	//go:generate assembly-gen -binary ./bin/app -package orders -o hidden.go -func github.com/acme/app/orders.recalc=Recalc
+//
+// Signatures are read from the DWARF of -binary, which must be built from the same sources.
+// With -stub it instead emits the skeleton of a patch plugin: replacement functions with the
+// signatures of -func and mirrors of the -type structs asserting their layout at compile time.
+// Adding -safe makes each replacement recover a panic of its implementation and fall back to
+// the original function.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// symbolFlags collects repeated symbol flags of the form name[=Alias].
+type symbolFlags []string
+
+func (s *symbolFlags) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *symbolFlags) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+func main() {
+	var funcs, variadics, globals, types symbolFlags
+	binary := flag.String("binary", "", "executable to read signatures from")
+	pkg := flag.String("package", os.Getenv("GOPACKAGE"), "package name of the generated file")
+	importPath := flag.String("importpath", "", "import path of the generated package, its types are referenced unqualified")
+	output := flag.String("o", "assembly_gen.go", "output file")
+	flag.Var(&funcs, "func", "function to wrap, as name[=Alias]; may be repeated")
+	flag.Var(&variadics, "vfunc", "variadic function to wrap, as name[=Alias]; may be repeated")
+	flag.Var(&globals, "global", "global variable to expose, as name[=Alias]; may be repeated")
+	flag.Var(&types, "type", "with -stub, struct type to mirror, as name[=Alias]; may be repeated")
+	stub := flag.Bool("stub", false, "emit a patch skeleton implementing the functions instead of wrappers")
+	safe := flag.Bool("safe", false, "with -stub, fall back to the original function when a replacement panics")
+	flag.Parse()
+
+	if *binary == "" || *pkg == "" || len(funcs)+len(variadics)+len(globals)+len(types) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var src []byte
+	var err error
+	if *stub {
+		src, err = generateStub(*binary, *pkg, *importPath, funcs, variadics, types, *safe)
+	} else {
+		src, err = generate(*binary, *pkg, *importPath, funcs, variadics, globals)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "assembly-gen:", err)
+		os.Exit(1)
+	}
+	if err = os.WriteFile(*output, src, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "assembly-gen:", err)
+		os.Exit(1)
+	}
+}
+
+// wrapped is a symbol and the identifier of its wrapper.
+type wrapped struct {
+	name     string
+	alias    string
+	variadic bool
+}
+
+func parseSymbols(specs []string, variadic bool) []wrapped {
+	var symbols []wrapped
+	for _, spec := range specs {
+		name, alias, ok := strings.Cut(spec, "=")
+		if !ok {
+			alias = defaultAlias(name)
+		}
+		symbols = append(symbols, wrapped{name: name, alias: alias, variadic: variadic})
+	}
+	return symbols
+}
+
+// defaultAlias derives an exported identifier from a symbol name,
+// "pkg.(*Engine).recalc" becomes "Engine_Recalc".
+func defaultAlias(name string) string {
+	slash := strings.LastIndex(name, "/")
+	_, local, _ := strings.Cut(name[slash+1:], ".")
+	var parts []string
+	for _, part := range strings.Split(local, ".") {
+		part = strings.Trim(part, "(*)")
+		if part == "" {
+			continue
+		}
+		r := []rune(part)
+		r[0] = unicode.ToUpper(r[0])
+		parts = append(parts, string(r))
+	}
+	return strings.Join(parts, "_")
+}
+
+func generate(binary, pkg, importPath string, funcSpecs, variadicSpecs, globalSpecs symbolFlags) ([]byte, error) {
+	funcs := append(parseSymbols(funcSpecs, false), parseSymbols(variadicSpecs, true)...)
+	globals := parseSymbols(globalSpecs, false)
+
+	var funcNames, globalNames []string
+	for _, f := range funcs {
+		funcNames = append(funcNames, f.name)
+	}
+	for _, g := range globals {
+		globalNames = append(globalNames, g.name)
+	}
+
+	data, err := openDWARF(binary)
+	if err != nil {
+		return nil, err
+	}
+	sigs, types, err := lookupSymbols(data, funcNames, globalNames)
+	if err != nil {
+		return nil, err
+	}
+
+	imports := &importSet{local: importPath, aliases: make(map[string]string)}
+	var body bytes.Buffer
+	var init bytes.Buffer
+
+	for _, f := range funcs {
+		decl, call, err := funcSource(sigs[f.name], f.variadic, imports)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.name, err)
+		}
+		fmt.Fprintf(&body, "var assemblygen%s func%s\n\n", f.alias, decl)
+		fmt.Fprintf(&body, "// %s calls %s.\nfunc %s%s {\n", f.alias, f.name, f.alias, decl)
+		fmt.Fprintf(&body, "if assemblygen%s == nil {\npanic(assemblygenErr)\n}\n", f.alias)
+		if len(sigs[f.name].results) > 0 {
+			body.WriteString("return ")
+		}
+		fmt.Fprintf(&body, "assemblygen%s(%s)\n}\n\n", f.alias, call)
+		fmt.Fprintf(&init, "if err = assembly.BindFunc(assemblygenAsm, %q, %v, &assemblygen%s); err != nil {\nassemblygenErr = err\nreturn\n}\n", f.name, f.variadic, f.alias)
+	}
+
+	for _, g := range globals {
+		typ, err := imports.qualify(types[g.name])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", g.name, err)
+		}
+		imports.add("unsafe")
+		fmt.Fprintf(&body, "var assemblygen%s *%s\n\n", g.alias, typ)
+		fmt.Fprintf(&body, "// %s returns the address of %s.\nfunc %s() *%s {\n", g.alias, g.name, g.alias, typ)
+		fmt.Fprintf(&body, "if assemblygen%s == nil {\npanic(assemblygenErr)\n}\nreturn assemblygen%s\n}\n\n", g.alias, g.alias)
+		fmt.Fprintf(&init, "if addr, err = assemblygenAsm.FindGlobalAddr(%q); err != nil {\nassemblygenErr = err\nreturn\n}\n", g.name)
+		// the address is reinterpreted rather than converted, go vet rejects uintptr to pointer conversions
+		fmt.Fprintf(&init, "assemblygen%s = *(**%s)(unsafe.Pointer(&addr))\n", g.alias, typ)
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by assembly-gen; DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	imports.source(&src, "github.com/go-hotfix/assembly")
+	src.WriteString("// assemblygenAsm is kept alive for the lifetime of the program, closing it would release the wrapped functions.\n")
+	src.WriteString("var assemblygenAsm assembly.DwarfAssembly\n\n")
+	src.WriteString("// assemblygenErr is the error that prevented resolving the wrapped symbols, wrappers panic with it.\n")
+	src.WriteString("var assemblygenErr error\n\n")
+	src.Write(body.Bytes())
+	src.WriteString("func init() {\nvar err error\n")
+	if len(globals) > 0 {
+		src.WriteString("var addr uintptr\n")
+	}
+	src.WriteString("if assemblygenAsm, err = assembly.NewDwarfAssembly(); err != nil {\nassemblygenErr = err\nreturn\n}\n")
+	src.Write(init.Bytes())
+	src.WriteString("}\n")
+
+	return format.Source(src.Bytes())
+}
+
+// funcSource returns the parameter and result list of a wrapper and the arguments forwarding its parameters.
+func funcSource(sig *signature, variadic bool, imports *importSet) (decl string, call string, err error) {
+	params, args, results, err := funcParts(sig, variadic, imports)
+	if err != nil {
+		return "", "", err
+	}
+	return "(" + strings.Join(params, ", ") + ")" + resultList(results), strings.Join(args, ", "), nil
+}
+
+// funcParts returns the parameter declarations, forwarded arguments and result types of sig.
+func funcParts(sig *signature, variadic bool, imports *importSet) (params, args, results []string, err error) {
+	for i, p := range sig.params {
+		typ, err := imports.qualify(p.typ)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		name := p.name
+		if name == "" || name == "_" || strings.HasPrefix(name, "~") {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		arg := name
+		if variadic && i == len(sig.params)-1 {
+			if !strings.HasPrefix(typ, "[]") {
+				return nil, nil, nil, fmt.Errorf("variadic parameter %s has type %s", name, typ)
+			}
+			typ, arg = "..."+typ[2:], name+"..."
+		}
+		params = append(params, name+" "+typ)
+		args = append(args, arg)
+	}
+
+	for _, r := range sig.results {
+		typ, err := imports.qualify(r.typ)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		results = append(results, typ)
+	}
+	return params, args, results, nil
+}
+
+// resultList formats results as the result list of a signature.
+func resultList(results []string) string {
+	switch len(results) {
+	case 0:
+		return ""
+	case 1:
+		return " " + results[0]
+	default:
+		return " (" + strings.Join(results, ", ") + ")"
+	}
+}
+
+// qualifiedIdent matches the package qualified identifiers of a DWARF type name.
+var qualifiedIdent = regexp.MustCompile(`[A-Za-z_][\w\-.~%/]*\.[A-Za-z_]\w*`)
+
+// importSet rewrites DWARF type names into Go source, collecting the imports they need.
+type importSet struct {
+	local   string
+	aliases map[string]string
+}
+
+func (s *importSet) qualify(typ string) (string, error) {
+	var err error
+	out := qualifiedIdent.ReplaceAllStringFunc(typ, func(ident string) string {
+		dot := strings.LastIndex(ident, ".")
+		path, name := ident[:dot], ident[dot+1:]
+		if path == s.local {
+			return name
+		}
+		if path == "main" || !unicode.IsUpper([]rune(name)[0]) {
+			err = fmt.Errorf("type %s cannot be referenced from another package", ident)
+			return ident
+		}
+		return s.add(path) + "." + name
+	})
+	return out, err
+}
+
+// add imports path and returns its alias.
+func (s *importSet) add(path string) string {
+	if alias, ok := s.aliases[path]; ok {
+		return alias
+	}
+	base := path[strings.LastIndex(path, "/")+1:]
+	alias := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return '_'
+	}, base)
+	candidate := alias
+	for n := 1; s.taken(candidate); n++ {
+		candidate = fmt.Sprintf("%s%d", alias, n)
+	}
+	alias = candidate
+	s.aliases[path] = alias
+	return alias
+}
+
+func (s *importSet) taken(alias string) bool {
+	if alias == "assembly" {
+		return true
+	}
+	for _, a := range s.aliases {
+		if a == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// source writes the import declaration of the collected imports and required.
+func (s *importSet) source(w *bytes.Buffer, required ...string) {
+	paths := append(required, s.sorted()...)
+	if len(paths) == 0 {
+		return
+	}
+	w.WriteString("import (\n")
+	for _, path := range paths {
+		if alias, ok := s.aliases[path]; ok && alias != path[strings.LastIndex(path, "/")+1:] {
+			fmt.Fprintf(w, "%s ", alias)
+		}
+		fmt.Fprintf(w, "%q\n", path)
+	}
+	w.WriteString(")\n\n")
+}
+
+func (s *importSet) sorted() []string {
+	paths := make([]string, 0, len(s.aliases))
+	for path := range s.aliases {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the generated sources")
+
+// The symbols below are read back from the DWARF of the test binary, the package under test is
+// linked under its import path rather than as main.
+const testPkg = "github.com/go-hotfix/assembly/cmd/assembly-gen."
+
+//go:noinline
+func genAdd(a, b int) int {
+	return a + b
+}
+
+//go:noinline
+func genScale(buf *bytes.Buffer, k float64, d time.Duration, names ...string) (n int, err error) {
+	for _, name := range names {
+		n += len(name)
+	}
+	buf.WriteString(d.String())
+	return int(float64(n) * k), nil
+}
+
+var genCounter = map[string]int{"calls": 1}
+
+type genRecord struct {
+	ID   int64
+	Name string
+	next *genRecord
+	_    [4]byte
+	At   time.Duration
+}
+
+var genRecords []genRecord
+
+func TestGenerate(t *testing.T) {
+	if 8 != unsafe.Sizeof(uintptr(0)) {
+		t.Skipf("the golden struct layouts are those of 64-bit architectures")
+	}
+	_, _ = genAdd(1, 2), genCounter
+	_, _ = genScale(&bytes.Buffer{}, 1, time.Second, "a")
+	genRecords = append(genRecords, genRecord{next: &genRecord{}})
+
+	binary, err := os.Executable()
+	if err != nil {
+		t.Fatalf("Executable() error: %v", err)
+	}
+	funcs := symbolFlags{testPkg + "genAdd=Add"}
+	variadics := symbolFlags{testPkg + "genScale"}
+
+	for _, tc := range []struct {
+		golden   string
+		generate func() ([]byte, error)
+	}{
+		{"wrappers.golden", func() ([]byte, error) {
+			return generate(binary, "gen", "", funcs, variadics, symbolFlags{testPkg + "genCounter=Counter"})
+		}},
+		{"stub.golden", func() ([]byte, error) {
+			return generateStub(binary, "gen", "", funcs, variadics, symbolFlags{testPkg + "genRecord=Record"}, false)
+		}},
+		{"stub-safe.golden", func() ([]byte, error) {
+			return generateStub(binary, "gen", "", funcs, variadics, nil, true)
+		}},
+	} {
+		t.Run(tc.golden, func(t *testing.T) {
+			src, err := tc.generate()
+			if err != nil {
+				t.Fatalf("generate() error: %v", err)
+			}
+			golden := filepath.Join("testdata", tc.golden)
+			if *update {
+				if err = os.WriteFile(golden, src, 0o644); err != nil {
+					t.Fatalf("WriteFile() error: %v", err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("ReadFile() error: %v", err)
+			}
+			if !bytes.Equal(want, src) {
+				t.Fatalf("generated source differs from %s, rerun with -update to accept:\n%s", golden, src)
+			}
+			vet(t, src)
+		})
+	}
+}
+
+// vet runs go vet on src as the only file of a package, resolving its imports in this module.
+func vet(t *testing.T, src []byte) {
+	file := filepath.Join(t.TempDir(), "gen.go")
+	if err := os.WriteFile(file, src, 0o644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	if out, err := exec.Command("go", "vet", file).CombinedOutput(); err != nil {
+		t.Fatalf("go vet error: %v\n%s", err, out)
+	}
+}
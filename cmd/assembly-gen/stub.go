@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"debug/dwarf"
+	"fmt"
+	"go/format"
+	"strings"
+)
+
+// generateStub emits a skeleton for a patch plugin: functions with the signatures of funcs
+// left to implement, and mirrors of the struct types with compile time layout assertions.
+// With safe the replacements recover panics of their implementation, see safeSource.
+func generateStub(binary, pkg, importPath string, funcSpecs, variadicSpecs, typeSpecs symbolFlags, safe bool) ([]byte, error) {
+	funcs := append(parseSymbols(funcSpecs, false), parseSymbols(variadicSpecs, true)...)
+	types := parseSymbols(typeSpecs, false)
+
+	var funcNames, typeNames []string
+	for _, f := range funcs {
+		funcNames = append(funcNames, f.name)
+	}
+	for _, t := range types {
+		typeNames = append(typeNames, t.name)
+	}
+
+	data, err := openDWARF(binary)
+	if err != nil {
+		return nil, err
+	}
+	sigs, _, err := lookupSymbols(data, funcNames, nil)
+	if err != nil {
+		return nil, err
+	}
+	structs, err := lookupStructs(data, typeNames)
+	if err != nil {
+		return nil, err
+	}
+
+	imports := &importSet{local: importPath, aliases: make(map[string]string)}
+	var body bytes.Buffer
+
+	for _, t := range types {
+		if err = structSource(&body, t, structs[t.name], imports); err != nil {
+			return nil, fmt.Errorf("%s: %w", t.name, err)
+		}
+	}
+
+	for _, f := range funcs {
+		if safe {
+			err = safeSource(&body, f, sigs[f.name], imports)
+		} else {
+			err = replacementSource(&body, f.alias, f.name, sigs[f.name], f.variadic, imports)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.name, err)
+		}
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by assembly-gen -stub, edit to implement the patch.\n\npackage %s\n\n", pkg)
+	imports.source(&src)
+	src.Write(body.Bytes())
+	return format.Source(src.Bytes())
+}
+
+// replacementSource emits a replacement of the function name left to implement.
+func replacementSource(w *bytes.Buffer, ident, name string, sig *signature, variadic bool, imports *importSet) error {
+	decl, _, err := funcSource(sig, variadic, imports)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "// %s replaces %s.\nfunc %s%s {\n", ident, name, ident, decl)
+	fmt.Fprintf(w, "panic(%q)\n}\n\n", "TODO: implement "+name)
+	return nil
+}
+
+// safeSource emits a replacement calling the implementation left to implement under a recover.
+// A panic is logged and the call is repeated through the Original variable, which the patch
+// must point at a trampoline of the original function: calling the patched entry would reenter
+// the replacement. While it is nil the panic is propagated.
+func safeSource(w *bytes.Buffer, f wrapped, sig *signature, imports *importSet) error {
+	params, args, results, err := funcParts(sig, f.variadic, imports)
+	if err != nil {
+		return err
+	}
+	imports.add("log")
+
+	var named, assign []string
+	for i, typ := range results {
+		named = append(named, fmt.Sprintf("res%d %s", i, typ))
+		assign = append(assign, fmt.Sprintf("res%d", i))
+	}
+	decl, call := "("+strings.Join(params, ", ")+")", strings.Join(args, ", ")
+	original, impl := f.alias+"Original", "impl"+f.alias
+
+	fmt.Fprintf(w, "// %s is called with the arguments of a call of %s whose implementation panicked.\n", original, f.alias)
+	fmt.Fprintf(w, "// It must be set to a trampoline of the original %s before the patch is applied,\n// while nil the panic is propagated.\n", f.name)
+	fmt.Fprintf(w, "var %s func%s%s\n\n", original, decl, resultList(results))
+
+	fmt.Fprintf(w, "// %s replaces %s, falling back to %s if %s panics.\nfunc %s%s", f.alias, f.name, original, impl, f.alias, decl)
+	if len(named) > 0 {
+		fmt.Fprintf(w, " (%s)", strings.Join(named, ", "))
+	}
+	w.WriteString(" {\ndefer func() {\nif recovered := recover(); recovered != nil {\n")
+	fmt.Fprintf(w, "if %s == nil {\npanic(recovered)\n}\n", original)
+	fmt.Fprintf(w, "log.Printf(\"%%s panicked, calling the original: %%v\", %q, recovered)\n", f.name)
+	if len(assign) > 0 {
+		fmt.Fprintf(w, "%s = ", strings.Join(assign, ", "))
+	}
+	fmt.Fprintf(w, "%s(%s)\n}\n}()\n", original, call)
+	if len(results) > 0 {
+		w.WriteString("return ")
+	}
+	fmt.Fprintf(w, "%s(%s)\n}\n\n", impl, call)
+
+	fmt.Fprintf(w, "// %s implements %s.\nfunc %s%s%s {\n", impl, f.alias, impl, decl, resultList(results))
+	fmt.Fprintf(w, "panic(%q)\n}\n\n", "TODO: implement "+f.name)
+	return nil
+}
+
+// structSource emits a mirror of st followed by assertions failing to compile unless its size
+// and field offsets match the binary. Fields of types that cannot be named from the stub are
+// replaced by opaque arrays of the same size.
+func structSource(w *bytes.Buffer, t wrapped, st *dwarf.StructType, imports *importSet) error {
+	imports.add("unsafe")
+
+	fmt.Fprintf(w, "// %s mirrors the layout of %s.\ntype %s struct {\n", t.alias, t.name, t.alias)
+	for i, f := range st.Field {
+		name := typeName(f.Type)
+		typ, err := imports.qualify(name)
+		if err != nil {
+			typ = fmt.Sprintf("%s // %s", opaqueType(f.Type.Size()), name)
+		}
+		fmt.Fprintf(w, "%s %s\n", fieldName(i, f.Name), typ)
+	}
+	w.WriteString("}\n\n")
+
+	fmt.Fprintf(w, "var _ = [1]struct{}{}[unsafe.Sizeof(%s{})-%d]\n", t.alias, st.Size())
+	for i, f := range st.Field {
+		fmt.Fprintf(w, "var _ = [1]struct{}{}[unsafe.Offsetof(%s{}.%s)-%d]\n", t.alias, fieldName(i, f.Name), f.ByteOffset)
+	}
+	w.WriteString("\n")
+	return nil
+}
+
+// fieldName returns the field name of the mirror, blank fields cannot be referenced by the assertions.
+func fieldName(i int, name string) string {
+	if name == "_" || name == "" {
+		return fmt.Sprintf("blank%d", i)
+	}
+	return name
+}
+
+// opaqueType returns an array type of size bytes, DWARF does not record alignment so the
+// widest unsigned integer dividing size is assumed to be the alignment of the original type.
+func opaqueType(size int64) string {
+	for _, width := range []int64{8, 4, 2} {
+		if size%width == 0 {
+			return fmt.Sprintf("[%d]uint%d", size/width, width*8)
+		}
+	}
+	return fmt.Sprintf("[%d]uint8", size)
+}
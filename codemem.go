@@ -0,0 +1,62 @@
+package assembly
+
+// codeChunkSize is the size of the executable mappings trampolines are carved from.
+const codeChunkSize = 64 << 10
+
+// codeChunk is an executable mapping, memory below used is taken.
+type codeChunk struct {
+	base, used uintptr
+}
+
+// codeChunks are the executable mappings allocated so far, guarded by patches.mu.
+// Trampolines are never freed: a replacement may still run on one after its patch is reverted.
+var codeChunks []*codeChunk
+
+// allocCode returns size bytes of executable memory within reach bytes of near, anywhere if
+// reach is zero. The memory is read only, it is written through writeCode.
+func allocCode(near uintptr, size int, reach uintptr) (uintptr, error) {
+	need := (uintptr(size) + 15) &^ 15
+	for _, c := range codeChunks {
+		if codeChunkSize-c.used >= need && withinReach(c.base, near, reach) {
+			addr := c.base + c.used
+			c.used += need
+			return addr, nil
+		}
+	}
+
+	base, err := mapCode(near, codeChunkSize, reach)
+	if err != nil {
+		return 0, err
+	}
+	codeChunks = append(codeChunks, &codeChunk{base: base, used: need})
+	return base, nil
+}
+
+// withinReach reports whether every address of the chunk at base is within reach of near.
+func withinReach(base, near, reach uintptr) bool {
+	if reach == 0 {
+		return true
+	}
+	if base >= near {
+		return base-near+codeChunkSize < reach
+	}
+	return near-base < reach
+}
+
+// codeHints yields the 1MB aligned addresses around near to try mapping code at, nearest first.
+func codeHints(near, reach uintptr, try func(hint uintptr) bool) {
+	const step = 1 << 20
+	if reach == 0 {
+		try(0)
+		return
+	}
+	origin := near &^ (step - 1)
+	for dist := uintptr(step); dist < reach; dist += step {
+		if try(origin + dist) {
+			return
+		}
+		if origin > dist && try(origin-dist) {
+			return
+		}
+	}
+}
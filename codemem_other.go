@@ -0,0 +1,12 @@
+//go:build !unix && !windows
+
+package assembly
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func mapCode(near, size, reach uintptr) (uintptr, error) {
+	return 0, fmt.Errorf("%w: executable memory on %s", ErrNotSupport, runtime.GOOS)
+}
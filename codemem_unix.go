@@ -0,0 +1,32 @@
+//go:build unix
+
+package assembly
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// mapCode maps size bytes of read only executable memory within reach of near. The kernel
+// treats the address as a hint, a mapping placed elsewhere is released and the next one tried.
+func mapCode(near, size, reach uintptr) (uintptr, error) {
+	var base uintptr
+	codeHints(near, reach, func(hint uintptr) bool {
+		ptr, err := unix.MmapPtr(-1, 0, unsafe.Pointer(hint), size, unix.PROT_READ|unix.PROT_EXEC, unix.MAP_PRIVATE|unix.MAP_ANON)
+		if err != nil {
+			return false
+		}
+		if withinReach(uintptr(ptr), near, reach) {
+			base = uintptr(ptr)
+			return true
+		}
+		unix.MunmapPtr(ptr, size)
+		return false
+	})
+	if base == 0 {
+		return 0, fmt.Errorf("%w: no executable memory within %#x of %#x", ErrNotSupport, reach, near)
+	}
+	return base, nil
+}
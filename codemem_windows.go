@@ -0,0 +1,29 @@
+package assembly
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// mapCode allocates size bytes of read only executable memory within reach of near,
+// trying the hints until VirtualAlloc finds one of them free.
+func mapCode(near, size, reach uintptr) (uintptr, error) {
+	var base uintptr
+	codeHints(near, reach, func(hint uintptr) bool {
+		addr, err := windows.VirtualAlloc(hint, size, windows.MEM_RESERVE|windows.MEM_COMMIT, windows.PAGE_EXECUTE_READ)
+		if err != nil {
+			return false
+		}
+		if withinReach(addr, near, reach) {
+			base = addr
+			return true
+		}
+		windows.VirtualFree(addr, 0, windows.MEM_RELEASE)
+		return false
+	})
+	if base == 0 {
+		return 0, fmt.Errorf("%w: no executable memory within %#x of %#x", ErrNotSupport, reach, near)
+	}
+	return base, nil
+}
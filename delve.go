@@ -7,6 +7,8 @@ import (
 	"unsafe"
 
 	"github.com/go-delve/delve/pkg/dwarf/godwarf"
+	"github.com/go-delve/delve/pkg/dwarf/line"
+	"github.com/go-delve/delve/pkg/dwarf/loclist"
 	"github.com/go-delve/delve/pkg/proc"
 )
 
@@ -17,17 +19,15 @@ type ModuleData struct {
 	typemapVar    *proc.Variable
 }
 
-type funcCallArg struct {
-	name       string
-	typ        godwarf.Type
-	off        int64
-	dwarfEntry *godwarf.Tree // non-nil if Go 1.17+
-	isret      bool
-}
-
 //go:linkname findType github.com/go-delve/delve/pkg/proc.(*BinaryInfo).findType
 func findType(bi *proc.BinaryInfo, name string) (godwarf.Type, error)
 
+//go:linkname funcToImage github.com/go-delve/delve/pkg/proc.(*BinaryInfo).funcToImage
+func funcToImage(bi *proc.BinaryInfo, fn *proc.Function) *proc.Image
+
+//go:linkname getDwarfTree github.com/go-delve/delve/pkg/proc.(*Image).getDwarfTree
+func getDwarfTree(image *proc.Image, off dwarf.Offset) (*godwarf.Tree, error)
+
 //go:linkname loadModuleData github.com/go-delve/delve/pkg/proc.LoadModuleData
 func loadModuleData(bi *proc.BinaryInfo, mem proc.MemoryReadWriter) ([]ModuleData, error)
 
@@ -37,9 +37,6 @@ func imageToModuleData(bi *proc.BinaryInfo, image *proc.Image, mds []ModuleData)
 //go:linkname dwarfToRuntimeType github.com/go-delve/delve/pkg/proc.dwarfToRuntimeType
 func dwarfToRuntimeType(bi *proc.BinaryInfo, mem proc.MemoryReadWriter, typ godwarf.Type) (typeAddr uint64, typeKind uint64, found bool, err error)
 
-//go:linkname funcCallArgs github.com/go-delve/delve/pkg/proc.funcCallArgs
-func funcCallArgs(fn *proc.Function, bi *proc.BinaryInfo, includeRet bool) (argFrameSize int64, formalArgs []funcCallArg, err error)
-
 type localMemory int
 
 func (mem *localMemory) ReadMemory(data []byte, addr uint64) (int, error) {
@@ -47,8 +44,10 @@ func (mem *localMemory) ReadMemory(data []byte, addr uint64) (int, error) {
 	return copy(data, buf), nil
 }
 
+// WriteMemory writes the memory of the current process, pages that are not writable are
+// made writable for the write and restored to their protection afterwards.
 func (mem *localMemory) WriteMemory(addr uint64, data []byte) (int, error) {
-	return 0, ErrNotSupport
+	return writeLocal(uintptr(addr), data)
 }
 
 func dwarfTypeName(dtyp dwarf.Type) string {
@@ -68,6 +67,14 @@ func dwarfTypeName(dtyp dwarf.Type) string {
 	}
 }
 
+// godwarfTypeName returns the name typ is declared with in DWARF, which is the name FindType looks up.
+func godwarfTypeName(typ godwarf.Type) string {
+	if name := typ.Common().Name; name != "" {
+		return name
+	}
+	return typ.String()
+}
+
 func resolveTypedef(typ godwarf.Type) godwarf.Type {
 	for {
 		switch tt := typ.(type) {
@@ -91,6 +98,68 @@ func entryType(data *dwarf.Data, entry *dwarf.Entry) (dwarf.Type, error) {
 	return data.Type(off)
 }
 
+// funcOffset returns the offset of the debug_info entry describing fn.
+func funcOffset(fn *proc.Function) dwarf.Offset {
+	return dwarf.Offset(reflect.ValueOf(fn).Elem().FieldByName("offset").Uint())
+}
+
+// funcFilePath returns the path of the file fileidx of the line table of the compile unit of fn.
+func funcFilePath(fn *proc.Function, fileidx int) (string, bool) {
+	rCU := reflect.ValueOf(fn).Elem().FieldByName("cu").Elem()
+	lineInfo := (*line.DebugLineInfo)(unsafe.Pointer(rCU.FieldByName("lineInfo").Pointer()))
+	if lineInfo == nil {
+		return "", false
+	}
+	// file numbering starts at 1 before DWARF 5
+	if rCU.FieldByName("Version").Uint() < 5 {
+		fileidx--
+	}
+	if fileidx < 0 || fileidx >= len(lineInfo.FileNames) {
+		return "", false
+	}
+	return lineInfo.FileNames[fileidx].Path, true
+}
+
+// funcLocationExpr returns the location expression of attr in entry valid at pc, reading the
+// location lists of the compile unit of fn directly rather than through BinaryInfo.Location,
+// which fails to find the compile unit of DWARF 5 range lists.
+func funcLocationExpr(fn *proc.Function, entry *dwarf.Entry, attr dwarf.Attr, pc uint64) ([]byte, error) {
+	switch v := entry.Val(attr).(type) {
+	case []byte:
+		return v, nil
+	case int64:
+		rCU := reflect.ValueOf(fn).Elem().FieldByName("cu").Elem()
+		rImage := rCU.FieldByName("image")
+		image := (*proc.Image)(unsafe.Pointer(rImage.Pointer()))
+		cuEntry := (*dwarf.Entry)(unsafe.Pointer(rCU.FieldByName("entry").Pointer()))
+
+		// the compile unit version is not always recorded, prefer DWARF 5 location lists whenever
+		// the image has no DWARF 2-4 ones.
+		var rdr loclist.Reader = (*loclist.Dwarf2Reader)(unsafe.Pointer(rImage.Elem().FieldByName("loclist2").Pointer()))
+		var debugAddr *godwarf.DebugAddr
+		if rCU.FieldByName("Version").Uint() >= 5 || rdr.Empty() {
+			rdr = (*loclist.Dwarf5Reader)(unsafe.Pointer(rImage.Elem().FieldByName("loclist5").Pointer()))
+			section := (*godwarf.DebugAddrSection)(unsafe.Pointer(rImage.Elem().FieldByName("debugAddr").Pointer()))
+			if addrBase, ok := cuEntry.Val(dwarf.AttrAddrBase).(int64); ok && section != nil {
+				debugAddr = section.GetSubsection(uint64(addrBase))
+			}
+		}
+		if rdr.Empty() {
+			return nil, fmt.Errorf("no location lists for %s", fn.Name)
+		}
+
+		e, err := rdr.Find(int(v), image.StaticBase, rCU.FieldByName("lowPC").Uint(), pc, debugAddr)
+		if err != nil {
+			return nil, err
+		}
+		if e == nil {
+			return nil, fmt.Errorf("could not find loclist entry at %#x for address %#x", v, pc)
+		}
+		return e.Instr, nil
+	}
+	return nil, fmt.Errorf("no location attribute %s", attr)
+}
+
 func entryAddress(p uintptr, l int) []byte {
 	return *(*[]byte)(unsafe.Pointer(&reflect.SliceHeader{Data: p, Len: l, Cap: l}))
 }
@@ -99,20 +168,35 @@ type Func struct {
 	codePtr uintptr
 }
 
-// CreateFuncForCodePtr https://github.com/alangpierce/go-forceexport/blob/8f1d6941cd755b975763ddb1f836561edddac2b8/forceexport.go#L31-L51
+// CreateFuncForCodePtr creates a function value without closure context, calling a closure that
+// captures variables through it corrupts memory, FindFunc refuses those with ErrClosureNotSupported.
+//
+// https://github.com/alangpierce/go-forceexport/blob/8f1d6941cd755b975763ddb1f836561edddac2b8/forceexport.go#L31-L51
 func CreateFuncForCodePtr(ftyp reflect.Type, codePtr uint64) reflect.Value {
+	// We give a nil delegate function because it will never actually be called.
+	newFuncVal, _ := forgeFunc(ftyp, codePtr, nil)
+	return newFuncVal
+}
+
+// forgeFunc creates a function value of type ftyp calling the code at codePtr,
+// and returns the code pointer of the reflect stub calling impl it replaced.
+func forgeFunc(ftyp reflect.Type, codePtr uint64, impl func([]reflect.Value) []reflect.Value) (reflect.Value, uintptr) {
 	// Use reflect.MakeFunc to create a well-formed function value that's
 	// guaranteed to be of the right type and guaranteed to be on the heap
-	// (so that we can modify it). We give a nil delegate function because
-	// it will never actually be called.
-	newFuncVal := reflect.MakeFunc(ftyp, nil)
+	// (so that we can modify it).
+	newFuncVal := reflect.MakeFunc(ftyp, impl)
 	// Use reflection on the reflect.Value (yep!) to grab the underling
 	// function value pointer. Trying to call newFuncVal.Pointer() wouldn't
 	// work because it gives the code pointer rather than the function value
 	// pointer. The function value is a struct that starts with its code
 	// pointer, so we can swap out the code pointer with our desired value.
-	funcValuePtr := reflect.ValueOf(newFuncVal).FieldByName("ptr").Pointer()
-	funcPtr := (*Func)(unsafe.Pointer(funcValuePtr))
+	funcPtr := funcValue(newFuncVal)
+	stubPtr := funcPtr.codePtr
 	funcPtr.codePtr = uintptr(codePtr)
-	return newFuncVal
+	return newFuncVal, stubPtr
+}
+
+func funcValue(fn reflect.Value) *Func {
+	funcValuePtr := reflect.ValueOf(fn).FieldByName("ptr").Pointer()
+	return (*Func)(unsafe.Pointer(funcValuePtr))
 }
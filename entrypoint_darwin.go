@@ -0,0 +1,48 @@
+package assembly
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// The dyld functions are called through the trampolines of entrypoint_darwin.s, as package
+// syscall and golang.org/x/sys/unix call libSystem without cgo.
+
+//go:cgo_import_dynamic libc_dyld_image_count _dyld_image_count "/usr/lib/libSystem.B.dylib"
+//go:cgo_import_dynamic libc_dyld_get_image_name _dyld_get_image_name "/usr/lib/libSystem.B.dylib"
+//go:cgo_import_dynamic libc_dyld_get_image_header _dyld_get_image_header "/usr/lib/libSystem.B.dylib"
+
+var (
+	libc_dyld_image_count_trampoline_addr      uintptr
+	libc_dyld_get_image_name_trampoline_addr   uintptr
+	libc_dyld_get_image_header_trampoline_addr uintptr
+)
+
+//go:linkname syscall_syscall syscall.syscall
+func syscall_syscall(fn, a1, a2, a3 uintptr) (r1, r2 uintptr, err syscall.Errno)
+
+// processModules lists the images dyld mapped into the process, the executable first. The base
+// of an image is its Mach-O header, the vmaddr of its __TEXT segment moved by the slide of dyld.
+func processModules() ([]LoadedLibrary, error) {
+	count, _, _ := syscall_syscall(libc_dyld_image_count_trampoline_addr, 0, 0, 0)
+	libraries := make([]LoadedLibrary, 0, uint32(count))
+	for i := uintptr(0); i < uintptr(uint32(count)); i++ {
+		header, _, _ := syscall_syscall(libc_dyld_get_image_header_trampoline_addr, i, 0, 0)
+		name, _, _ := syscall_syscall(libc_dyld_get_image_name_trampoline_addr, i, 0, 0)
+		if header == 0 || name == 0 {
+			// the image was unloaded after the count was taken.
+			continue
+		}
+		libraries = append(libraries, LoadedLibrary{Path: cString(name), Base: uint64(header)})
+	}
+	return libraries, nil
+}
+
+// cString copies the NUL terminated string at p.
+func cString(p uintptr) string {
+	n := 0
+	for *(*byte)(unsafe.Pointer(p + uintptr(n))) != 0 {
+		n++
+	}
+	return string(unsafe.Slice((*byte)(unsafe.Pointer(p)), n))
+}
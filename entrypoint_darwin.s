@@ -0,0 +1,16 @@
+#include "textflag.h"
+
+TEXT libc_dyld_image_count_trampoline<>(SB),NOSPLIT,$0-0
+	JMP	libc_dyld_image_count(SB)
+GLOBL	·libc_dyld_image_count_trampoline_addr(SB), RODATA, $8
+DATA	·libc_dyld_image_count_trampoline_addr(SB)/8, $libc_dyld_image_count_trampoline<>(SB)
+
+TEXT libc_dyld_get_image_name_trampoline<>(SB),NOSPLIT,$0-0
+	JMP	libc_dyld_get_image_name(SB)
+GLOBL	·libc_dyld_get_image_name_trampoline_addr(SB), RODATA, $8
+DATA	·libc_dyld_get_image_name_trampoline_addr(SB)/8, $libc_dyld_get_image_name_trampoline<>(SB)
+
+TEXT libc_dyld_get_image_header_trampoline<>(SB),NOSPLIT,$0-0
+	JMP	libc_dyld_get_image_header(SB)
+GLOBL	·libc_dyld_get_image_header_trampoline_addr(SB), RODATA, $8
+DATA	·libc_dyld_get_image_header_trampoline_addr(SB)/8, $libc_dyld_get_image_header_trampoline<>(SB)
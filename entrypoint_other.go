@@ -0,0 +1,8 @@
+//go:build !windows && !darwin
+
+package assembly
+
+// processModules is only needed on Windows and macOS, elsewhere the link map of the dynamic loader lists the libraries.
+func processModules() ([]LoadedLibrary, error) {
+	return nil, ErrNotSupport
+}
@@ -9,37 +9,63 @@ import (
 )
 
 func getEntrypoint(targetModulePath string) (uintptr, error) {
+	return moduleBase(windows.CurrentProcess(), targetModulePath)
+}
 
-	processHandle := windows.CurrentProcess()
+// processModules lists the modules mapped into the current process, the executable first.
+func processModules() ([]LoadedLibrary, error) {
+	return enumModules(windows.CurrentProcess())
+}
 
-	var modules [1024]windows.Handle
-	var needed uint32
-	if err := windows.EnumProcessModules(processHandle, &modules[0], uint32(unsafe.Sizeof(modules[0]))*1024, &needed); err != nil {
+// moduleBase returns the base address of the module targetModulePath in the process.
+func moduleBase(processHandle windows.Handle, targetModulePath string) (uintptr, error) {
+	modules, err := enumModules(processHandle)
+	if err != nil {
 		return 0, err
 	}
 
 	var moduleList []string
+	for _, m := range modules {
+		if targetModulePath == m.Path {
+			return uintptr(m.Base), nil
+		}
+		moduleList = append(moduleList, m.Path)
+	}
+
+	return 0, fmt.Errorf("module not found: %s not found in [%s]", targetModulePath, moduleList)
+}
+
+// enumModules lists the modules mapped into the process, the executable first.
+func enumModules(processHandle windows.Handle) ([]LoadedLibrary, error) {
+	// the buffer grows until it holds every module, more may be loaded between two calls.
+	modules := make([]windows.Handle, 1024)
+	size := uint32(unsafe.Sizeof(modules[0]))
+	for {
+		var needed uint32
+		if err := windows.EnumProcessModules(processHandle, &modules[0], size*uint32(len(modules)), &needed); err != nil {
+			return nil, err
+		}
+		if needed <= size*uint32(len(modules)) {
+			modules = modules[:needed/size]
+			break
+		}
+		modules = make([]windows.Handle, needed/size+64)
+	}
+
+	var libraries []LoadedLibrary
 	var modulePathUTF16Bytes [windows.MAX_PATH]uint16
-	var count = needed / uint32(unsafe.Sizeof(modules[0]))
 
-	for i := uint32(0); i < count; i++ {
+	for i := range modules {
 		var mi windows.ModuleInfo
 		if err := windows.GetModuleInformation(processHandle, modules[i], &mi, uint32(unsafe.Sizeof(mi))); err != nil {
-			return 0, err
+			return nil, err
 		}
 
 		if err := windows.GetModuleFileNameEx(processHandle, modules[i], &modulePathUTF16Bytes[0], windows.MAX_PATH); err != nil {
-			return 0, err
-		}
-
-		var modulePath = syscall.UTF16ToString(modulePathUTF16Bytes[:])
-
-		if targetModulePath == modulePath {
-			return mi.BaseOfDll, nil
+			return nil, err
 		}
 
-		moduleList = append(moduleList, modulePath)
+		libraries = append(libraries, LoadedLibrary{Path: syscall.UTF16ToString(modulePathUTF16Bytes[:]), Base: uint64(mi.BaseOfDll)})
 	}
-
-	return 0, fmt.Errorf("module not found: %s not found in [%s]", targetModulePath, moduleList)
+	return libraries, nil
 }
@@ -4,6 +4,7 @@ go 1.21
 
 require (
 	github.com/go-delve/delve v1.23.1
+	golang.org/x/arch v0.6.0
 	golang.org/x/sys v0.27.0
 )
 
@@ -11,6 +12,5 @@ require (
 	github.com/cilium/ebpf v0.11.0 // indirect
 	github.com/hashicorp/golang-lru v1.0.2 // indirect
 	github.com/sirupsen/logrus v1.9.3 // indirect
-	golang.org/x/arch v0.6.0 // indirect
 	golang.org/x/exp v0.0.0-20230224173230-c95f2b4c22f2 // indirect
 )
@@ -0,0 +1,7 @@
+package assembly
+
+// flushInstructionCache makes code written from start to end visible to instruction fetch:
+// arm64 does not keep the instruction cache coherent with stores.
+//
+//go:noescape
+func flushInstructionCache(start, end uintptr)
@@ -0,0 +1,34 @@
+#include "textflag.h"
+
+// func flushInstructionCache(start, end uintptr)
+// Cleans the data cache and invalidates the instruction cache to the point of unification
+// by the minimum line sizes of CTR_EL0, then synchronizes the instruction stream.
+TEXT ·flushInstructionCache(SB), NOSPLIT, $0-16
+	MOVD start+0(FP), R0
+	MOVD end+8(FP), R1
+	MRS CTR_EL0, R2
+	MOVD $4, R3
+	UBFX $16, R2, $4, R4
+	LSL R4, R3, R4
+	AND $15, R2, R5
+	LSL R5, R3, R5
+
+	SUB $1, R4, R6
+	BIC R6, R0, R7
+dclean:
+	DC CVAU, R7
+	ADD R4, R7, R7
+	CMP R1, R7
+	BLO dclean
+	DSB $11
+
+	SUB $1, R5, R6
+	BIC R6, R0, R7
+iinval:
+	WORD $0xd50b7527 // IC IVAU, R7
+	ADD R5, R7, R7
+	CMP R1, R7
+	BLO iinval
+	DSB $11
+	ISB $15
+	RET
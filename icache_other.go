@@ -0,0 +1,6 @@
+//go:build !arm64
+
+package assembly
+
+// flushInstructionCache is a no-op, the instruction cache of the architecture is coherent with stores.
+func flushInstructionCache(start, end uintptr) {}
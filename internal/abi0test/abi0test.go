@@ -0,0 +1,14 @@
+//go:build amd64 || arm64
+
+// Package abi0test provides assembly functions compiled for ABI0, to test calls bridged to them.
+package abi0test
+
+// Vector mixes floats and an integer, ABIInternal would pass it in registers, ABI0 passes it
+// on the stack.
+type Vector struct {
+	A, B, C, D, E float64
+	N             int64
+}
+
+// Scale returns v with its floats multiplied by k and the sum of the scaled floats.
+func Scale(v Vector, k float64) (r Vector, sum float64)
@@ -0,0 +1,30 @@
+#include "textflag.h"
+
+// func Scale(v Vector, k float64) (r Vector, sum float64)
+TEXT ·Scale(SB), NOSPLIT, $0-112
+	MOVSD k+48(FP), X0
+	XORPS X2, X2
+	MOVSD v_A+0(FP), X1
+	MULSD X0, X1
+	MOVSD X1, r_A+56(FP)
+	ADDSD X1, X2
+	MOVSD v_B+8(FP), X1
+	MULSD X0, X1
+	MOVSD X1, r_B+64(FP)
+	ADDSD X1, X2
+	MOVSD v_C+16(FP), X1
+	MULSD X0, X1
+	MOVSD X1, r_C+72(FP)
+	ADDSD X1, X2
+	MOVSD v_D+24(FP), X1
+	MULSD X0, X1
+	MOVSD X1, r_D+80(FP)
+	ADDSD X1, X2
+	MOVSD v_E+32(FP), X1
+	MULSD X0, X1
+	MOVSD X1, r_E+88(FP)
+	ADDSD X1, X2
+	MOVQ v_N+40(FP), AX
+	MOVQ AX, r_N+96(FP)
+	MOVSD X2, sum+104(FP)
+	RET
@@ -0,0 +1,30 @@
+#include "textflag.h"
+
+// func Scale(v Vector, k float64) (r Vector, sum float64)
+TEXT ·Scale(SB), NOSPLIT, $0-112
+	FMOVD k+48(FP), F0
+	FMOVD ZR, F2
+	FMOVD v_A+0(FP), F1
+	FMULD F0, F1, F1
+	FMOVD F1, r_A+56(FP)
+	FADDD F1, F2, F2
+	FMOVD v_B+8(FP), F1
+	FMULD F0, F1, F1
+	FMOVD F1, r_B+64(FP)
+	FADDD F1, F2, F2
+	FMOVD v_C+16(FP), F1
+	FMULD F0, F1, F1
+	FMOVD F1, r_C+72(FP)
+	FADDD F1, F2, F2
+	FMOVD v_D+24(FP), F1
+	FMULD F0, F1, F1
+	FMOVD F1, r_D+80(FP)
+	FADDD F1, F2, F2
+	FMOVD v_E+32(FP), F1
+	FMULD F0, F1, F1
+	FMOVD F1, r_E+88(FP)
+	FADDD F1, F2, F2
+	MOVD v_N+40(FP), R0
+	MOVD R0, r_N+96(FP)
+	FMOVD F2, sum+104(FP)
+	RET
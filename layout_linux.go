@@ -0,0 +1,40 @@
+package assembly
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// mappedFile returns the file mapped at addr according to /proc/self/maps, empty if the
+// address is unmapped or anonymous.
+func mappedFile(addr uintptr) (string, error) {
+	f, err := os.Open("/proc/self/maps")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		lo, hi, ok := strings.Cut(fields[0], "-")
+		if !ok {
+			continue
+		}
+		from, err1 := strconv.ParseUint(lo, 16, 64)
+		to, err2 := strconv.ParseUint(hi, 16, 64)
+		if err1 != nil || err2 != nil || uintptr(from) > addr || addr >= uintptr(to) {
+			continue
+		}
+		if len(fields) < 6 || !strings.HasPrefix(fields[5], "/") {
+			return "", nil
+		}
+		return strings.TrimSuffix(strings.Join(fields[5:], " "), " (deleted)"), nil
+	}
+	return "", scanner.Err()
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !windows
+
+package assembly
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func mappedFile(addr uintptr) (string, error) {
+	return "", fmt.Errorf("%w: reading mappings on %s", ErrNotSupport, runtime.GOOS)
+}
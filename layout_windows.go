@@ -0,0 +1,34 @@
+package assembly
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// memImage is the MEM_IMAGE type of memory mapped from an image file.
+const memImage = 0x1000000
+
+// mappedFile returns the module whose image is mapped at addr, empty if the address is
+// not part of a loaded module.
+func mappedFile(addr uintptr) (string, error) {
+	var info windows.MemoryBasicInformation
+	if err := windows.VirtualQuery(addr, &info, unsafe.Sizeof(info)); err != nil {
+		return "", err
+	}
+	if info.State != windows.MEM_COMMIT || info.Type != memImage {
+		return "", nil
+	}
+
+	var module windows.Handle
+	flags := uint32(windows.GET_MODULE_HANDLE_EX_FLAG_FROM_ADDRESS | windows.GET_MODULE_HANDLE_EX_FLAG_UNCHANGED_REFCOUNT)
+	if err := windows.GetModuleHandleEx(flags, (*uint16)(unsafe.Pointer(addr)), &module); err != nil {
+		return "", nil
+	}
+	var name [windows.MAX_LONG_PATH]uint16
+	n, err := windows.GetModuleFileName(module, &name[0], uint32(len(name)))
+	if err != nil {
+		return "", err
+	}
+	return windows.UTF16ToString(name[:n]), nil
+}
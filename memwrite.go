@@ -0,0 +1,75 @@
+package assembly
+
+import (
+	"runtime/debug"
+	"sync"
+)
+
+// writeMu serializes writes changing page protections, two writes to the same page would
+// otherwise restore the protection while the other is still copying.
+var writeMu sync.Mutex
+
+// writeLocal copies data to addr in the current process. Writable memory is written directly,
+// read-only pages are made writable for the copy and restored to their protection afterwards.
+func writeLocal(addr uintptr, data []byte) (int, error) {
+	if len(data) == 0 {
+		return 0, nil
+	}
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	if tryCopy(addr, data) {
+		return len(data), nil
+	}
+	if err := writeProtected(addr, data); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// tryCopy copies data to addr, reporting false if the copy faulted because the memory is not writable.
+func tryCopy(addr uintptr, data []byte) (ok bool) {
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	copy(entryAddress(addr, len(data)), data)
+	return true
+}
+
+// holdWritable makes the pages covering the code of patches writable and holds writeMu until
+// release restores their protection, the writes of that code in between are plain copies
+// making no system call.
+func holdWritable(patches []codePatch) (release func() error, err error) {
+	writeMu.Lock()
+	var restores []func() error
+	release = func() error {
+		defer writeMu.Unlock()
+		// pages shared by several patches are restored last by the first, which saw their
+		// original protection.
+		var restoreErr error
+		for i := len(restores) - 1; i >= 0; i-- {
+			if err := restores[i](); err != nil && restoreErr == nil {
+				restoreErr = err
+			}
+		}
+		return restoreErr
+	}
+	for _, p := range patches {
+		restore, err := unprotect(p.pc, len(p.code))
+		if err != nil {
+			release()
+			return nil, err
+		}
+		restores = append(restores, restore)
+	}
+	return release, nil
+}
+
+// pageRange returns the page aligned range covering size bytes at addr.
+func pageRange(addr uintptr, size int, pageSize uintptr) (start, end uintptr) {
+	start = addr &^ (pageSize - 1)
+	end = (addr + uintptr(size) + pageSize - 1) &^ (pageSize - 1)
+	return start, end
+}
@@ -0,0 +1,16 @@
+//go:build !unix && !windows
+
+package assembly
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func writeProtected(addr uintptr, data []byte) error {
+	return fmt.Errorf("%w: changing page protections on %s", ErrNotSupport, runtime.GOOS)
+}
+
+func unprotect(addr uintptr, size int) (func() error, error) {
+	return nil, fmt.Errorf("%w: changing page protections on %s", ErrNotSupport, runtime.GOOS)
+}
@@ -0,0 +1,61 @@
+//go:build linux
+
+package assembly
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestWriteProtectedMemory(t *testing.T) {
+	page, err := unix.Mmap(-1, 0, os.Getpagesize(), unix.PROT_READ, unix.MAP_PRIVATE|unix.MAP_ANON)
+	if nil != err {
+		t.Fatalf("Mmap() error: %v", err)
+	}
+	defer unix.Munmap(page)
+
+	addr := uintptr(unsafe.Pointer(&page[0]))
+	want := []byte("hotfix")
+	if n, err := new(localMemory).WriteMemory(uint64(addr)+8, want); nil != err || len(want) != n {
+		t.Fatalf("WriteMemory() got = %d, %v", n, err)
+	}
+	if !bytes.Equal(want, page[8:8+len(want)]) {
+		t.Fatalf("WriteMemory() wrote %q, want %q", page[8:8+len(want)], want)
+	}
+	if prots := pageProtections(addr, addr+uintptr(len(page))); 1 != len(prots) || unix.PROT_READ != prots[0].prot {
+		t.Fatalf("WriteMemory() left protections %+v, want read only", prots)
+	}
+}
+
+func TestHoldWritable(t *testing.T) {
+	page, err := unix.Mmap(-1, 0, 2*os.Getpagesize(), unix.PROT_READ, unix.MAP_PRIVATE|unix.MAP_ANON)
+	if nil != err {
+		t.Fatalf("Mmap() error: %v", err)
+	}
+	defer unix.Munmap(page)
+
+	addr := uintptr(unsafe.Pointer(&page[0]))
+	want := []byte("hotfix")
+	// the second patch shares the first page with the first and spans the second page.
+	code := []codePatch{{pc: addr + 8, code: want}, {pc: addr + uintptr(os.Getpagesize()) - 2, code: want}}
+	release, err := holdWritable(code)
+	if nil != err {
+		t.Fatalf("holdWritable() error: %v", err)
+	}
+	for _, c := range code {
+		copyCode(c.pc, c.code)
+	}
+	if err = release(); nil != err {
+		t.Fatalf("release() error: %v", err)
+	}
+	if !bytes.Equal(want, page[8:8+len(want)]) {
+		t.Fatalf("copyCode() wrote %q, want %q", page[8:8+len(want)], want)
+	}
+	if prots := pageProtections(addr, addr+uintptr(len(page))); 1 != len(prots) || unix.PROT_READ != prots[0].prot {
+		t.Fatalf("release() left protections %+v, want read only", prots)
+	}
+}
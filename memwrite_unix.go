@@ -0,0 +1,95 @@
+//go:build unix
+
+package assembly
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// writeProtected makes the pages covering data writable, copies it and restores the protection
+// of every page.
+func writeProtected(addr uintptr, data []byte) error {
+	restore, err := unprotect(addr, len(data))
+	if err != nil {
+		return err
+	}
+	copy(entryAddress(addr, len(data)), data)
+	return restore()
+}
+
+// unprotect makes the pages covering size bytes at addr writable, restore sets back the
+// protection of every page. The protection is read from /proc/self/maps, where it is unavailable
+// the pages are assumed to be code and restored to read and execute.
+func unprotect(addr uintptr, size int) (restore func() error, err error) {
+	start, end := pageRange(addr, size, uintptr(os.Getpagesize()))
+	prots := pageProtections(start, end)
+
+	if err := unix.Mprotect(entryAddress(start, int(end-start)), unix.PROT_READ|unix.PROT_WRITE|unix.PROT_EXEC); err != nil {
+		return nil, fmt.Errorf("mprotect %#x-%#x: %w", start, end, err)
+	}
+	return func() error {
+		var restoreErr error
+		for _, r := range prots {
+			if err := unix.Mprotect(entryAddress(r.start, int(r.end-r.start)), r.prot); err != nil && restoreErr == nil {
+				restoreErr = fmt.Errorf("restore protection of %#x-%#x: %w", r.start, r.end, err)
+			}
+		}
+		return restoreErr
+	}, nil
+}
+
+// protRange is the protection of the pages from start to end.
+type protRange struct {
+	start, end uintptr
+	prot       int
+}
+
+// pageProtections returns the protections of the pages from start to end.
+func pageProtections(start, end uintptr) []protRange {
+	fallback := []protRange{{start: start, end: end, prot: unix.PROT_READ | unix.PROT_EXEC}}
+	f, err := os.Open("/proc/self/maps")
+	if err != nil {
+		return fallback
+	}
+	defer f.Close()
+
+	var prots []protRange
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		lo, hi, ok := strings.Cut(fields[0], "-")
+		if !ok {
+			continue
+		}
+		from, err1 := strconv.ParseUint(lo, 16, 64)
+		to, err2 := strconv.ParseUint(hi, 16, 64)
+		if err1 != nil || err2 != nil || uintptr(to) <= start || uintptr(from) >= end {
+			continue
+		}
+		prots = append(prots, protRange{start: max(start, uintptr(from)), end: min(end, uintptr(to)), prot: mapsProt(fields[1])})
+	}
+	if len(prots) == 0 {
+		return fallback
+	}
+	return prots
+}
+
+// mapsProt converts the permissions column of /proc/self/maps, e.g. "r-xp".
+func mapsProt(perms string) int {
+	var prot int
+	for i, flag := range []int{unix.PROT_READ, unix.PROT_WRITE, unix.PROT_EXEC} {
+		if i < len(perms) && perms[i] != '-' {
+			prot |= flag
+		}
+	}
+	return prot
+}
@@ -0,0 +1,61 @@
+package assembly
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var procFlushInstructionCache = windows.NewLazySystemDLL("kernel32.dll").NewProc("FlushInstructionCache")
+
+// writeProtected makes every region covering data writable, copies it and restores the
+// protection of each region.
+func writeProtected(addr uintptr, data []byte) error {
+	restore, err := unprotect(addr, len(data))
+	if err != nil {
+		return err
+	}
+	copy(entryAddress(addr, len(data)), data)
+	return restore()
+}
+
+// unprotect makes every region covering size bytes at addr writable with VirtualProtect, restore
+// flushes the instruction cache of the range, as it may be code, and sets back the protection of
+// each region.
+func unprotect(addr uintptr, size int) (restore func() error, err error) {
+	start, end := pageRange(addr, size, uintptr(windows.Getpagesize()))
+
+	type region struct {
+		base, size uintptr
+		prot       uint32
+	}
+	var regions []region
+	restore = func() error {
+		procFlushInstructionCache.Call(uintptr(windows.CurrentProcess()), addr, uintptr(size))
+		var restoreErr error
+		for _, r := range regions {
+			var old uint32
+			if err := windows.VirtualProtect(r.base, r.size, r.prot, &old); err != nil && restoreErr == nil {
+				restoreErr = fmt.Errorf("restore protection of %#x: %w", r.base, err)
+			}
+		}
+		return restoreErr
+	}
+	for base := start; base < end; {
+		var info windows.MemoryBasicInformation
+		if err := windows.VirtualQuery(base, &info, unsafe.Sizeof(info)); err != nil {
+			restore()
+			return nil, fmt.Errorf("VirtualQuery %#x: %w", base, err)
+		}
+		size := min(info.RegionSize-(base-info.BaseAddress), end-base)
+		var old uint32
+		if err := windows.VirtualProtect(base, size, windows.PAGE_EXECUTE_READWRITE, &old); err != nil {
+			restore()
+			return nil, fmt.Errorf("VirtualProtect %#x: %w", base, err)
+		}
+		regions = append(regions, region{base: base, size: size, prot: old})
+		base += size
+	}
+	return restore, nil
+}
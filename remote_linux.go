@@ -0,0 +1,65 @@
+package assembly
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"unsafe"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// atEntry is the auxiliary vector entry holding the entry point of the executable.
+const atEntry = 9
+
+// procMemory reads the memory of a process through /proc/<pid>/mem, it never writes to it.
+type procMemory struct {
+	file *os.File
+}
+
+func (m *procMemory) ReadMemory(data []byte, addr uint64) (int, error) {
+	return m.file.ReadAt(data, int64(addr))
+}
+
+func (m *procMemory) WriteMemory(addr uint64, data []byte) (int, error) {
+	return 0, fmt.Errorf("%w: writing %d bytes at %#x of an attached process", ErrNotSupport, len(data), addr)
+}
+
+func (m *procMemory) Close() error {
+	return m.file.Close()
+}
+
+// attachProcess opens /proc/<pid>/mem read only, which takes the permission to ptrace the process:
+// the same user and a permissive kernel.yama.ptrace_scope, or CAP_SYS_PTRACE. The process
+// keeps running, values are read while it changes them.
+func attachProcess(pid int) (string, uint64, proc.MemoryReadWriter, error) {
+	path, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", pid))
+	if err != nil {
+		return "", 0, nil, err
+	}
+	auxv, err := os.ReadFile(fmt.Sprintf("/proc/%d/auxv", pid))
+	if err != nil {
+		return "", 0, nil, err
+	}
+	var entryPoint uint64
+	const word = int(unsafe.Sizeof(uintptr(0)))
+	for i := 0; i+2*word <= len(auxv); i += 2 * word {
+		if readWord(auxv[i:]) == atEntry {
+			entryPoint = readWord(auxv[i+word:])
+			break
+		}
+	}
+
+	file, err := os.OpenFile(fmt.Sprintf("/proc/%d/mem", pid), os.O_RDONLY, 0)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	return path, entryPoint, &procMemory{file: file}, nil
+}
+
+func readWord(b []byte) uint64 {
+	if unsafe.Sizeof(uintptr(0)) == 4 {
+		return uint64(binary.NativeEndian.Uint32(b))
+	}
+	return binary.NativeEndian.Uint64(b)
+}
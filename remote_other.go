@@ -0,0 +1,14 @@
+//go:build !linux && !windows
+
+package assembly
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+func attachProcess(pid int) (string, uint64, proc.MemoryReadWriter, error) {
+	return "", 0, nil, fmt.Errorf("%w: attaching to processes on %s", ErrNotSupport, runtime.GOOS)
+}
@@ -0,0 +1,56 @@
+package assembly
+
+import (
+	"fmt"
+	"syscall"
+
+	"github.com/go-delve/delve/pkg/proc"
+	"golang.org/x/sys/windows"
+)
+
+// processMemory reads the memory of a process through its handle, it never writes to it.
+type processMemory struct {
+	handle windows.Handle
+}
+
+func (m *processMemory) ReadMemory(data []byte, addr uint64) (int, error) {
+	var n uintptr
+	if len(data) == 0 {
+		return 0, nil
+	}
+	err := windows.ReadProcessMemory(m.handle, uintptr(addr), &data[0], uintptr(len(data)), &n)
+	return int(n), err
+}
+
+func (m *processMemory) WriteMemory(addr uint64, data []byte) (int, error) {
+	return 0, fmt.Errorf("%w: writing %d bytes at %#x of an attached process", ErrNotSupport, len(data), addr)
+}
+
+func (m *processMemory) Close() error {
+	return windows.CloseHandle(m.handle)
+}
+
+// attachProcess opens the process with the access rights of ReadProcessMemory and of listing
+// its modules only, the process keeps running while it is read.
+func attachProcess(pid int) (string, uint64, proc.MemoryReadWriter, error) {
+	const access = windows.PROCESS_VM_READ | windows.PROCESS_QUERY_INFORMATION
+	handle, err := windows.OpenProcess(access, false, uint32(pid))
+	if err != nil {
+		return "", 0, nil, err
+	}
+
+	var buf [windows.MAX_PATH]uint16
+	var size = uint32(len(buf))
+	if err = windows.QueryFullProcessImageName(handle, 0, &buf[0], &size); err != nil {
+		windows.CloseHandle(handle)
+		return "", 0, nil, err
+	}
+	path := syscall.UTF16ToString(buf[:size])
+
+	base, err := moduleBase(handle, path)
+	if err != nil {
+		windows.CloseHandle(handle)
+		return "", 0, nil, err
+	}
+	return path, uint64(base), &processMemory{handle: handle}, nil
+}